@@ -25,8 +25,8 @@ class CreateCTIMetadataWorkload extends WorkloadModuleBase {
             SenderIdentity: 'HeadOfOperations',
             CID: `Qm${crypto.randomBytes(32).toString('hex')}`,
             VaultKey: `vaultKey${this.txIndex}`,
-            SHA256Hash: `hash${this.txIndex}`,
-            AccessList: ['HeadOfOperations']
+            SHA256Hash: crypto.createHash('sha256').update(`payload${this.txIndex}`).digest('hex'),
+            AccessList: ['Org1MSP/HeadOfOperations']
         };
 
         const request = {
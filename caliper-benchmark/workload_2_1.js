@@ -62,7 +62,7 @@ class CreateCTIMetadataWorkload extends WorkloadModuleBase {
             CID: cid.toString(),
             VaultKey: vaultKeyName,
             SHA256Hash: crypto.createHash('sha256').update(plainData).digest('hex'),
-            AccessList: ['HeadOfOperations']
+            AccessList: ['Org1MSP/HeadOfOperations']
         };
 
         const request = {
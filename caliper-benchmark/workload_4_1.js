@@ -1,6 +1,7 @@
 'use strict';
 
 const { WorkloadModuleBase } = require('@hyperledger/caliper-core');
+const crypto = require('crypto');
 const axios = require('axios');
 const fs = require('fs');
 const path = require('path');
@@ -51,8 +52,8 @@ class FabricIPFSWorkload extends WorkloadModuleBase {
             SenderIdentity: 'HeadOfOperations',
             CID: cid.toString(),
             VaultKey: 'asd', // Not used
-            SHA256Hash: 'asd', // Not used
-            AccessList: ['HeadOfOperations']
+            SHA256Hash: crypto.createHash('sha256').update(fileData).digest('hex'),
+            AccessList: ['Org1MSP/HeadOfOperations']
         };
 
         // 4. Send metadata to Fabric
@@ -63,7 +63,7 @@ class FabricVaultWorkload extends WorkloadModuleBase {
             CID: `Qm${crypto.randomBytes(32).toString('hex')}`,
             VaultKey: vaultKeyName,
             SHA256Hash: crypto.createHash('sha256').update(plainData).digest('hex'),
-            AccessList: ['HeadOfOperations']
+            AccessList: ['Org1MSP/HeadOfOperations']
         };
 
         // 6. Send metadata to Fabric
@@ -99,7 +99,7 @@ class MixedCTIMetadataWorkload extends WorkloadModuleBase {
                     CID: cid.toString(),
                     VaultKey: vaultKeyName,
                     SHA256Hash: crypto.createHash('sha256').update(plainData).digest('hex'),
-                    AccessList: ['HeadOfOperations']
+                    AccessList: ['Org1MSP/HeadOfOperations']
                 };
 
                 const request = {
@@ -0,0 +1,208 @@
+// File: channel_config.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Channel Configuration
+// ──────────────────────────────────────────────────────────────────────────────
+
+// configObjectType is the composite-key namespace of on-chain contract settings
+const configObjectType = "config"
+
+// ChannelConfig holds contract-wide settings that channel admins manage on-chain
+type ChannelConfig struct {
+	AllowForwardRefs bool   `json:"allow_forward_refs"`         // accept references to objects not yet on the ledger
+	DuplicatePolicy  string `json:"duplicate_policy,omitempty"` // "link" (default), "reject" or "allow" indicators with a known pattern
+}
+
+// channelAdminsKey is the configObjectType key of the organizations whose admins may
+// change channel-wide settings
+const channelAdminsKey = "admins"
+
+// getChannelAdmins returns the MSP IDs of the channel-admin organizations, or nil
+// before InitChannelAdmins has stored them
+func (c *CTIStixContract) getChannelAdmins(ctx contractapi.TransactionContextInterface) ([]string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{channelAdminsKey})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key for channel admins: %v", err)
+	}
+	bytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read channel admins: %v", err)
+	}
+	if bytes == nil {
+		return nil, nil
+	}
+	var msps []string
+	if err := json.Unmarshal(bytes, &msps); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal channel admins: %v", err)
+	}
+	return msps, nil
+}
+
+// putChannelAdmins checks and stores the MSP IDs of the channel-admin organizations
+func (c *CTIStixContract) putChannelAdmins(ctx contractapi.TransactionContextInterface, mspIDs []string) error {
+	if len(mspIDs) == 0 {
+		return fmt.Errorf("at least one channel-admin MSP ID is required")
+	}
+	seen := map[string]bool{}
+	for _, msp := range mspIDs {
+		if strings.TrimSpace(msp) != msp || msp == "" {
+			return fmt.Errorf("invalid MSP ID '%s'", msp)
+		}
+		if seen[msp] {
+			return fmt.Errorf("MSP ID %s is listed more than once", msp)
+		}
+		seen[msp] = true
+	}
+	bytes, err := json.Marshal(mspIDs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal channel admins: %v", err)
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{channelAdminsKey})
+	if err != nil {
+		return fmt.Errorf("failed to create key for channel admins: %v", err)
+	}
+	return c.putAsset(ctx, key, bytes)
+}
+
+// requireOrgAdmin fails unless the submitter is an admin of one of the given
+// organizations and returns its MSP ID
+func (c *CTIStixContract) requireOrgAdmin(ctx contractapi.TransactionContextInterface, msps []string) (string, error) {
+	admin, err := c.isAdmin(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !admin {
+		return "", fmt.Errorf("only organization admins may change channel-wide settings")
+	}
+	mspID, _, err := c.getSubmitter(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, msp := range msps {
+		if msp == mspID {
+			return mspID, nil
+		}
+	}
+	return "", fmt.Errorf("only admins of %s may change channel-wide settings, submitter is %s", strings.Join(msps, ", "), mspID)
+}
+
+// requireAdmin fails unless the submitter is an admin of one of the channel-admin
+// organizations stored on the ledger. Channel-wide settings (channel configuration,
+// sharing policy, permission table) are locked until InitChannelAdmins has run.
+func (c *CTIStixContract) requireAdmin(ctx contractapi.TransactionContextInterface) error {
+	msps, err := c.getChannelAdmins(ctx)
+	if err != nil {
+		return err
+	}
+	if len(msps) == 0 {
+		return fmt.Errorf("channel-wide settings are locked until InitChannelAdmins stores the channel-admin organizations")
+	}
+	_, err = c.requireOrgAdmin(ctx, msps)
+	return err
+}
+
+// getChannelConfig reads the channel configuration, falling back to the defaults
+func (c *CTIStixContract) getChannelConfig(ctx contractapi.TransactionContextInterface) (*ChannelConfig, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{"channel"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key for channel configuration: %v", err)
+	}
+	bytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read channel configuration: %v", err)
+	}
+
+	cfg := &ChannelConfig{}
+	if bytes == nil {
+		return cfg, nil
+	}
+	if err := json.Unmarshal(bytes, cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal channel configuration: %v", err)
+	}
+	return cfg, nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Channel Configuration Methods
+// ──────────────────────────────────────────────────────────────────────────────
+
+// GetChannelConfig returns the current channel configuration
+func (c *CTIStixContract) GetChannelConfig(ctx contractapi.TransactionContextInterface) (*ChannelConfig, error) {
+	return c.getChannelConfig(ctx)
+}
+
+// SetChannelConfig replaces the channel configuration; only channel admins may call it
+func (c *CTIStixContract) SetChannelConfig(ctx contractapi.TransactionContextInterface, jsonStr string) error {
+	if err := c.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	var cfg ChannelConfig
+	if err := json.Unmarshal([]byte(jsonStr), &cfg); err != nil {
+		return fmt.Errorf("failed to parse channel configuration JSON: %v", err)
+	}
+	switch cfg.DuplicatePolicy {
+	case "", duplicateLink, duplicateReject, duplicateAllow:
+	default:
+		return fmt.Errorf("duplicate_policy must be '%s', '%s' or '%s', got '%s'", duplicateLink, duplicateReject, duplicateAllow, cfg.DuplicatePolicy)
+	}
+	bytes, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal channel configuration: %v", err)
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{"channel"})
+	if err != nil {
+		return fmt.Errorf("failed to create key for channel configuration: %v", err)
+	}
+	return c.putAsset(ctx, key, bytes)
+}
+
+// InitChannelAdmins stores the organizations whose admins may change channel-wide
+// settings. It is the chaincode's init transaction: commit the chaincode definition
+// with --init-required and invoke it with --isInit, so that it runs before any other
+// transaction. It fails once the set is stored, and the submitter must be an admin
+// of one of the listed organizations.
+func (c *CTIStixContract) InitChannelAdmins(ctx contractapi.TransactionContextInterface, mspIDs []string) error {
+	msps, err := c.getChannelAdmins(ctx)
+	if err != nil {
+		return err
+	}
+	if len(msps) > 0 {
+		return fmt.Errorf("channel admins are already set to %s; use SetChannelAdmins", strings.Join(msps, ", "))
+	}
+	if _, err := c.requireOrgAdmin(ctx, mspIDs); err != nil {
+		return err
+	}
+	return c.putChannelAdmins(ctx, mspIDs)
+}
+
+// GetChannelAdmins returns the MSP IDs of the channel-admin organizations
+func (c *CTIStixContract) GetChannelAdmins(ctx contractapi.TransactionContextInterface) ([]string, error) {
+	msps, err := c.getChannelAdmins(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if msps == nil {
+		msps = []string{}
+	}
+	return msps, nil
+}
+
+// SetChannelAdmins replaces the channel-admin organizations; only channel admins may
+// call it
+func (c *CTIStixContract) SetChannelAdmins(ctx contractapi.TransactionContextInterface, mspIDs []string) error {
+	if err := c.requireAdmin(ctx); err != nil {
+		return err
+	}
+	return c.putChannelAdmins(ctx, mspIDs)
+}
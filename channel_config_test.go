@@ -0,0 +1,73 @@
+// File: channel_config_test.go
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestChannelAdmins(t *testing.T) {
+	l := newMockLedger(t)
+	org1Admin := newMockIdentity("Org1MSP", "admin", "", true)
+	org1User := newMockIdentity("Org1MSP", "analyst", "producer", false)
+	org2Admin := newMockIdentity("Org2MSP", "admin", "", true)
+	org3Admin := newMockIdentity("Org3MSP", "admin", "", true)
+	setConfig := func(ctx contractapi.TransactionContextInterface) error {
+		return (&CTIStixContract{}).SetChannelConfig(ctx, `{"allow_forward_refs":true}`)
+	}
+	initAdmins := func(msps ...string) func(ctx contractapi.TransactionContextInterface) error {
+		return func(ctx contractapi.TransactionContextInterface) error {
+			return (&CTIStixContract{}).InitChannelAdmins(ctx, msps)
+		}
+	}
+	setAdmins := func(msps ...string) func(ctx contractapi.TransactionContextInterface) error {
+		return func(ctx contractapi.TransactionContextInterface) error {
+			return (&CTIStixContract{}).SetChannelAdmins(ctx, msps)
+		}
+	}
+
+	// The steps run in order against one ledger
+	steps := []struct {
+		name    string
+		client  *mockIdentity
+		fn      func(ctx contractapi.TransactionContextInterface) error
+		errText string // "" if the transaction succeeds
+	}{
+		{"settings are locked before the init transaction", org1Admin, setConfig, "locked"},
+		{"init by a client that is not an admin", org1User, initAdmins("Org1MSP"), "only organization admins"},
+		{"init by an admin of an unlisted organization", org3Admin, initAdmins("Org1MSP", "Org2MSP"), "submitter is Org3MSP"},
+		{"init with a duplicate MSP ID", org1Admin, initAdmins("Org1MSP", "Org1MSP"), "more than once"},
+		{"init", org1Admin, initAdmins("Org1MSP", "Org2MSP"), ""},
+		{"a second init", org2Admin, initAdmins("Org2MSP"), "already set"},
+		{"admin of a channel-admin organization", org2Admin, setConfig, ""},
+		{"non-admin of a channel-admin organization", org1User, setConfig, "only organization admins"},
+		{"admin of another organization", org3Admin, setConfig, "submitter is Org3MSP"},
+		{"another organization cannot add itself", org3Admin, setAdmins("Org3MSP"), "submitter is Org3MSP"},
+		{"a channel admin replaces the list", org1Admin, setAdmins("Org1MSP", "Org3MSP"), ""},
+		{"a removed organization loses the right", org2Admin, setConfig, "submitter is Org2MSP"},
+		{"an added organization gains it", org3Admin, setConfig, ""},
+		{"the list cannot be emptied", org1Admin, setAdmins(), "at least one"},
+	}
+	for _, step := range steps {
+		err := l.invoke(step.client, step.fn)
+		if step.errText == "" && err != nil {
+			t.Errorf("%s: %v", step.name, err)
+		}
+		if step.errText != "" && (err == nil || !strings.Contains(err.Error(), step.errText)) {
+			t.Errorf("%s: error %v, want %q", step.name, err, step.errText)
+		}
+	}
+
+	var admins []string
+	err := l.invoke(org1User, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		admins, err = (&CTIStixContract{}).GetChannelAdmins(ctx)
+		return err
+	})
+	if err != nil || strings.Join(admins, ",") != "Org1MSP,Org3MSP" {
+		t.Errorf("GetChannelAdmins = %v, %v", admins, err)
+	}
+}
@@ -0,0 +1,179 @@
+// File: cti_metadata.go
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) CTI Metadata Asset
+// ──────────────────────────────────────────────────────────────────────────────
+
+// ctiMetadataObjectType is the composite-key namespace for CTI metadata records,
+// keeping them apart from STIX objects stored under their STIX IDs
+const ctiMetadataObjectType = "ctimetadata"
+
+// CTIMetadata describes an encrypted CTI payload kept off-chain: the ciphertext
+// lives in IPFS (CID) and its AES key in Vault (VaultKey)
+type CTIMetadata struct {
+	UUID           string   `json:"UUID"`           // e.g. "test-1714560000000-1"
+	Description    string   `json:"Description"`    // free text
+	Timestamp      string   `json:"Timestamp"`      // RFC 3339, e.g. "2025-05-01T12:15:00Z"
+	SenderIdentity string   `json:"SenderIdentity"` // common name of the submitter's certificate
+	SenderMSP      string   `json:"SenderMSP"`      // MSP ID of the submitter, set by the chaincode
+	CID            string   `json:"CID"`            // IPFS content identifier of the encrypted payload
+	VaultKey       string   `json:"VaultKey"`       // Vault key name holding the AES key
+	SHA256Hash     string   `json:"SHA256Hash"`     // hex SHA-256 of the plaintext payload
+	AccessList     []string `json:"AccessList"`     // identities ("Org1MSP/HeadOfOperations") or MSP IDs allowed to read
+}
+
+// validate checks the caller-supplied fields of a CTIMetadata record
+func (m *CTIMetadata) validate() error {
+	if strings.TrimSpace(m.UUID) == "" {
+		return fmt.Errorf("UUID is required")
+	}
+	if _, err := time.Parse(time.RFC3339, m.Timestamp); err != nil {
+		return fmt.Errorf("Timestamp must be an RFC 3339 timestamp, got '%s'", m.Timestamp)
+	}
+	if strings.TrimSpace(m.CID) == "" {
+		return fmt.Errorf("CID is required")
+	}
+	if strings.TrimSpace(m.VaultKey) == "" {
+		return fmt.Errorf("VaultKey is required")
+	}
+	if len(m.SHA256Hash) != 64 {
+		return fmt.Errorf("SHA256Hash must be 64 hex characters, got %d", len(m.SHA256Hash))
+	}
+	if _, err := hex.DecodeString(m.SHA256Hash); err != nil {
+		return fmt.Errorf("SHA256Hash must be hex encoded: %v", err)
+	}
+	if len(m.AccessList) == 0 {
+		return fmt.Errorf("AccessList must contain at least one entry")
+	}
+	for _, entry := range m.AccessList {
+		if strings.TrimSpace(entry) == "" {
+			return fmt.Errorf("AccessList must not contain empty entries")
+		}
+		if msp, cn, ok := strings.Cut(entry, "/"); ok && (msp == "" || cn == "") {
+			return fmt.Errorf("AccessList entry '%s' must be an MSP ID or MSP ID/common name", entry)
+		}
+	}
+	return nil
+}
+
+// canRead reports whether the given submitter may read this record: the sender
+// itself, anyone whose MSP ID appears on the AccessList, or an identity listed as
+// MSP ID/common name. A common name is only unique within its MSP, so a bare common
+// name never matches.
+func (m *CTIMetadata) canRead(mspID, commonName string) bool {
+	if mspID == m.SenderMSP && commonName == m.SenderIdentity {
+		return true
+	}
+	for _, entry := range m.AccessList {
+		if entry == mspID || entry == mspID+"/"+commonName {
+			return true
+		}
+	}
+	return false
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) CTI Metadata Methods
+// ──────────────────────────────────────────────────────────────────────────────
+
+// CreateCTIMetadata records the metadata of an encrypted off-chain CTI payload.
+// SenderIdentity is bound to the submitter's certificate and SenderMSP to its MSP.
+func (c *CTIStixContract) CreateCTIMetadata(
+	ctx contractapi.TransactionContextInterface,
+	jsonStr string,
+) error {
+	if err := c.requirePermission(ctx, actionCreate); err != nil {
+		return err
+	}
+
+	var meta CTIMetadata
+	if err := json.Unmarshal([]byte(jsonStr), &meta); err != nil {
+		return fmt.Errorf("failed to parse CTI metadata JSON: %v", err)
+	}
+	if err := meta.validate(); err != nil {
+		return fmt.Errorf("invalid CTI metadata: %v", err)
+	}
+
+	mspID, commonName, err := c.getSubmitter(ctx)
+	if err != nil {
+		return err
+	}
+	// The sender may be omitted, but it may not claim to be somebody else
+	if meta.SenderIdentity == "" {
+		meta.SenderIdentity = commonName
+	}
+	if meta.SenderIdentity != commonName {
+		return fmt.Errorf("SenderIdentity '%s' does not match submitter '%s'", meta.SenderIdentity, commonName)
+	}
+	if meta.SenderMSP != "" && meta.SenderMSP != mspID {
+		return fmt.Errorf("SenderMSP '%s' does not match submitter MSP '%s'", meta.SenderMSP, mspID)
+	}
+	meta.SenderMSP = mspID
+
+	key, err := ctx.GetStub().CreateCompositeKey(ctiMetadataObjectType, []string{meta.UUID})
+	if err != nil {
+		return fmt.Errorf("failed to create key for CTI metadata %s: %v", meta.UUID, err)
+	}
+	exists, err := c.assetExists(ctx, key)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("CTI metadata with UUID %s already exists", meta.UUID)
+	}
+
+	bytes, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal CTI metadata for storage: %v", err)
+	}
+	return c.putAsset(ctx, key, bytes)
+}
+
+// ReadCTIMetadata retrieves CTI metadata by UUID. Callers that are neither the
+// sender nor on the AccessList are refused.
+func (c *CTIStixContract) ReadCTIMetadata(
+	ctx contractapi.TransactionContextInterface,
+	uuid string,
+) (*CTIMetadata, error) {
+	if err := c.requirePermission(ctx, actionRead); err != nil {
+		return nil, err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(ctiMetadataObjectType, []string{uuid})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key for CTI metadata %s: %v", uuid, err)
+	}
+	bytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CTI metadata %s from world state: %v", uuid, err)
+	}
+	if bytes == nil {
+		return nil, fmt.Errorf("CTI metadata %s does not exist", uuid)
+	}
+
+	var meta CTIMetadata
+	if err := json.Unmarshal(bytes, &meta); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal CTI metadata JSON: %v", err)
+	}
+
+	mspID, commonName, err := c.getSubmitter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !meta.canRead(mspID, commonName) {
+		return nil, fmt.Errorf("submitter '%s' (%s) is not on the access list of CTI metadata %s", commonName, mspID, uuid)
+	}
+	return &meta, nil
+}
@@ -4,6 +4,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
@@ -24,59 +25,94 @@ type CTIStixContract struct {
 
 // Indicator represents a STIX 2.1 “indicator” object
 type Indicator struct {
-	Type               string              `json:"type"`         // must be "indicator"
-	ID                 string              `json:"id"`           // e.g. "indicator--UUID"
-	SpecVersion        string              `json:"spec_version"` // must be "2.1"
-	Created            string              `json:"created"`      // e.g. "2025-05-01T12:15:00Z"
-	Modified           string              `json:"modified"`     // e.g. "2025-05-01T12:15:00Z"
-	Name               string              `json:"name"`
-	Description        string              `json:"description"`
-	Pattern            string              `json:"pattern"`      // e.g. "[ipv4-addr:value = '203.0.113.45']"
-	PatternType        string              `json:"pattern_type"` // e.g. "stix"
-	ValidFrom          string              `json:"valid_from"`   // e.g. "2025-05-01T00:00:00Z"
-	Labels             []string            `json:"labels"`       // e.g. ["c2-server","malware-c2"]
-	Confidence         int                 `json:"confidence"`   // e.g. 75
-	ExternalReferences []ExternalReference `json:"external_references"`
+	CommonProperties
+	Name            string           `json:"name,omitempty"`
+	Description     string           `json:"description,omitempty"`
+	IndicatorTypes  []string         `json:"indicator_types,omitempty"` // e.g. ["malicious-activity"]
+	Pattern         string           `json:"pattern"`                   // e.g. "[ipv4-addr:value = '203.0.113.45']"
+	PatternType     string           `json:"pattern_type"`              // e.g. "stix"
+	PatternVersion  string           `json:"pattern_version,omitempty"` // e.g. "2.1"
+	ValidFrom       string           `json:"valid_from"`                // e.g. "2025-05-01T00:00:00Z"
+	ValidUntil      string           `json:"valid_until,omitempty"`     // e.g. "2025-08-01T00:00:00Z"
+	KillChainPhases []KillChainPhase `json:"kill_chain_phases,omitempty"`
 }
 
 // Relationship represents a STIX 2.1 “relationship” object
 type Relationship struct {
-	Type             string `json:"type"`              // must be "relationship"
-	ID               string `json:"id"`                // e.g. "relationship--UUID"
-	SpecVersion      string `json:"spec_version"`      // "2.1"
-	Created          string `json:"created"`           // e.g. "2025-05-01T12:20:00Z"
-	Modified         string `json:"modified"`          // e.g. "2025-05-01T12:20:00Z"
-	RelationshipType string `json:"relationship_type"` // e.g. "indicates"
-	SourceRef        string `json:"source_ref"`        // e.g. "indicator--…"
-	TargetRef        string `json:"target_ref"`        // e.g. "malware--…"
+	CommonProperties
+	RelationshipType string `json:"relationship_type"`     // e.g. "indicates"
+	Description      string `json:"description,omitempty"` // free text
+	SourceRef        string `json:"source_ref"`            // e.g. "indicator--…"
+	TargetRef        string `json:"target_ref"`            // e.g. "malware--…"
+	StartTime        string `json:"start_time,omitempty"`  // e.g. "2025-05-01T00:00:00Z"
+	StopTime         string `json:"stop_time,omitempty"`   // e.g. "2025-06-01T00:00:00Z"
 }
 
 // Sighting represents a STIX 2.1 “sighting” object
 type Sighting struct {
-	Type             string   `json:"type"`               // must be "sighting"
-	ID               string   `json:"id"`                 // e.g. "sighting--UUID"
-	SpecVersion      string   `json:"spec_version"`       // "2.1"
-	Created          string   `json:"created"`            // e.g. "2025-05-02T08:30:00Z"
-	Modified         string   `json:"modified"`           // e.g. "2025-05-02T08:30:00Z"
-	FirstSeen        string   `json:"first_seen"`         // e.g. "2025-05-02T07:45:00Z"
-	LastSeen         string   `json:"last_seen"`          // e.g. "2025-05-02T08:00:00Z"
-	Count            int      `json:"count"`              // e.g. 3
-	SightingOfRef    string   `json:"sighting_of_ref"`    // e.g. "indicator--…"
-	WhereSightedRefs []string `json:"where_sighted_refs"` // e.g. ["identity--…"]
+	CommonProperties
+	Description      string   `json:"description,omitempty"`
+	FirstSeen        string   `json:"first_seen,omitempty"`         // e.g. "2025-05-02T07:45:00Z"
+	LastSeen         string   `json:"last_seen,omitempty"`          // e.g. "2025-05-02T08:00:00Z"
+	Count            int      `json:"count,omitempty"`              // e.g. 3
+	SightingOfRef    string   `json:"sighting_of_ref"`              // e.g. "indicator--…"
+	ObservedDataRefs []string `json:"observed_data_refs,omitempty"` // e.g. ["observed-data--…"]
+	WhereSightedRefs []string `json:"where_sighted_refs,omitempty"` // e.g. ["identity--…"]
+	Summary          bool     `json:"summary,omitempty"`
 }
 
 // Bundle represents a STIX 2.1 “bundle” object, containing multiple STIX objects
 type Bundle struct {
-	Type        string            `json:"type"`         // must be "bundle"
-	ID          string            `json:"id"`           // e.g. "bundle--UUID"
-	SpecVersion string            `json:"spec_version"` // "2.1"
-	Objects     []json.RawMessage `json:"objects"`      // an array of raw JSON for each STIX object
+	Type        string            `json:"type"`                   // must be "bundle"
+	ID          string            `json:"id"`                     // e.g. "bundle--UUID"
+	SpecVersion string            `json:"spec_version,omitempty"` // only in STIX 2.0 bundles
+	Objects     []json.RawMessage `json:"objects"`                // an array of raw JSON for each STIX object
+}
+
+// bundleManifest is what is stored for a bundle once its objects have been
+// stored individually: the bundle ID and the IDs of the objects it carried
+type bundleManifest struct {
+	Type       string   `json:"type"` // "bundle"
+	ID         string   `json:"id"`
+	ObjectRefs []string `json:"object_refs"`
+}
+
+// BundleReport lists the outcome of ingesting each object of a bundle
+type BundleReport struct {
+	BundleID string               `json:"bundle_id"`
+	Stored   bool                 `json:"stored"` // false if the bundle was rejected as a whole
+	Objects  []BundleObjectResult `json:"objects"`
+}
+
+// BundleObjectResult is the outcome for one object of a bundle
+type BundleObjectResult struct {
+	Index   int          `json:"index"` // position in the bundle's objects array
+	ID      string       `json:"id"`
+	Type    string       `json:"type"`
+	Error   string       `json:"error,omitempty"`
+	Fields  []FieldError `json:"fields,omitempty"`  // field-level validation errors, if any
+	Merged  bool         `json:"merged,omitempty"`  // an SCO merged into a stored one the submitter may see
+	Skipped bool         `json:"skipped,omitempty"` // a predefined TLP marking definition, not stored
+}
+
+// setError records why an object of a bundle was rejected
+func (r *BundleObjectResult) setError(err error) {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		r.Error = "invalid STIX object"
+		r.Fields = verr.Errors
+		return
+	}
+	r.Error = err.Error()
 }
 
 // ExternalReference is used by Indicator (and potentially other objects)
 type ExternalReference struct {
-	SourceName string `json:"source_name"`
-	URL        string `json:"url"`
+	SourceName  string            `json:"source_name"`
+	Description string            `json:"description,omitempty"`
+	URL         string            `json:"url,omitempty"`
+	Hashes      map[string]string `json:"hashes,omitempty"`
+	ExternalID  string            `json:"external_id,omitempty"`
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -88,18 +124,6 @@ func (c *CTIStixContract) putAsset(ctx contractapi.TransactionContextInterface,
 	return ctx.GetStub().PutState(id, assetJSON)
 }
 
-// getAsset helper function to read any object by ID from world state
-func (c *CTIStixContract) getAsset(ctx contractapi.TransactionContextInterface, id string) ([]byte, error) {
-	data, err := ctx.GetStub().GetState(id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read %s from world state: %v", id, err)
-	}
-	if data == nil {
-		return nil, fmt.Errorf("asset %s does not exist", id)
-	}
-	return data, nil
-}
-
 // assetExists checks if a key already exists in world state
 func (c *CTIStixContract) assetExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
 	data, err := ctx.GetStub().GetState(id)
@@ -109,23 +133,42 @@ func (c *CTIStixContract) assetExists(ctx contractapi.TransactionContextInterfac
 	return data != nil, nil
 }
 
-// getAllAssets returns all JSON objects stored in world state (unfiltered)
-func (c *CTIStixContract) getAllAssets(ctx contractapi.TransactionContextInterface) ([]json.RawMessage, error) {
-	iterator, err := ctx.GetStub().GetStateByRange("", "")
+// resultJSON renders the result of a transaction that returns STIX objects.
+// contract-api-go derives the response schema from the Go type and describes a
+// json.RawMessage as an array of integers, so these transactions return the JSON
+// text instead, with every object exactly as it is stored.
+func resultJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get state by range: %v", err)
+		return "", fmt.Errorf("failed to marshal result: %v", err)
 	}
-	defer iterator.Close()
+	return string(data), nil
+}
 
-	var results []json.RawMessage
-	for iterator.HasNext() {
-		queryResponse, err := iterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("failed to iterate: %v", err)
-		}
-		results = append(results, queryResponse.Value)
+// readTypedObject reads an object for one of the typed readers into v. The object
+// must be of the reader's type, and a revoked object is refused unless
+// includeRevoked is set.
+func (c *CTIStixContract) readTypedObject(
+	ctx contractapi.TransactionContextInterface,
+	typ string,
+	id string,
+	includeRevoked bool,
+	v interface{},
+) error {
+	rec, obj, err := c.readVisibleObject(ctx, id)
+	if err != nil {
+		return err
+	}
+	if rec.Type != typ {
+		return fmt.Errorf("%s is a %s, not a %s", id, rec.Type, typ)
 	}
-	return results, nil
+	if rec.Revoked && !includeRevoked {
+		return fmt.Errorf("%s has been revoked: %s", id, rec.Reason)
+	}
+	if err := json.Unmarshal(obj, v); err != nil {
+		return fmt.Errorf("failed to unmarshal %s JSON: %v", typ, err)
+	}
+	return nil
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -137,47 +180,19 @@ func (c *CTIStixContract) CreateIndicator(
 	ctx contractapi.TransactionContextInterface,
 	jsonStr string,
 ) error {
-	// jsonStr is expected to be a JSON string representing the full Indicator object
-	var ind Indicator
-	if err := json.Unmarshal([]byte(jsonStr), &ind); err != nil {
-		return fmt.Errorf("failed to parse indicator JSON: %v", err)
-	}
-
-	// Ensure the “type” field is correct
-	if ind.Type != "indicator" {
-		return fmt.Errorf("asset type must be 'indicator', got '%s'", ind.Type)
-	}
-
-	// Check if this ID already exists
-	exists, err := c.assetExists(ctx, ind.ID)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return fmt.Errorf("indicator with ID %s already exists", ind.ID)
-	}
-
-	// Marshal back to JSON (ensuring consistent formatting) and store
-	bytes, err := json.Marshal(ind)
-	if err != nil {
-		return fmt.Errorf("failed to marshal indicator for storage: %v", err)
-	}
-	return c.putAsset(ctx, ind.ID, bytes)
+	return c.createTypedObject(ctx, "indicator", []byte(jsonStr))
 }
 
-// ReadIndicator retrieves a single Indicator by its STIX ID
+// ReadIndicator retrieves a single Indicator by its STIX ID. Like ReadObject it refuses a
+// revoked indicator unless includeRevoked is set.
 func (c *CTIStixContract) ReadIndicator(
 	ctx contractapi.TransactionContextInterface,
 	id string,
+	includeRevoked bool,
 ) (*Indicator, error) {
-	bytes, err := c.getAsset(ctx, id)
-	if err != nil {
-		return nil, err
-	}
-
 	var ind Indicator
-	if err := json.Unmarshal(bytes, &ind); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal indicator JSON: %v", err)
+	if err := c.readTypedObject(ctx, "indicator", id, includeRevoked, &ind); err != nil {
+		return nil, err
 	}
 	return &ind, nil
 }
@@ -188,43 +203,19 @@ func (c *CTIStixContract) ReadIndicator(
 
 // CreateRelationship writes a new STIX Relationship into world state
 func (c *CTIStixContract) CreateRelationship(ctx contractapi.TransactionContextInterface, jsonStr string) error {
-	var rel Relationship
-	if err := json.Unmarshal([]byte(jsonStr), &rel); err != nil {
-		return fmt.Errorf("failed to parse relationship JSON: %v", err)
-	}
-
-	if rel.Type != "relationship" {
-		return fmt.Errorf("asset type must be 'relationship', got '%s'", rel.Type)
-	}
-
-	exists, err := c.assetExists(ctx, rel.ID)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return fmt.Errorf("relationship with ID %s already exists", rel.ID)
-	}
-
-	bytes, err := json.Marshal(rel)
-	if err != nil {
-		return fmt.Errorf("failed to marshal relationship for storage: %v", err)
-	}
-	return c.putAsset(ctx, rel.ID, bytes)
+	return c.createTypedObject(ctx, "relationship", []byte(jsonStr))
 }
 
-// ReadRelationship retrieves a single Relationship by its STIX ID
+// ReadRelationship retrieves a single Relationship by its STIX ID. Like ReadObject it refuses a
+// revoked relationship unless includeRevoked is set.
 func (c *CTIStixContract) ReadRelationship(
 	ctx contractapi.TransactionContextInterface,
 	id string,
+	includeRevoked bool,
 ) (*Relationship, error) {
-	bytes, err := c.getAsset(ctx, id)
-	if err != nil {
-		return nil, err
-	}
-
 	var rel Relationship
-	if err := json.Unmarshal(bytes, &rel); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal relationship JSON: %v", err)
+	if err := c.readTypedObject(ctx, "relationship", id, includeRevoked, &rel); err != nil {
+		return nil, err
 	}
 	return &rel, nil
 }
@@ -235,43 +226,19 @@ func (c *CTIStixContract) ReadRelationship(
 
 // CreateSighting writes a new STIX Sighting into world state
 func (c *CTIStixContract) CreateSighting(ctx contractapi.TransactionContextInterface, jsonStr string) error {
-	var sit Sighting
-	if err := json.Unmarshal([]byte(jsonStr), &sit); err != nil {
-		return fmt.Errorf("failed to parse sighting JSON: %v", err)
-	}
-
-	if sit.Type != "sighting" {
-		return fmt.Errorf("asset type must be 'sighting', got '%s'", sit.Type)
-	}
-
-	exists, err := c.assetExists(ctx, sit.ID)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return fmt.Errorf("sighting with ID %s already exists", sit.ID)
-	}
-
-	bytes, err := json.Marshal(sit)
-	if err != nil {
-		return fmt.Errorf("failed to marshal sighting for storage: %v", err)
-	}
-	return c.putAsset(ctx, sit.ID, bytes)
+	return c.createTypedObject(ctx, "sighting", []byte(jsonStr))
 }
 
-// ReadSighting retrieves a single Sighting by its STIX ID
+// ReadSighting retrieves a single Sighting by its STIX ID. Like ReadObject it refuses a
+// revoked sighting unless includeRevoked is set.
 func (c *CTIStixContract) ReadSighting(
 	ctx contractapi.TransactionContextInterface,
 	id string,
+	includeRevoked bool,
 ) (*Sighting, error) {
-	bytes, err := c.getAsset(ctx, id)
-	if err != nil {
-		return nil, err
-	}
-
 	var sit Sighting
-	if err := json.Unmarshal(bytes, &sit); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal sighting JSON: %v", err)
+	if err := c.readTypedObject(ctx, "sighting", id, includeRevoked, &sit); err != nil {
+		return nil, err
 	}
 	return &sit, nil
 }
@@ -280,146 +247,330 @@ func (c *CTIStixContract) ReadSighting(
 // 7) Bundle Methods
 // ──────────────────────────────────────────────────────────────────────────────
 
-// CreateBundle writes a new STIX Bundle into world state
-func (c *CTIStixContract) CreateBundle(ctx contractapi.TransactionContextInterface, jsonStr string) error {
+// CreateBundle validates every object of a STIX Bundle and stores each one under its
+// own key, followed by a manifest of the bundle. References from relationships and
+// sightings must resolve within the bundle or the ledger (or are tracked as pending
+// when the channel allows forward references). Either every object is
+// stored or, if any object fails, none is and the error carries the per-object report.
+func (c *CTIStixContract) CreateBundle(ctx contractapi.TransactionContextInterface, jsonStr string) (*BundleReport, error) {
+	if err := c.requirePermission(ctx, actionCreate); err != nil {
+		return nil, err
+	}
+
 	var b Bundle
 	if err := json.Unmarshal([]byte(jsonStr), &b); err != nil {
-		return fmt.Errorf("failed to parse bundle JSON: %v", err)
+		return nil, fmt.Errorf("failed to parse bundle JSON: %v", err)
 	}
 
 	if b.Type != "bundle" {
-		return fmt.Errorf("asset type must be 'bundle', got '%s'", b.Type)
+		return nil, fmt.Errorf("asset type must be 'bundle', got '%s'", b.Type)
+	}
+	if len(b.Objects) == 0 {
+		return nil, fmt.Errorf("bundle %s contains no objects", b.ID)
 	}
 
-	exists, err := c.assetExists(ctx, b.ID)
+	exists, err := c.stixObjectExists(ctx, b.ID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if exists {
-		return fmt.Errorf("bundle with ID %s already exists", b.ID)
+		return nil, fmt.Errorf("bundle with ID %s already exists", b.ID)
 	}
 
-	// We keep the “Objects” field as an array of Raw JSON; no need to validate every sub-object here
-	bytes, err := json.Marshal(b)
+	sub, err := c.getSubmitterIdentity(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to marshal bundle for storage: %v", err)
+		return nil, err
 	}
-	return c.putAsset(ctx, b.ID, bytes)
-}
-
-// ReadBundle retrieves a single Bundle by its STIX ID
-func (c *CTIStixContract) ReadBundle(
-	ctx contractapi.TransactionContextInterface,
-	id string,
-) (*Bundle, error) {
-	bytes, err := c.getAsset(ctx, id)
+	mspID := sub.MSPID
+	identityID, err := c.ensureOrgIdentity(ctx, mspID)
 	if err != nil {
 		return nil, err
 	}
 
-	var b Bundle
-	if err := json.Unmarshal(bytes, &b); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal bundle JSON: %v", err)
+	// First pass: decode every object so that references can point forward within the bundle
+	report := &BundleReport{BundleID: b.ID}
+	pending := make(map[string]json.RawMessage, len(b.Objects))
+	props := make([]map[string]json.RawMessage, len(b.Objects))
+	failed := false
+	for i := range b.Objects {
+		result := BundleObjectResult{Index: i}
+		raw, err := stampCreatedByRef(b.Objects[i], identityID)
+		if err == nil {
+			raw, err = withObservableID(raw)
+		}
+		if err != nil {
+			result.setError(err)
+			failed = true
+			report.Objects = append(report.Objects, result)
+			continue
+		}
+		b.Objects[i] = raw
+		var header struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		}
+		if err := json.Unmarshal(raw, &header); err != nil {
+			result.Error = fmt.Sprintf("failed to parse object JSON: %v", err)
+		} else {
+			result.ID, result.Type = header.ID, header.Type
+			if _, p, _, err := decodeStixObject(header.Type, raw); err != nil {
+				result.setError(err)
+			} else if _, dup := pending[header.ID]; dup {
+				result.Error = fmt.Sprintf("%s appears more than once in the bundle", header.ID)
+			} else {
+				props[i] = p
+				pending[header.ID] = raw
+			}
+		}
+		failed = failed || result.Error != ""
+		report.Objects = append(report.Objects, result)
 	}
-	return &b, nil
-}
 
-// ──────────────────────────────────────────────────────────────────────────────
-// 8) Generic “Get All” Method
-// ──────────────────────────────────────────────────────────────────────────────
-
-// GetAllObjects returns a JSON array of all stored STIX objects (Indicator, Relationship,
-// Sighting, Bundle, or any other JSON‐blobs) in the world state.
-func (c *CTIStixContract) GetAllObjects(
-	ctx contractapi.TransactionContextInterface,
-) ([]json.RawMessage, error) {
-	allData, err := c.getAllAssets(ctx)
+	// Second pass: ledger checks, extensions and references
+	cfg, err := c.getChannelConfig(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return allData, nil
-}
+	missing := make([][]string, len(b.Objects))
+	opinions := map[string]string{} // object ref|author → opinion pending in the bundle
+	originals := make([]*duplicateOriginal, len(b.Objects))
+	fingerprints := map[string]*duplicateOriginal{} // pattern fingerprint → first indicator in the bundle
+	v, err := c.orgViewer(ctx, mspID)
+	if err != nil {
+		return nil, err
+	}
+	merges := make([]bool, len(b.Objects))
+	for i := range b.Objects {
+		result := &report.Objects[i]
+		if props[i] == nil {
+			continue
+		}
+		if _, ok := tlpLevels[result.ID]; ok {
+			result.Skipped = true
+			continue
+		}
+		exists, err := c.stixObjectExists(ctx, result.ID)
+		if err != nil {
+			result.setError(err)
+		} else if exists && !isObservableObject(result.Type, props[i]) {
+			result.Error = fmt.Sprintf("%s with ID %s already exists", result.Type, result.ID)
+		} else if stub, err := c.getPrivateStub(ctx, result.ID); err != nil {
+			result.setError(err)
+		} else if stub != nil {
+			result.Error = fmt.Sprintf("%s with ID %s already exists", result.Type, result.ID)
+		} else if err := c.checkExtensions(ctx, props[i], pending); err != nil {
+			result.setError(err)
+		} else if err := c.checkMarkingRefs(ctx, props[i], pending); err != nil {
+			result.setError(err)
+		} else if err := c.checkSingleOpinion(ctx, result.ID, props[i], opinions); err != nil {
+			result.setError(err)
+		} else if err := c.requireRevokePermission(ctx, props[i]); err != nil {
+			result.setError(err)
+		} else if missing[i], err = c.checkObjectRefs(ctx, v, result.Type, props[i], pending, cfg.AllowForwardRefs); err != nil {
+			result.setError(err)
+		} else if originals[i], err = c.findDuplicate(ctx, cfg, v, result.ID, props[i], fingerprints); err != nil {
+			result.setError(err)
+		}
+		merges[i] = exists && result.Error == ""
+		if merges[i] {
+			// Only flag merges into objects the submitter can see, so that the report
+			// does not disclose restricted observables
+			if result.Merged, err = c.observableVisible(ctx, result.ID, mspID); err != nil {
+				return nil, err
+			}
+		}
+		failed = failed || result.Error != ""
+	}
 
-// GetAllObjects returns a single JSON array containing all stored STIX objects.
-func (c *CTIStixContract) GetAllObjects2(
-	ctx contractapi.TransactionContextInterface,
-) ([]byte, error) {
-	iterator, err := ctx.GetStub().GetStateByRange("", "")
+	if failed {
+		reportJSON, err := json.Marshal(report)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal bundle report: %v", err)
+		}
+		return nil, fmt.Errorf("bundle %s rejected, no object was stored: %s", b.ID, reportJSON)
+	}
+
+	public, err := c.publicViewer(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get state by range: %v", err)
+		return nil, err
 	}
-	defer iterator.Close()
+	manifest := bundleManifest{Type: "bundle", ID: b.ID}
+	var events []ObjectEvent
+	for i, raw := range b.Objects {
+		if report.Objects[i].Skipped {
+			continue
+		}
+		if merges[i] {
+			event, err := c.mergeObservable(ctx, report.Objects[i].ID, raw, props[i], mspID, public)
+			if err != nil {
+				return nil, fmt.Errorf("failed to merge object %d (%s) of bundle %s: %v", i, report.Objects[i].ID, b.ID, err)
+			}
+			manifest.ObjectRefs = append(manifest.ObjectRefs, report.Objects[i].ID)
+			events = append(events, event)
+			continue
+		}
 
-	// Collect all world‐state values (each is raw JSON already)
-	var allData []json.RawMessage
-	for iterator.HasNext() {
-		queryResponse, err := iterator.Next()
+		rec, err := newLedgerRecord(raw)
 		if err != nil {
-			return nil, fmt.Errorf("failed to iterate: %v", err)
+			return nil, err
+		}
+		rec.CreatorMSP, rec.CreatorSubject = mspID, sub.Subject
+		if isObservableObject(rec.Type, props[i]) {
+			rec.ReportedBy = []string{mspID}
+		}
+		if err := c.putStixObject(ctx, rec); err != nil {
+			return nil, fmt.Errorf("failed to store object %d (%s) of bundle %s: %v", i, rec.ID, b.ID, err)
+		}
+		if err := c.updateObjectIndexes(ctx, rec.ID, nil, props[i]); err != nil {
+			return nil, err
+		}
+		if err := c.resolvePendingRefs(ctx, rec.ID); err != nil {
+			return nil, err
+		}
+		if err := c.recordPendingRefs(ctx, rec.ID, missing[i]); err != nil {
+			return nil, err
+		}
+		manifest.ObjectRefs = append(manifest.ObjectRefs, rec.ID)
+		events = append(events, newObjectEvent(ctx, createdEventName(rec.Type), rec, props[i], public))
+		if originals[i] != nil {
+			link, linkProps, err := c.linkDuplicate(ctx, mspID, sub.Subject, rec.ID, props[i], originals[i])
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, newObjectEvent(ctx, objectCreatedEvent, link, linkProps, public))
 		}
-		allData = append(allData, queryResponse.Value)
 	}
 
-	// Marshal the slice of RawMessage into one JSON array (of objects)
-	resultBytes, err := json.Marshal(allData)
+	manifestJSON, err := json.Marshal(manifest)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal allData to JSON: %v", err)
+		return nil, fmt.Errorf("failed to marshal bundle manifest: %v", err)
 	}
-	return resultBytes, nil
+	rec, err := newLedgerRecord(manifestJSON)
+	if err != nil {
+		return nil, err
+	}
+	rec.CreatorMSP = mspID
+	if err := c.putStixObject(ctx, rec); err != nil {
+		return nil, err
+	}
+	if err := c.emitEvents(ctx, events); err != nil {
+		return nil, err
+	}
+	report.Stored = true
+	return report, nil
 }
 
-// GetAllObjects2 returns a Go‐slice of RawMessage,
-// which Fabric ContractAPI will marshal into a JSON array of objects.
-func (c *CTIStixContract) GetAllObjects3(
+// ReadBundle rebuilds a stored Bundle from its manifest, with the current version of
+// each object it carried
+func (c *CTIStixContract) ReadBundle(
 	ctx contractapi.TransactionContextInterface,
-) ([]json.RawMessage, error) {
-	iterator, err := ctx.GetStub().GetStateByRange("", "")
+	id string,
+) (string, error) {
+	rec, err := c.getStixObject(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get state by range: %v", err)
+		return "", err
+	}
+	v, err := c.newObjectViewer(ctx)
+	if err != nil {
+		return "", err
 	}
-	defer iterator.Close()
 
-	// Each queryResponse.Value is already a []byte containing valid JSON for one object
-	var allData []json.RawMessage
-	for iterator.HasNext() {
-		queryResponse, err := iterator.Next()
+	var manifest bundleManifest
+	if err := json.Unmarshal(rec.Object, &manifest); err != nil {
+		return "", fmt.Errorf("failed to unmarshal bundle JSON: %v", err)
+	}
+	b := &Bundle{Type: "bundle", ID: manifest.ID, Objects: []json.RawMessage{}}
+	for _, ref := range manifest.ObjectRefs {
+		objRec, err := c.getStixObject(ctx, ref)
+		if err != nil {
+			return "", err
+		}
+		obj, err := v.view(objRec)
 		if err != nil {
-			return nil, fmt.Errorf("failed to iterate: %v", err)
+			return "", err
+		}
+		if obj != nil {
+			b.Objects = append(b.Objects, obj)
 		}
-		allData = append(allData, queryResponse.Value)
 	}
+	return resultJSON(b)
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 8) Paginated Listing
+// ──────────────────────────────────────────────────────────────────────────────
 
-	// Return the slice. ContractAPI will automatically JSON‐marshal it as:
-	//   [ { …first object… }, { …second object… }, … ]
-	return allData, nil
+const (
+	defaultPageSize = 100  // page size used when the caller passes 0
+	maxPageSize     = 1000 // upper bound on a single page
+)
+
+// ObjectPage is the response envelope of every paginated listing: one page of STIX
+// objects (as submitted) plus the bookmark to pass in for the next page
+type ObjectPage struct {
+	Objects  []json.RawMessage `json:"objects"`
+	Bookmark string            `json:"bookmark"` // empty when there are no more pages
 }
 
-// GetAllObjects returns a true JSON array of objects by returning a slice of generic maps.
-func (c *CTIStixContract) GetAllObjects4(
+// clampPageSize applies the default and maximum page size
+func clampPageSize(pageSize int32) int32 {
+	if pageSize <= 0 {
+		return defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		return maxPageSize
+	}
+	return pageSize
+}
+
+// ListObjects returns one page of stored STIX objects, optionally restricted to one
+// STIX type (empty objectType lists every type). Revoked objects are left out unless
+// includeRevoked is set, so a page may hold fewer than pageSize objects.
+func (c *CTIStixContract) ListObjects(
 	ctx contractapi.TransactionContextInterface,
-) ([]interface{}, error) {
-	iterator, err := ctx.GetStub().GetStateByRange("", "")
+	objectType string,
+	pageSize int32,
+	bookmark string,
+	includeRevoked bool,
+) (string, error) {
+	v, err := c.newObjectViewer(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get state by range: %v", err)
+		return "", err
+	}
+	var attributes []string
+	if objectType != "" {
+		attributes = []string{objectType}
+	}
+	iterator, meta, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(
+		stixObjectType, attributes, clampPageSize(pageSize), bookmark)
+	if err != nil {
+		return "", fmt.Errorf("failed to list objects: %v", err)
 	}
 	defer iterator.Close()
 
-	var allData []interface{}
+	page := &ObjectPage{Objects: []json.RawMessage{}}
 	for iterator.HasNext() {
 		queryResponse, err := iterator.Next()
 		if err != nil {
-			return nil, fmt.Errorf("failed to iterate: %v", err)
+			return "", fmt.Errorf("failed to iterate: %v", err)
+		}
+		rec, err := parseLedgerRecord(queryResponse.Value)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %v", queryResponse.Key, err)
+		}
+		if rec.Revoked && !includeRevoked {
+			continue
 		}
-		// Unmarshal each world‐state value (which is []byte JSON) into a generic map
-		var obj map[string]interface{}
-		if err := json.Unmarshal(queryResponse.Value, &obj); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal world‐state value: %v", err)
+		obj, err := v.view(rec)
+		if err != nil {
+			return "", err
+		}
+		if obj != nil {
+			page.Objects = append(page.Objects, obj)
 		}
-		allData = append(allData, obj)
 	}
-
-	// Return the slice of maps. ContractAPI will JSON‐marshal it as [ {...}, {...} ]
-	return allData, nil
+	page.Bookmark = meta.Bookmark
+	return resultJSON(page)
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
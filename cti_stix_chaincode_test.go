@@ -0,0 +1,563 @@
+// File: cti_stix_chaincode_test.go
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Mock Ledger and Identities
+// ──────────────────────────────────────────────────────────────────────────────
+
+// mockIdentity is a client identity with a fixed MSP, certificate subject and
+// cti.role attribute
+type mockIdentity struct {
+	mspID string
+	cert  *x509.Certificate
+	attrs map[string]string
+}
+
+// newMockIdentity returns a client of an organization; roles is the cti.role value,
+// or "" for a certificate without one
+func newMockIdentity(mspID, commonName, roles string, admin bool) *mockIdentity {
+	subject := pkix.Name{CommonName: commonName, Organization: []string{mspID}}
+	if admin {
+		subject.OrganizationalUnit = []string{"admin"}
+	}
+	id := &mockIdentity{mspID: mspID, cert: &x509.Certificate{Subject: subject}, attrs: map[string]string{}}
+	if roles != "" {
+		id.attrs[roleAttribute] = roles
+	}
+	return id
+}
+
+func (m *mockIdentity) GetID() (string, error)    { return "x509::" + m.cert.Subject.String(), nil }
+func (m *mockIdentity) GetMSPID() (string, error) { return m.mspID, nil }
+func (m *mockIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return m.cert, nil
+}
+func (m *mockIdentity) GetAttributeValue(name string) (string, bool, error) {
+	value, found := m.attrs[name]
+	return value, found, nil
+}
+func (m *mockIdentity) AssertAttributeValue(name, value string) error {
+	if m.attrs[name] != value {
+		return fmt.Errorf("attribute %s is not %s", name, value)
+	}
+	return nil
+}
+
+// mockContext is the transaction context of one client over a shared MockStub
+type mockContext struct {
+	stub     *shimtest.MockStub
+	history  keyHistory
+	identity *mockIdentity
+}
+
+func (m *mockContext) GetStub() shim.ChaincodeStubInterface {
+	return &pagedStub{MockStub: m.stub, history: m.history}
+}
+func (m *mockContext) GetClientIdentity() cid.ClientIdentity { return m.identity }
+
+// pagedStub adds the paginated range queries and key history that MockStub leaves
+// unimplemented. Like Fabric it returns a bookmark to resume from, which it encodes
+// so that a test fails if the contract builds bookmarks itself instead of passing
+// back the returned one.
+type pagedStub struct {
+	*shimtest.MockStub
+	history keyHistory
+	args    []string // function and parameters of a transaction invoked through contract-api-go
+}
+
+// keyHistory holds every write to each key, oldest first
+type keyHistory map[string][]*queryresult.KeyModification
+
+func (s *pagedStub) PutState(key string, value []byte) error {
+	if err := s.MockStub.PutState(key, value); err != nil {
+		return err
+	}
+	s.record(key, value, false)
+	return nil
+}
+
+func (s *pagedStub) DelState(key string) error {
+	if err := s.MockStub.DelState(key); err != nil {
+		return err
+	}
+	s.record(key, nil, true)
+	return nil
+}
+
+// record adds a write of the current transaction to the history of its key
+func (s *pagedStub) record(key string, value []byte, isDelete bool) {
+	if s.history != nil {
+		s.history[key] = append(s.history[key], &queryresult.KeyModification{
+			TxId: s.TxID, Value: value, Timestamp: s.TxTimestamp, IsDelete: isDelete,
+		})
+	}
+}
+
+func (s *pagedStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &historyIterator{mods: append([]*queryresult.KeyModification{}, s.history[key]...)}, nil
+}
+
+func (s *pagedStub) GetFunctionAndParameters() (string, []string) {
+	if len(s.args) == 0 {
+		return "", nil
+	}
+	return s.args[0], s.args[1:]
+}
+
+func (s *pagedStub) GetArgs() [][]byte {
+	args := make([][]byte, len(s.args))
+	for i, arg := range s.args {
+		args[i] = []byte(arg)
+	}
+	return args
+}
+
+func (s *pagedStub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	iterator, err := s.GetStateByRange(startKey, endKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.page(iterator, pageSize, bookmark)
+}
+
+func (s *pagedStub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	iterator, err := s.GetStateByPartialCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.page(iterator, pageSize, bookmark)
+}
+
+// GetQueryResultWithPagination fails as on a peer whose state database is LevelDB
+func (s *pagedStub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	return nil, nil, fmt.Errorf("ExecuteQueryWithPagination not supported for leveldb")
+}
+
+// page reads up to pageSize records from the key the bookmark encodes onwards
+func (s *pagedStub) page(iterator shim.StateQueryIteratorInterface, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	defer iterator.Close()
+	start, err := hex.DecodeString(bookmark)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid bookmark '%s'", bookmark)
+	}
+	page := &kvIterator{}
+	meta := &pb.QueryResponseMetadata{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if kv.Key < string(start) {
+			continue
+		}
+		if int32(len(page.kvs)) == pageSize {
+			meta.Bookmark = hex.EncodeToString([]byte(kv.Key))
+			break
+		}
+		page.kvs = append(page.kvs, kv)
+	}
+	meta.FetchedRecordsCount = int32(len(page.kvs))
+	return page, meta, nil
+}
+
+// kvIterator iterates over one page of records
+type kvIterator struct {
+	kvs []*queryresult.KV
+}
+
+func (it *kvIterator) HasNext() bool { return len(it.kvs) > 0 }
+func (it *kvIterator) Close() error  { return nil }
+func (it *kvIterator) Next() (*queryresult.KV, error) {
+	kv := it.kvs[0]
+	it.kvs = it.kvs[1:]
+	return kv, nil
+}
+
+// historyIterator iterates over the history of one key, newest first as in Fabric
+type historyIterator struct {
+	mods []*queryresult.KeyModification
+}
+
+func (it *historyIterator) HasNext() bool { return len(it.mods) > 0 }
+func (it *historyIterator) Close() error  { return nil }
+func (it *historyIterator) Next() (*queryresult.KeyModification, error) {
+	mod := it.mods[len(it.mods)-1]
+	it.mods = it.mods[:len(it.mods)-1]
+	return mod, nil
+}
+
+// mockLedger runs the transactions of a test against one MockStub
+type mockLedger struct {
+	t       *testing.T
+	stub    *shimtest.MockStub
+	history keyHistory
+	clock   time.Time // transaction time of the next transactions; zero for the wall clock
+	txNum   int
+}
+
+func newMockLedger(t *testing.T) *mockLedger {
+	return &mockLedger{t: t, stub: shimtest.NewMockStub("cti", nil), history: keyHistory{}}
+}
+
+// invoke runs fn as one transaction submitted by id. The MockStub keeps the writes
+// of a failed transaction, which Fabric would discard, so checking that a rejected
+// transaction stored nothing is stricter than Fabric requires.
+func (l *mockLedger) invoke(id *mockIdentity, fn func(ctx contractapi.TransactionContextInterface) error) error {
+	txID := l.startTransaction()
+	defer l.stub.MockTransactionEnd(txID)
+	return fn(&mockContext{stub: l.stub, history: l.history, identity: id})
+}
+
+// startTransaction starts the next transaction at the ledger's clock and returns its ID
+func (l *mockLedger) startTransaction() string {
+	l.txNum++
+	txID := fmt.Sprintf("tx%d", l.txNum)
+	l.stub.MockTransactionStart(txID)
+	if !l.clock.IsZero() {
+		l.stub.TxTimestamp.Seconds, l.stub.TxTimestamp.Nanos = l.clock.Unix(), int32(l.clock.Nanosecond())
+	}
+	return txID
+}
+
+// invokeChaincode runs one transaction through contract-api-go, as a peer would,
+// with the given serialized identity as its creator
+func (l *mockLedger) invokeChaincode(cc shim.Chaincode, creator []byte, args ...string) pb.Response {
+	l.stub.Creator = creator
+	txID := l.startTransaction()
+	defer l.stub.MockTransactionEnd(txID)
+	return cc.Invoke(&pagedStub{MockStub: l.stub, history: l.history, args: args})
+}
+
+// create stores a STIX object submitted by id, failing the test if it is rejected
+func (l *mockLedger) create(id *mockIdentity, props map[string]interface{}) {
+	l.t.Helper()
+	raw := stixObject(l.t, props)
+	err := l.invoke(id, func(ctx contractapi.TransactionContextInterface) error {
+		return (&CTIStixContract{}).CreateObject(ctx, string(raw))
+	})
+	if err != nil {
+		l.t.Fatalf("CreateObject(%s): %v", raw, err)
+	}
+}
+
+// initChannelAdmins runs the init transaction that makes the given organizations
+// channel admins
+func (l *mockLedger) initChannelAdmins(admin *mockIdentity, mspIDs ...string) {
+	l.t.Helper()
+	err := l.invoke(admin, func(ctx contractapi.TransactionContextInterface) error {
+		return (&CTIStixContract{}).InitChannelAdmins(ctx, mspIDs)
+	})
+	if err != nil {
+		l.t.Fatal(err)
+	}
+}
+
+// events drains the events set since the last call
+func (l *mockLedger) events() []string {
+	var names []string
+	for {
+		select {
+		case e := <-l.stub.ChaincodeEventsChannel:
+			names = append(names, e.EventName)
+		default:
+			return names
+		}
+	}
+}
+
+// exists reports whether a STIX object is stored
+func (l *mockLedger) exists(id string) bool {
+	l.t.Helper()
+	var exists bool
+	err := l.invoke(newMockIdentity("Org1MSP", "reader", "", false), func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		exists, err = (&CTIStixContract{}).stixObjectExists(ctx, id)
+		return err
+	})
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	return exists
+}
+
+// stixObject renders a STIX object of a test from its properties
+func stixObject(t *testing.T, props map[string]interface{}) json.RawMessage {
+	t.Helper()
+	raw, err := json.Marshal(props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// sdo returns the common properties of an SDO or SRO created at a fixed time
+func sdo(typ, id string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":         typ,
+		"spec_version": "2.1",
+		"id":           typ + "--" + id,
+		"created":      "2025-05-01T12:15:00.000Z",
+		"modified":     "2025-05-01T12:15:00.000Z",
+	}
+}
+
+// testIndicator returns an indicator on an IPv4 address
+func testIndicator(id, ip string) map[string]interface{} {
+	props := sdo("indicator", id)
+	props["pattern"] = "[ipv4-addr:value = '" + ip + "']"
+	props["pattern_type"] = "stix"
+	props["valid_from"] = "2025-05-01T00:00:00Z"
+	return props
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Bundle Tests
+// ──────────────────────────────────────────────────────────────────────────────
+
+func TestCreateBundleStoresEveryObject(t *testing.T) {
+	l := newMockLedger(t)
+	producer := newMockIdentity("Org1MSP", "analyst", "producer", false)
+
+	malware := sdo("malware", "31b940d4-6f7f-459a-80ea-9c1f17b5891b")
+	malware["name"], malware["is_family"] = "Poison Ivy", true
+	rel := sdo("relationship", "44298a74-ba52-4f0c-87a3-1824e67d7fad")
+	rel["relationship_type"] = "indicates"
+	rel["source_ref"] = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+	rel["target_ref"] = "malware--31b940d4-6f7f-459a-80ea-9c1f17b5891b"
+
+	// The relationship comes first and refers forward within the bundle
+	bundle := stixObject(t, map[string]interface{}{
+		"type": "bundle",
+		"id":   "bundle--5d0092c5-5f74-4287-9642-33f4c354e56d",
+		"objects": []json.RawMessage{
+			stixObject(t, rel),
+			stixObject(t, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45")),
+			stixObject(t, malware),
+		},
+	})
+	var report *BundleReport
+	err := l.invoke(producer, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		report, err = (&CTIStixContract{}).CreateBundle(ctx, string(bundle))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Stored || len(report.Objects) != 3 {
+		t.Fatalf("report %+v", report)
+	}
+	for _, id := range []string{
+		"relationship--44298a74-ba52-4f0c-87a3-1824e67d7fad",
+		"indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f",
+		"malware--31b940d4-6f7f-459a-80ea-9c1f17b5891b",
+		"bundle--5d0092c5-5f74-4287-9642-33f4c354e56d",
+	} {
+		if !l.exists(id) {
+			t.Errorf("%s was not stored", id)
+		}
+	}
+	if events := l.events(); len(events) != 1 {
+		t.Errorf("events %v, want a single event for the transaction", events)
+	}
+
+	var b Bundle
+	err = l.invoke(producer, func(ctx contractapi.TransactionContextInterface) error {
+		bundleJSON, err := (&CTIStixContract{}).ReadBundle(ctx, "bundle--5d0092c5-5f74-4287-9642-33f4c354e56d")
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(bundleJSON), &b)
+	})
+	if err != nil || len(b.Objects) != 3 {
+		t.Fatalf("ReadBundle = %v, %v", b, err)
+	}
+}
+
+func TestCreateBundleIsAtomic(t *testing.T) {
+	valid := stixObject(t, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45"))
+	existing := stixObject(t, testIndicator("0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21", "198.51.100.7"))
+
+	dangling := sdo("relationship", "44298a74-ba52-4f0c-87a3-1824e67d7fad")
+	dangling["relationship_type"] = "indicates"
+	dangling["source_ref"] = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+	dangling["target_ref"] = "malware--31b940d4-6f7f-459a-80ea-9c1f17b5891b" // neither stored nor in the bundle
+
+	badConfidence := testIndicator("b2cc9b2c-6c5d-4d5a-8a4f-6b4bd0c7c2a9", "192.0.2.1")
+	badConfidence["confidence"] = 150
+
+	revoked := testIndicator("d3c1e0a6-8a4f-4e8b-9f5e-1a2b3c4d5e6f", "192.0.2.2")
+	revoked["revoked"] = true // producers may not revoke
+
+	tests := []struct {
+		name    string
+		objects []json.RawMessage
+		errText string
+	}{
+		{"invalid object last", []json.RawMessage{valid, stixObject(t, badConfidence)}, "confidence"},
+		{"dangling reference", []json.RawMessage{valid, stixObject(t, dangling)}, "malware--31b940d4-6f7f-459a-80ea-9c1f17b5891b"},
+		{"object already stored", []json.RawMessage{valid, existing}, "already exists"},
+		{"same id twice", []json.RawMessage{valid, valid}, "more than once"},
+		{"revoked without the revoke permission", []json.RawMessage{valid, stixObject(t, revoked)}, "may not revoke"},
+		{"not JSON", []json.RawMessage{valid, json.RawMessage(`"indicator"`)}, "rejected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newMockLedger(t)
+			producer := newMockIdentity("Org1MSP", "analyst", "producer", false)
+			err := l.invoke(producer, func(ctx contractapi.TransactionContextInterface) error {
+				return (&CTIStixContract{}).CreateObject(ctx, string(existing))
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			l.events()
+
+			bundle := stixObject(t, map[string]interface{}{
+				"type":    "bundle",
+				"id":      "bundle--5d0092c5-5f74-4287-9642-33f4c354e56d",
+				"objects": tt.objects,
+			})
+			err = l.invoke(producer, func(ctx contractapi.TransactionContextInterface) error {
+				_, err := (&CTIStixContract{}).CreateBundle(ctx, string(bundle))
+				return err
+			})
+			if err == nil {
+				t.Fatal("bundle was accepted")
+			}
+			if !strings.Contains(err.Error(), "no object was stored") || !strings.Contains(err.Error(), tt.errText) {
+				t.Errorf("error %q does not report %q", err, tt.errText)
+			}
+			for _, id := range []string{
+				"indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f",
+				"bundle--5d0092c5-5f74-4287-9642-33f4c354e56d",
+			} {
+				if l.exists(id) {
+					t.Errorf("%s was stored by a rejected bundle", id)
+				}
+			}
+			if events := l.events(); len(events) != 0 {
+				t.Errorf("rejected bundle emitted %v", events)
+			}
+		})
+	}
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 3) Contract API Tests
+// ──────────────────────────────────────────────────────────────────────────────
+
+// serializedIdentity returns a transaction creator as the peer passes it: the MSP ID
+// and a certificate carrying cti.role the way Fabric CA encodes attributes
+func serializedIdentity(t *testing.T, mspID, commonName, roles string) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs, err := json.Marshal(map[string]map[string]string{"attrs": {roleAttribute: roles}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		Subject:         pkix.Name{CommonName: commonName, Organization: []string{mspID}},
+		NotBefore:       time.Now().Add(-time.Hour),
+		NotAfter:        time.Now().Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{{Id: asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}, Value: attrs}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creator, err := proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   mspID,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return creator
+}
+
+// TestTransactionsThroughContractAPI invokes the transactions that return STIX
+// objects through contract-api-go, which checks every response against the schema
+// it derives from the Go return type
+func TestTransactionsThroughContractAPI(t *testing.T) {
+	cc, err := contractapi.NewChaincode(&CTIStixContract{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := newMockLedger(t)
+	creator := serializedIdentity(t, "Org1MSP", "analyst", "producer")
+
+	// The property order and the large integer only survive if the object is returned as stored
+	indicator := `{"type":"indicator","spec_version":"2.1","id":"indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f",` +
+		`"created_by_ref":"` + orgIdentityID("Org1MSP") + `","created":"2025-05-01T12:15:00.000Z","modified":"2025-05-01T12:15:00.000Z",` +
+		`"x_acme_case":12345678901234567,"pattern":"[ipv4-addr:value = '203.0.113.45']","pattern_type":"stix","valid_from":"2025-05-01T00:00:00Z"}`
+	malware := sdo("malware", "31b940d4-6f7f-459a-80ea-9c1f17b5891b")
+	malware["name"], malware["is_family"], malware["created_by_ref"] = "Poison Ivy", true, orgIdentityID("Org1MSP")
+	malwareJSON := stixObject(t, malware)
+	bundle := stixObject(t, map[string]interface{}{
+		"type":    "bundle",
+		"id":      "bundle--5d0092c5-5f74-4287-9642-33f4c354e56d",
+		"objects": []json.RawMessage{malwareJSON},
+	})
+	for _, args := range [][]string{{"CreateObject", indicator}, {"CreateBundle", string(bundle)}} {
+		if res := l.invokeChaincode(cc, creator, args...); res.Status != shim.OK {
+			t.Fatalf("%s: %s", args[0], res.Message)
+		}
+	}
+
+	tests := []struct {
+		args []string
+		want string // JSON that the response must carry verbatim
+	}{
+		{[]string{"ReadObject", "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "false"}, indicator},
+		{[]string{"ListObjects", "indicator", "10", "", "false"}, indicator},
+		{[]string{"ListActiveIndicators", "", "10", ""}, indicator},
+		{[]string{"LookupObservable", "ipv4-addr", "203.0.113.45"}, indicator},
+		{[]string{"GetRelatedObjects", "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "1", "[]", "[]"}, indicator},
+		{[]string{"ExportBundle", `{"types":["indicator"]}`, ""}, indicator},
+		{[]string{"ReadBundle", "bundle--5d0092c5-5f74-4287-9642-33f4c354e56d"}, string(malwareJSON)},
+		{[]string{"GetOrgIdentity", ""}, `"id":"` + orgIdentityID("Org1MSP") + `"`},
+		{[]string{"ReportSighting", "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "2", "", ""}, `"count":2`},
+	}
+	for _, tt := range tests {
+		res := l.invokeChaincode(cc, creator, tt.args...)
+		if res.Status != shim.OK {
+			t.Errorf("%s: %s", tt.args[0], res.Message)
+			continue
+		}
+		if !json.Valid(res.Payload) || !strings.Contains(string(res.Payload), tt.want) {
+			t.Errorf("%s returned %s, want it to carry %s", tt.args[0], res.Payload, tt.want)
+		}
+	}
+}
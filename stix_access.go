@@ -0,0 +1,294 @@
+// File: stix_access.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Roles and Permissions
+// ──────────────────────────────────────────────────────────────────────────────
+
+// roleAttribute is the Fabric CA certificate attribute that carries the CTI role(s)
+// of a client, e.g. “cti.role=producer” or “cti.role=producer,reviewer”
+const roleAttribute = "cti.role"
+
+// Actions governed by the permission table
+const (
+	actionCreate  = "create"  // CreateObject, Create*, CreateBundle, CreatePrivateObject, CreateCTIMetadata
+	actionUpdate  = "update"  // UpdateObject
+	actionRevoke  = "revoke"  // RevokeObject, and any submission that sets revoked
+	actionApprove = "approve" // ApproveObject
+	actionRead    = "read"    // object, private-object and metadata reads, lookups and queries
+)
+
+// permissionActions lists every action a permission table may grant
+var permissionActions = []string{actionCreate, actionUpdate, actionRevoke, actionApprove, actionRead}
+
+// PermissionTable maps every action to the roles that may perform it. Certificates
+// without a cti.role attribute act with DefaultRoles; an empty list locks them out.
+type PermissionTable struct {
+	DefaultRoles []string            `json:"default_roles"` // roles of clients whose certificate has no cti.role
+	Permissions  map[string][]string `json:"permissions"`   // action → roles allowed to perform it
+}
+
+// defaultPermissionTable applies until channel admins set their own. Certificates
+// issued before roles were introduced keep producing and reviewing.
+func defaultPermissionTable() *PermissionTable {
+	return &PermissionTable{
+		DefaultRoles: []string{"producer", "reviewer"},
+		Permissions: map[string][]string{
+			actionCreate:  {"producer"},
+			actionUpdate:  {"producer"},
+			actionRevoke:  {"reviewer"},
+			actionApprove: {"reviewer"},
+			actionRead:    {"producer", "reviewer", "consumer"},
+		},
+	}
+}
+
+// getPermissionTable reads the permission table, falling back to the defaults
+func (c *CTIStixContract) getPermissionTable(ctx contractapi.TransactionContextInterface) (*PermissionTable, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{"permissions"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key for permission table: %v", err)
+	}
+	bytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read permission table: %v", err)
+	}
+	if bytes == nil {
+		return defaultPermissionTable(), nil
+	}
+
+	table := &PermissionTable{}
+	if err := json.Unmarshal(bytes, table); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal permission table: %v", err)
+	}
+	return table, nil
+}
+
+// getSubmitterRoles returns the CTI roles of the submitter, read from the cti.role
+// attribute of its certificate, or nil if the certificate does not carry one
+func (c *CTIStixContract) getSubmitterRoles(ctx contractapi.TransactionContextInterface) ([]string, error) {
+	value, found, err := ctx.GetClientIdentity().GetAttributeValue(roleAttribute)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s attribute: %v", roleAttribute, err)
+	}
+	if !found {
+		return nil, nil
+	}
+	var roles []string
+	for _, role := range strings.Split(value, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+// requirePermission fails unless one of the submitter's roles may perform the action
+func (c *CTIStixContract) requirePermission(ctx contractapi.TransactionContextInterface, action string) error {
+	table, err := c.getPermissionTable(ctx)
+	if err != nil {
+		return err
+	}
+	roles, err := c.getSubmitterRoles(ctx)
+	if err != nil {
+		return err
+	}
+	if roles == nil {
+		roles = table.DefaultRoles
+	}
+	for _, allowed := range table.Permissions[action] {
+		for _, role := range roles {
+			if role == allowed {
+				return nil
+			}
+		}
+	}
+
+	sub, err := c.getSubmitterIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	if len(roles) == 0 {
+		return fmt.Errorf("submitter '%s' (%s) has no %s attribute and may not %s", sub.CommonName, sub.MSPID, roleAttribute, action)
+	}
+	return fmt.Errorf("role %s of submitter '%s' (%s) may not %s", strings.Join(roles, ","), sub.CommonName, sub.MSPID, action)
+}
+
+// requireRevokePermission checks the revoke action for a submitted object that sets
+// revoked, so that a producer cannot store a revoked object through a create function
+func (c *CTIStixContract) requireRevokePermission(
+	ctx contractapi.TransactionContextInterface,
+	props map[string]json.RawMessage,
+) error {
+	var revoked bool
+	if err := json.Unmarshal(props["revoked"], &revoked); err != nil || !revoked {
+		return nil
+	}
+	return c.requirePermission(ctx, actionRevoke)
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Permission Table Methods
+// ──────────────────────────────────────────────────────────────────────────────
+
+// GetPermissionTable returns the role-based permission table of the channel
+func (c *CTIStixContract) GetPermissionTable(ctx contractapi.TransactionContextInterface) (*PermissionTable, error) {
+	return c.getPermissionTable(ctx)
+}
+
+// SetPermissionTable replaces the role-based permission table; only channel admins
+// may call it. Actions left out of the table are denied to every role.
+func (c *CTIStixContract) SetPermissionTable(ctx contractapi.TransactionContextInterface, jsonStr string) error {
+	if err := c.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	var table PermissionTable
+	if err := json.Unmarshal([]byte(jsonStr), &table); err != nil {
+		return fmt.Errorf("failed to parse permission table JSON: %v", err)
+	}
+	known := make(map[string]bool, len(permissionActions))
+	for _, action := range permissionActions {
+		known[action] = true
+	}
+	actions := make([]string, 0, len(table.Permissions))
+	for action := range table.Permissions {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		if !known[action] {
+			return fmt.Errorf("unknown action '%s', expected one of %s", action, strings.Join(permissionActions, ", "))
+		}
+		for _, role := range table.Permissions[action] {
+			if strings.TrimSpace(role) == "" || strings.Contains(role, ",") {
+				return fmt.Errorf("action '%s': invalid role '%s'", action, role)
+			}
+		}
+	}
+	if table.Permissions == nil {
+		table.Permissions = map[string][]string{}
+	}
+	if table.DefaultRoles == nil {
+		table.DefaultRoles = []string{}
+	}
+
+	bytes, err := json.Marshal(table)
+	if err != nil {
+		return fmt.Errorf("failed to marshal permission table: %v", err)
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{"permissions"})
+	if err != nil {
+		return fmt.Errorf("failed to create key for permission table: %v", err)
+	}
+	return c.putAsset(ctx, key, bytes)
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 3) Approvals
+// ──────────────────────────────────────────────────────────────────────────────
+
+// approvalObjectType is the composite-key namespace of approvals:
+// approvalObjectType + [STIX ID, approving MSP ID]
+const approvalObjectType = "approval"
+
+// Approval records that a reviewer of an organization vouched for a version of an object
+type Approval struct {
+	ObjectID        string `json:"object_id"`
+	Modified        string `json:"modified,omitempty"` // “modified” of the approved version
+	ApproverMSP     string `json:"approver_msp"`
+	ApproverSubject string `json:"approver_subject"`
+	Comment         string `json:"comment,omitempty"`
+	Timestamp       string `json:"timestamp"` // transaction timestamp of the approval
+	TxID            string `json:"tx_id"`
+}
+
+// ApproveObject records the submitting organization's approval of the current
+// version of a STIX object. A later approval by the same organization replaces the
+// earlier one, e.g. after the object was versioned.
+func (c *CTIStixContract) ApproveObject(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+	comment string,
+) (*Approval, error) {
+	if err := c.requirePermission(ctx, actionApprove); err != nil {
+		return nil, err
+	}
+
+	rec, _, err := c.readVisibleObject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Revoked {
+		return nil, fmt.Errorf("%s has been revoked and cannot be approved", id)
+	}
+	sub, err := c.getSubmitterIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now, err := c.txTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	approval := &Approval{
+		ObjectID:        id,
+		Modified:        rec.Modified,
+		ApproverMSP:     sub.MSPID,
+		ApproverSubject: sub.Subject,
+		Comment:         comment,
+		Timestamp:       formatStixTimestamp(now),
+		TxID:            ctx.GetStub().GetTxID(),
+	}
+	bytes, err := json.Marshal(approval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal approval of %s: %v", id, err)
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(approvalObjectType, []string{id, sub.MSPID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create approval key for %s: %v", id, err)
+	}
+	if err := c.putAsset(ctx, key, bytes); err != nil {
+		return nil, err
+	}
+	return approval, nil
+}
+
+// GetApprovals returns the approvals of a STIX object, one per organization
+func (c *CTIStixContract) GetApprovals(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+) ([]*Approval, error) {
+	if _, _, err := c.readVisibleObject(ctx, id); err != nil {
+		return nil, err
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(approvalObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read approvals of %s: %v", id, err)
+	}
+	defer iterator.Close()
+
+	approvals := []*Approval{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate: %v", err)
+		}
+		var approval Approval
+		if err := json.Unmarshal(queryResponse.Value, &approval); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal approval %s: %v", queryResponse.Key, err)
+		}
+		approvals = append(approvals, &approval)
+	}
+	return approvals, nil
+}
@@ -0,0 +1,158 @@
+// File: stix_access_test.go
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// permitted reports whether a client may perform an action, and the error if not
+func (l *mockLedger) permitted(id *mockIdentity, action string) error {
+	return l.invoke(id, func(ctx contractapi.TransactionContextInterface) error {
+		return (&CTIStixContract{}).requirePermission(ctx, action)
+	})
+}
+
+func TestDefaultPermissions(t *testing.T) {
+	l := newMockLedger(t)
+	tests := []struct {
+		roles   string
+		allowed []string
+	}{
+		{"producer", []string{actionCreate, actionUpdate, actionRead}},
+		{"reviewer", []string{actionRevoke, actionApprove, actionRead}},
+		{"consumer", []string{actionRead}},
+		{"producer, reviewer", permissionActions},
+		{"", permissionActions}, // no cti.role attribute: the default roles
+		{"auditor", nil},
+	}
+	for _, tt := range tests {
+		id := newMockIdentity("Org1MSP", "user", tt.roles, false)
+		allowed := map[string]bool{}
+		for _, action := range tt.allowed {
+			allowed[action] = true
+		}
+		for _, action := range permissionActions {
+			err := l.permitted(id, action)
+			if allowed[action] != (err == nil) {
+				t.Errorf("roles %q, %s: error %v", tt.roles, action, err)
+			}
+		}
+	}
+
+	if err := l.permitted(newMockIdentity("Org1MSP", "reader", "consumer", false), actionCreate); err == nil ||
+		!strings.Contains(err.Error(), "role consumer of submitter 'reader' (Org1MSP) may not create") {
+		t.Errorf("error %v", err)
+	}
+
+	// A producer cannot store an object that is already revoked
+	props := testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45")
+	props["revoked"] = true
+	err := l.invoke(newMockIdentity("Org1MSP", "analyst", "producer", false), func(ctx contractapi.TransactionContextInterface) error {
+		return (&CTIStixContract{}).CreateObject(ctx, string(stixObject(t, props)))
+	})
+	if err == nil || !strings.Contains(err.Error(), "may not revoke") {
+		t.Errorf("revoked create: error %v", err)
+	}
+}
+
+func TestSetPermissionTable(t *testing.T) {
+	l := newMockLedger(t)
+	admin := newMockIdentity("Org1MSP", "admin", "", true)
+	l.initChannelAdmins(admin, "Org1MSP")
+	set := func(id *mockIdentity, table string) error {
+		return l.invoke(id, func(ctx contractapi.TransactionContextInterface) error {
+			return (&CTIStixContract{}).SetPermissionTable(ctx, table)
+		})
+	}
+
+	for _, tt := range []struct {
+		client  *mockIdentity
+		table   string
+		errText string
+	}{
+		{newMockIdentity("Org1MSP", "analyst", "producer,reviewer", false), `{"permissions":{}}`, "only organization admins"},
+		{newMockIdentity("Org2MSP", "admin", "", true), `{"permissions":{}}`, "submitter is Org2MSP"},
+		{admin, `{"permissions":{"delete":["reviewer"]}}`, "unknown action 'delete'"},
+		{admin, `{"permissions":{"read":["consumer,auditor"]}}`, "action 'read': invalid role"},
+		{admin, `{"permissions":{"read":[" "]}}`, "action 'read': invalid role"},
+	} {
+		if err := set(tt.client, tt.table); err == nil || !strings.Contains(err.Error(), tt.errText) {
+			t.Errorf("SetPermissionTable(%s): error %v, want %q", tt.table, err, tt.errText)
+		}
+	}
+
+	// Actions left out are denied, and an empty default locks out clients without a role
+	table := `{"default_roles":[],"permissions":{"create":["consumer"],"read":["consumer","auditor"]}}`
+	if err := set(admin, table); err != nil {
+		t.Fatal(err)
+	}
+	var got *PermissionTable
+	err := l.invoke(admin, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		got, err = (&CTIStixContract{}).GetPermissionTable(ctx)
+		return err
+	})
+	if err != nil || len(got.DefaultRoles) != 0 || strings.Join(got.Permissions[actionRead], ",") != "consumer,auditor" {
+		t.Errorf("GetPermissionTable = %+v, %v", got, err)
+	}
+	for _, tt := range []struct {
+		roles, action string
+		allowed       bool
+	}{
+		{"consumer", actionCreate, true},
+		{"auditor", actionRead, true},
+		{"producer", actionCreate, false},
+		{"reviewer", actionRevoke, false},
+		{"", actionRead, false},
+	} {
+		err := l.permitted(newMockIdentity("Org1MSP", "user", tt.roles, false), tt.action)
+		if tt.allowed != (err == nil) {
+			t.Errorf("roles %q, %s: error %v", tt.roles, tt.action, err)
+		}
+	}
+	if err := l.permitted(newMockIdentity("Org1MSP", "user", "", false), actionRead); err == nil ||
+		!strings.Contains(err.Error(), "has no cti.role attribute and may not read") {
+		t.Errorf("error %v", err)
+	}
+}
+
+func TestApproveObject(t *testing.T) {
+	const id = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+	l := newMockLedger(t)
+	l.create(newMockIdentity("Org1MSP", "analyst", "producer", false), testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45"))
+	approve := func(client *mockIdentity, comment string) error {
+		return l.invoke(client, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := (&CTIStixContract{}).ApproveObject(ctx, id, comment)
+			return err
+		})
+	}
+
+	if err := approve(newMockIdentity("Org2MSP", "analyst", "producer", false), ""); err == nil || !strings.Contains(err.Error(), "may not approve") {
+		t.Errorf("producer approval: error %v", err)
+	}
+	for _, comment := range []string{"looks right", "confirmed by our sensors"} {
+		if err := approve(newMockIdentity("Org2MSP", "lead", "reviewer", false), comment); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := approve(newMockIdentity("Org3MSP", "lead", "reviewer", false), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var approvals []*Approval
+	err := l.invoke(newMockIdentity("Org1MSP", "reader", "consumer", false), func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		approvals, err = (&CTIStixContract{}).GetApprovals(ctx, id)
+		return err
+	})
+	if err != nil || len(approvals) != 2 {
+		t.Fatalf("GetApprovals = %v, %v", approvals, err)
+	}
+	if a := approvals[0]; a.ApproverMSP != "Org2MSP" || a.Comment != "confirmed by our sensors" || a.Modified != "2025-05-01T12:15:00.000Z" || a.TxID == "" {
+		t.Errorf("approval %+v", a)
+	}
+}
@@ -0,0 +1,351 @@
+// File: stix_duplicates.go
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Pattern Fingerprints
+// ──────────────────────────────────────────────────────────────────────────────
+
+// fingerprintObjectType is the composite-key namespace of the duplicate index:
+// fingerprintObjectType + [pattern fingerprint, indicator ID]
+const fingerprintObjectType = "fingerprint"
+
+// canonicalPattern renders a parsed STIX pattern in a canonical form, so that
+// patterns that differ only in layout compare equal:
+//   - operands of AND and OR are sorted and deduplicated (FOLLOWEDBY keeps its order);
+//   - observable values are normalized as in the observable index, e.g. IPs become
+//     CIDR blocks and domain names are lower-cased, and hash names are upper-cased;
+//   - IN sets are sorted, a one-element IN becomes “=” and NOT = becomes “!=”.
+func canonicalPattern(expr observationExpr) string {
+	switch o := expr.(type) {
+	case obsBracket:
+		return "[" + canonicalComparison(o.Comparison) + "]"
+	case obsQualified:
+		inner := canonicalPattern(o.Expr)
+		if _, ok := o.Expr.(obsBinary); ok {
+			inner = "(" + inner + ")"
+		}
+		switch o.Qualifier {
+		case "WITHIN":
+			return inner + " WITHIN " + strings.Join(o.Args, " ") + " SECONDS"
+		case "REPEATS":
+			return inner + " REPEATS " + strings.Join(o.Args, " ") + " TIMES"
+		}
+		return inner + " " + o.Qualifier + " " + strings.Join(o.Args, " STOP ")
+	case obsBinary:
+		var operands []string
+		for _, operand := range flattenObservations(o.Op, o) {
+			s := canonicalPattern(operand)
+			if b, ok := operand.(obsBinary); ok && b.Op != o.Op {
+				s = "(" + s + ")"
+			}
+			operands = append(operands, s)
+		}
+		if o.Op != "FOLLOWEDBY" {
+			operands = sortedUnique(operands)
+		}
+		return strings.Join(operands, " "+o.Op+" ")
+	}
+	return ""
+}
+
+// flattenObservations returns the operands of a chain of one operator, so that
+// a AND (b AND c) and (a AND b) AND c give the same list
+func flattenObservations(op string, expr observationExpr) []observationExpr {
+	if b, ok := expr.(obsBinary); ok && b.Op == op {
+		return append(flattenObservations(op, b.Left), flattenObservations(op, b.Right)...)
+	}
+	return []observationExpr{expr}
+}
+
+// flattenComparisons is flattenObservations for the comparison layer
+func flattenComparisons(op string, expr comparisonExpr) []comparisonExpr {
+	if b, ok := expr.(cmpBinary); ok && b.Op == op {
+		return append(flattenComparisons(op, b.Left), flattenComparisons(op, b.Right)...)
+	}
+	return []comparisonExpr{expr}
+}
+
+// canonicalComparison renders a comparison expression in canonical form
+func canonicalComparison(cmp comparisonExpr) string {
+	switch c := cmp.(type) {
+	case cmpBinary:
+		var operands []string
+		for _, operand := range flattenComparisons(c.Op, c) {
+			s := canonicalComparison(operand)
+			if b, ok := operand.(cmpBinary); ok && b.Op != c.Op {
+				s = "(" + s + ")"
+			}
+			operands = append(operands, s)
+		}
+		return strings.Join(sortedUnique(operands), " "+c.Op+" ")
+	case cmpExists:
+		return "EXISTS " + canonicalPath(c.Path)
+	case cmpTest:
+		op, negated, value := c.Op, c.Negated, c.Value
+		if op == "IN" && len(value.Set) == 1 {
+			op, value = "=", value.Set[0]
+		}
+		if negated && op == "=" {
+			op, negated = "!=", false
+		} else if negated && op == "!=" {
+			op, negated = "=", false
+		}
+		if negated {
+			op = "NOT " + op
+		}
+		return canonicalPath(c.Path) + " " + op + " " + canonicalLiteral(c.Path, value)
+	}
+	return ""
+}
+
+// canonicalPath renders an object path, upper-casing the hash name of a hashes step
+func canonicalPath(path objectPath) string {
+	steps := append([]string(nil), path.Steps...)
+	for i := 1; i < len(steps); i++ {
+		if steps[i-1] == "hashes" {
+			steps[i] = "'" + strings.ToUpper(strings.Trim(steps[i], "'")) + "'"
+		}
+	}
+	return path.ObjectType + ":" + objectPath{Steps: steps}.property()
+}
+
+// canonicalLiteral renders a literal, normalizing the values the observable index
+// understands
+func canonicalLiteral(path objectPath, lit patternLiteral) string {
+	switch lit.Kind {
+	case "set":
+		members := make([]string, 0, len(lit.Set))
+		for _, member := range lit.Set {
+			members = append(members, canonicalLiteral(path, member))
+		}
+		return "(" + strings.Join(sortedUnique(members), ", ") + ")"
+	case "string":
+		value := lit.Value
+		if observableTypes[path.ObjectType] && path.property() == "value" {
+			if normalized, err := normalizeObservable(path.ObjectType, value); err == nil {
+				value = normalized
+			}
+		} else if len(path.Steps) > 0 && path.Steps[0] == "hashes" {
+			value = strings.ToLower(value)
+		}
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+	case "timestamp":
+		return "t'" + lit.Value + "'"
+	case "hex":
+		return "h'" + lit.Value + "'"
+	case "binary":
+		return "b'" + lit.Value + "'"
+	}
+	return lit.Value
+}
+
+// sortedUnique sorts strings and drops repeats
+func sortedUnique(values []string) []string {
+	sort.Strings(values)
+	out := values[:0]
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// patternFingerprint returns the hex SHA-256 of an indicator's canonical pattern.
+// Patterns in other languages are only stripped of surrounding and repeated whitespace.
+func patternFingerprint(patternType, pattern string) (string, error) {
+	canonical := strings.Join(strings.Fields(pattern), " ")
+	if patternType == "stix" {
+		expr, err := parseStixPattern(pattern)
+		if err != nil {
+			return "", err
+		}
+		canonical = canonicalPattern(expr)
+	}
+	sum := sha256.Sum256([]byte(patternType + "\n" + canonical))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// fingerprintKey returns the duplicate-index key of an unrevoked indicator, or ""
+// for any other object
+func fingerprintKey(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+	props map[string]json.RawMessage,
+) (string, error) {
+	if stringProperty(props, "type") != "indicator" {
+		return "", nil
+	}
+	var revoked bool
+	if err := json.Unmarshal(props["revoked"], &revoked); err == nil && revoked {
+		return "", nil
+	}
+	fp, err := patternFingerprint(stringProperty(props, "pattern_type"), stringProperty(props, "pattern"))
+	if err != nil {
+		return "", nil // an invalid pattern is reported by validation
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(fingerprintObjectType, []string{fp, id})
+	if err != nil {
+		return "", fmt.Errorf("failed to create fingerprint key for %s: %v", id, err)
+	}
+	return key, nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Duplicate Policy
+// ──────────────────────────────────────────────────────────────────────────────
+
+// Values of ChannelConfig.DuplicatePolicy
+const (
+	duplicateLink   = "link"   // store the indicator and link it to the original (default)
+	duplicateReject = "reject" // refuse the indicator
+	duplicateAllow  = "allow"  // store the indicator without a link
+)
+
+// duplicateOriginal is an indicator a new one duplicates, with the object markings
+// the duplicate-of link must carry
+type duplicateOriginal struct {
+	ID       string
+	Markings []string // object_marking_refs of the original
+}
+
+// findDuplicate applies the channel's duplicate policy to a new indicator. It returns
+// the indicator to link the new one to, or nil if there is none or the policy does
+// not link. The original is the earliest-created unrevoked indicator with the same
+// fingerprint that the submitter may see (v), on the ledger or, through pending
+// (fingerprint → indicator, nil outside bundles), earlier in the same bundle.
+// Indicators withheld from the submitter are ignored, so that neither a rejection
+// nor a link discloses them.
+func (c *CTIStixContract) findDuplicate(
+	ctx contractapi.TransactionContextInterface,
+	cfg *ChannelConfig,
+	v *objectViewer,
+	id string,
+	props map[string]json.RawMessage,
+	pending map[string]*duplicateOriginal,
+) (*duplicateOriginal, error) {
+	if stringProperty(props, "type") != "indicator" || cfg.DuplicatePolicy == duplicateAllow {
+		return nil, nil
+	}
+	fp, err := patternFingerprint(stringProperty(props, "pattern_type"), stringProperty(props, "pattern"))
+	if err != nil {
+		return nil, err
+	}
+
+	var original *duplicateOriginal
+	originalCreated := ""
+	ids, err := c.indexedIDs(ctx, fingerprintObjectType, []string{fp})
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range ids {
+		if other == id {
+			continue
+		}
+		rec, err := c.getStixObject(ctx, other)
+		if err != nil {
+			return nil, err
+		}
+		obj, err := v.view(rec)
+		if err != nil {
+			return nil, err
+		}
+		if obj == nil {
+			continue
+		}
+		var header versionHeader
+		if err := json.Unmarshal(rec.Object, &header); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s JSON: %v", other, err)
+		}
+		if original == nil || header.Created < originalCreated {
+			var otherProps map[string]json.RawMessage
+			if err := json.Unmarshal(rec.Object, &otherProps); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal %s JSON: %v", other, err)
+			}
+			original = &duplicateOriginal{ID: other, Markings: markingRefs(otherProps)}
+			originalCreated = header.Created
+		}
+	}
+	if original == nil && pending != nil {
+		original = pending[fp]
+	}
+	if pending != nil && pending[fp] == nil {
+		pending[fp] = &duplicateOriginal{ID: id, Markings: markingRefs(props)}
+	}
+
+	if original == nil {
+		return nil, nil
+	}
+	if cfg.DuplicatePolicy == duplicateReject {
+		return nil, fmt.Errorf("indicator %s duplicates the pattern of %s", id, original.ID)
+	}
+	return original, nil
+}
+
+// linkDuplicate stores a duplicate-of relationship from a new indicator to the
+// original. Its id is derived from both, so the link is written only once. It
+// carries the markings of both ends, granular ones as object markings, so that it is
+// never more widely visible than either. The link does not go through
+// checkObjectRefs or checkRelationshipTypes. The new indicator, written in the same
+// transaction, cannot be read back for the reference check; findDuplicate only picks
+// an original the submitter may see; and duplicate-of joins any two indicators.
+func (c *CTIStixContract) linkDuplicate(
+	ctx contractapi.TransactionContextInterface,
+	mspID, subject string,
+	duplicateID string,
+	duplicateProps map[string]json.RawMessage,
+	original *duplicateOriginal,
+) (*ledgerRecord, map[string]json.RawMessage, error) {
+	now, err := c.txTimestamp(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	ts := formatStixTimestamp(now)
+	link := map[string]interface{}{
+		"type":              "relationship",
+		"spec_version":      "2.1",
+		"id":                derivedID("relationship", "duplicate-of", duplicateID, original.ID),
+		"created":           ts,
+		"modified":          ts,
+		"created_by_ref":    orgIdentityID(mspID),
+		"relationship_type": "duplicate-of",
+		"source_ref":        duplicateID,
+		"target_ref":        original.ID,
+	}
+	if markings := unionStrings(nil, append(markingRefs(duplicateProps), original.Markings...)); len(markings) > 0 {
+		link["object_marking_refs"] = markings
+	}
+	linkJSON, err := json.Marshal(link)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal duplicate-of relationship: %v", err)
+	}
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(linkJSON, &props); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse duplicate-of relationship: %v", err)
+	}
+
+	rec, err := newLedgerRecord(linkJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+	rec.CreatorMSP, rec.CreatorSubject = mspID, subject
+	if err := c.putStixObject(ctx, rec); err != nil {
+		return nil, nil, err
+	}
+	if err := c.updateObjectIndexes(ctx, rec.ID, nil, props); err != nil {
+		return nil, nil, err
+	}
+	return rec, props, nil
+}
@@ -0,0 +1,161 @@
+// File: stix_duplicates_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestPatternFingerprint(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"[ipv4-addr:value = '203.0.113.45']", "[ipv4-addr:value = '203.0.113.45/32']", true},
+		{"[ipv4-addr:value = '203.0.113.45']", "[ ipv4-addr:value   =  '203.0.113.45' ]", true},
+		{"[domain-name:value = 'Example.COM.']", "[domain-name:value = 'example.com']", true},
+		{"[file:hashes.'sha-256' = 'ABCDEF']", "[file:hashes.'SHA-256' = 'abcdef']", true},
+		{"[ipv4-addr:value = '198.51.100.7' OR ipv4-addr:value = '203.0.113.45']", "[ipv4-addr:value = '203.0.113.45' OR ipv4-addr:value = '198.51.100.7']", true},
+		{"[ipv4-addr:value = '203.0.113.45' AND ipv4-addr:value = '203.0.113.45']", "[ipv4-addr:value = '203.0.113.45']", true},
+		{"[ipv4-addr:value IN ('203.0.113.45')]", "[ipv4-addr:value = '203.0.113.45']", true},
+		{"[ipv4-addr:value IN ('203.0.113.45', '198.51.100.7')]", "[ipv4-addr:value IN ('198.51.100.7', '203.0.113.45')]", true},
+		{"[ipv4-addr:value NOT = '203.0.113.45']", "[ipv4-addr:value != '203.0.113.45']", true},
+		{"[ipv4-addr:value = '203.0.113.45'] OR [domain-name:value = 'example.com']", "[domain-name:value = 'example.com'] OR [ipv4-addr:value = '203.0.113.45']", true},
+		{"[ipv4-addr:value = '203.0.113.45'] FOLLOWEDBY [domain-name:value = 'example.com']", "[domain-name:value = 'example.com'] FOLLOWEDBY [ipv4-addr:value = '203.0.113.45']", false},
+		{"[ipv4-addr:value = '203.0.113.45']", "[ipv4-addr:value = '203.0.113.46']", false},
+		{"[ipv4-addr:value = '203.0.113.45']", "[ipv4-addr:value != '203.0.113.45']", false},
+		{"[domain-name:value = 'example.com']", "[url:value = 'example.com']", false},
+	}
+	for _, tt := range tests {
+		a, err := patternFingerprint("stix", tt.a)
+		if err != nil {
+			t.Fatalf("patternFingerprint(%s): %v", tt.a, err)
+		}
+		b, err := patternFingerprint("stix", tt.b)
+		if err != nil {
+			t.Fatalf("patternFingerprint(%s): %v", tt.b, err)
+		}
+		if (a == b) != tt.same {
+			t.Errorf("fingerprints of %s and %s: same = %v, want %v", tt.a, tt.b, a == b, tt.same)
+		}
+	}
+
+	// Other pattern languages only lose surrounding and repeated whitespace
+	a, _ := patternFingerprint("sigma", "  title: x\n  detection:   y ")
+	b, _ := patternFingerprint("sigma", "title: x detection: y")
+	c, _ := patternFingerprint("snort", "title: x detection: y")
+	if a != b || b == c {
+		t.Errorf("non-STIX fingerprints: %s %s %s", a, b, c)
+	}
+}
+
+func TestDuplicatePolicy(t *testing.T) {
+	const (
+		originalID  = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+		duplicateID = "indicator--0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21"
+	)
+	linkID := derivedID("relationship", "duplicate-of", duplicateID, originalID)
+	tests := []struct {
+		policy   string
+		marking  string // object marking of the original
+		errText  string
+		linked   bool
+		linkSeen string // MSP that must be able to read the link
+	}{
+		{"", tlpGreen, "", true, "Org3MSP"},
+		{"link", tlpAmber, "", true, "Org2MSP"},
+		{"reject", tlpGreen, "duplicates the pattern of " + originalID, false, ""},
+		{"allow", tlpGreen, "", false, ""},
+		{"reject", tlpRed, "", false, ""}, // a withheld original is ignored
+		{"link", tlpRed, "", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy+" "+tt.marking, func(t *testing.T) {
+			l := newMockLedger(t)
+			admin := newMockIdentity("Org1MSP", "admin", "", true)
+			l.initChannelAdmins(admin, "Org1MSP")
+			err := l.invoke(admin, func(ctx contractapi.TransactionContextInterface) error {
+				c := &CTIStixContract{}
+				if err := c.SetChannelConfig(ctx, `{"duplicate_policy":"`+tt.policy+`"}`); err != nil {
+					return err
+				}
+				return c.SetSharingPolicy(ctx, `{"rules":[{"marking_ref":"`+tlpAmber+`","allowed_msps":["Org2MSP"]}]}`)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			original := testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45")
+			original["object_marking_refs"] = []string{tt.marking}
+			l.create(newMockIdentity("Org1MSP", "analyst", "producer", false), original)
+			l.events()
+
+			duplicate := testIndicator("0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21", "203.0.113.45/32")
+			duplicate["object_marking_refs"] = []string{tlpGreen}
+			org2 := newMockIdentity("Org2MSP", "analyst", "producer", false)
+			err = l.invoke(org2, func(ctx contractapi.TransactionContextInterface) error {
+				return (&CTIStixContract{}).CreateObject(ctx, string(stixObject(t, duplicate)))
+			})
+			if tt.errText != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errText) {
+					t.Fatalf("error %v, want %q", err, tt.errText)
+				}
+				if l.exists(duplicateID) {
+					t.Error("rejected duplicate was stored")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.exists(linkID) != tt.linked {
+				t.Fatalf("link stored = %v, want %v", !tt.linked, tt.linked)
+			}
+			if events := l.events(); tt.linked != (len(events) == 1 && events[0] == objectEventBatch) {
+				t.Errorf("events %v", events)
+			}
+			if !tt.linked {
+				return
+			}
+
+			var rel *Relationship
+			err = l.invoke(newMockIdentity(tt.linkSeen, "reader", "", false), func(ctx contractapi.TransactionContextInterface) error {
+				var err error
+				rel, err = (&CTIStixContract{}).ReadRelationship(ctx, linkID, false)
+				return err
+			})
+			if err != nil {
+				t.Fatalf("%s reading the link: %v", tt.linkSeen, err)
+			}
+			if rel.RelationshipType != "duplicate-of" || rel.SourceRef != duplicateID || rel.TargetRef != originalID ||
+				rel.CreatedByRef != orgIdentityID("Org2MSP") || strings.Join(rel.ObjectMarkingRefs, ",") != strings.Join(unionStrings([]string{tlpGreen}, []string{tt.marking}), ",") {
+				t.Errorf("link %+v", rel)
+			}
+		})
+	}
+}
+
+// A bundle's second indicator with a known pattern is linked to the first
+func TestDuplicatesWithinABundle(t *testing.T) {
+	first := stixObject(t, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45"))
+	second := stixObject(t, testIndicator("0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21", "203.0.113.45/32"))
+	bundle := stixObject(t, map[string]interface{}{
+		"type":    "bundle",
+		"id":      "bundle--5d0092c5-5f74-4287-9642-33f4c354e56d",
+		"objects": []json.RawMessage{first, second},
+	})
+	l := newMockLedger(t)
+	err := l.invoke(newMockIdentity("Org1MSP", "analyst", "producer", false), func(ctx contractapi.TransactionContextInterface) error {
+		_, err := (&CTIStixContract{}).CreateBundle(ctx, string(bundle))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link := derivedID("relationship", "duplicate-of", "indicator--0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21", "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"); !l.exists(link) {
+		t.Errorf("%s was not stored", link)
+	}
+}
@@ -0,0 +1,129 @@
+// File: stix_events.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Chaincode Events
+// ──────────────────────────────────────────────────────────────────────────────
+
+// Chaincode event names. Fabric keeps a single event per transaction, so a
+// transaction that stores several objects emits objectEventBatch with all of them.
+const (
+	objectCreatedEvent    = "ObjectCreated"
+	objectVersionedEvent  = "ObjectVersioned"
+	objectRevokedEvent    = "ObjectRevoked"
+	sightingReportedEvent = "SightingReported"
+	observableMergedEvent = "ObservableMerged"
+	objectEventBatch      = "ObjectEvents"
+)
+
+// ObjectEvent is the payload of a chaincode event: enough for a subscriber to decide
+// whether to fetch the object, but none of its content. Observables, the sighted
+// object and the revocation reason are left out when a marking restricts the object.
+type ObjectEvent struct {
+	Event       string   `json:"event"` // one of the event names above
+	ID          string   `json:"id"`
+	Type        string   `json:"type"`
+	CreatorMSP  string   `json:"creator_msp"`
+	Modified    string   `json:"modified,omitempty"`
+	Markings    []string `json:"markings,omitempty"`        // object_marking_refs
+	Observables []string `json:"observables,omitempty"`     // observable keys, "<type>:<normalized value>"
+	SightingOf  string   `json:"sighting_of_ref,omitempty"` // SightingReported only
+	Reason      string   `json:"reason,omitempty"`          // ObjectRevoked only
+	ReportedBy  string   `json:"reported_by,omitempty"`     // ObservableMerged only: the resubmitting org
+	Restricted  bool     `json:"restricted,omitempty"`      // not every channel member may read the object
+	Collection  string   `json:"collection,omitempty"`      // private data collection holding the object
+	TxID        string   `json:"tx_id"`
+}
+
+// restricts reports whether a marking of an object, object-level or granular, keeps
+// some channel member from reading all of it
+func (v *objectViewer) restricts(props map[string]json.RawMessage) bool {
+	for _, ref := range markingRefs(props) {
+		if !v.allowed(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// newObjectEvent builds the event of a stored object version. The viewer is the
+// channel-wide one from publicViewer; events are readable by every member.
+func newObjectEvent(
+	ctx contractapi.TransactionContextInterface,
+	name string,
+	rec *ledgerRecord,
+	props map[string]json.RawMessage,
+	public *objectViewer,
+) ObjectEvent {
+	event := ObjectEvent{
+		Event:      name,
+		ID:         rec.ID,
+		Type:       rec.Type,
+		CreatorMSP: rec.CreatorMSP,
+		Modified:   rec.Modified,
+		Markings:   stringListProperty(props, "object_marking_refs"),
+		Restricted: public.restricts(props),
+		TxID:       ctx.GetStub().GetTxID(),
+	}
+	if event.Restricted {
+		return event
+	}
+	for _, obs := range objectObservables(rec.Type, props) {
+		event.Observables = append(event.Observables, obs.Type+":"+obs.Value)
+	}
+	if name == sightingReportedEvent {
+		event.SightingOf = stringProperty(props, "sighting_of_ref")
+	}
+	return event
+}
+
+// createdEventName returns the event of a newly stored object: SightingReported
+// for sightings, ObjectCreated for everything else
+func createdEventName(typ string) string {
+	if typ == "sighting" {
+		return sightingReportedEvent
+	}
+	return objectCreatedEvent
+}
+
+// emitEvents sets the chaincode event of the transaction: the single event under its
+// own name, or several under objectEventBatch with a JSON array payload
+func (c *CTIStixContract) emitEvents(ctx contractapi.TransactionContextInterface, events []ObjectEvent) error {
+	switch len(events) {
+	case 0:
+		return nil
+	case 1:
+		payload, err := json.Marshal(events[0])
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s event: %v", events[0].Event, err)
+		}
+		return ctx.GetStub().SetEvent(events[0].Event, payload)
+	}
+	payload, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %v", objectEventBatch, err)
+	}
+	return ctx.GetStub().SetEvent(objectEventBatch, payload)
+}
+
+// emitObjectEvent sets the event of a transaction that stored a single object version
+func (c *CTIStixContract) emitObjectEvent(
+	ctx contractapi.TransactionContextInterface,
+	name string,
+	rec *ledgerRecord,
+	props map[string]json.RawMessage,
+) error {
+	public, err := c.publicViewer(ctx)
+	if err != nil {
+		return err
+	}
+	return c.emitEvents(ctx, []ObjectEvent{newObjectEvent(ctx, name, rec, props, public)})
+}
@@ -0,0 +1,155 @@
+// File: stix_events_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// objectEvents drains the events set since the last call and decodes their
+// payloads; a batch contributes each of its events
+func (l *mockLedger) objectEvents() (names []string, events []ObjectEvent, payloads string) {
+	l.t.Helper()
+	for {
+		select {
+		case e := <-l.stub.ChaincodeEventsChannel:
+			names = append(names, e.EventName)
+			payloads += string(e.Payload)
+			if e.EventName == objectEventBatch {
+				var batch []ObjectEvent
+				if err := json.Unmarshal(e.Payload, &batch); err != nil {
+					l.t.Fatal(err)
+				}
+				events = append(events, batch...)
+				continue
+			}
+			var event ObjectEvent
+			if err := json.Unmarshal(e.Payload, &event); err != nil {
+				l.t.Fatal(err)
+			}
+			events = append(events, event)
+		default:
+			return names, events, payloads
+		}
+	}
+}
+
+func TestObjectEvents(t *testing.T) {
+	const (
+		green = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+		red   = "indicator--0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21"
+	)
+	l := newMockLedger(t)
+	org1 := newMockIdentity("Org1MSP", "analyst", "", false)
+	contract := &CTIStixContract{}
+	greenProps := testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45")
+	greenProps["object_marking_refs"] = []string{tlpGreen}
+	redProps := testIndicator("0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21", "198.51.100.7")
+	redProps["object_marking_refs"] = []string{tlpRed}
+	redProps["description"] = "internal sensor"
+	versioned := testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45")
+	versioned["object_marking_refs"], versioned["modified"] = []string{tlpGreen}, "2025-05-02T08:00:00.000Z"
+
+	steps := []struct {
+		name string
+		fn   func(ctx contractapi.TransactionContextInterface) error
+		want ObjectEvent // TxID is checked separately
+	}{
+		{"create", func(ctx contractapi.TransactionContextInterface) error {
+			return contract.CreateObject(ctx, string(stixObject(t, greenProps)))
+		}, ObjectEvent{Event: objectCreatedEvent, ID: green, Type: "indicator", CreatorMSP: "Org1MSP", Modified: "2025-05-01T12:15:00.000Z",
+			Markings: []string{tlpGreen}, Observables: []string{"ipv4-addr:203.0.113.45/32"}}},
+		{"create restricted", func(ctx contractapi.TransactionContextInterface) error {
+			return contract.CreateObject(ctx, string(stixObject(t, redProps)))
+		}, ObjectEvent{Event: objectCreatedEvent, ID: red, Type: "indicator", CreatorMSP: "Org1MSP", Modified: "2025-05-01T12:15:00.000Z",
+			Markings: []string{tlpRed}, Restricted: true}},
+		{"version", func(ctx contractapi.TransactionContextInterface) error {
+			return contract.UpdateObject(ctx, string(stixObject(t, versioned)))
+		}, ObjectEvent{Event: objectVersionedEvent, ID: green, Type: "indicator", CreatorMSP: "Org1MSP", Modified: "2025-05-02T08:00:00.000Z",
+			Markings: []string{tlpGreen}, Observables: []string{"ipv4-addr:203.0.113.45/32"}}},
+		{"sighting", func(ctx contractapi.TransactionContextInterface) error {
+			_, err := contract.ReportSighting(ctx, green, 2, "2025-05-02T09:00:00Z", "2025-05-02T10:00:00Z")
+			return err
+		}, ObjectEvent{Event: sightingReportedEvent, ID: orgSightingID("Org1MSP", green), Type: "sighting", CreatorMSP: "Org1MSP", SightingOf: green}},
+		{"revoke restricted", func(ctx contractapi.TransactionContextInterface) error {
+			return contract.RevokeObject(ctx, red, "sensor misfired")
+		}, ObjectEvent{Event: objectRevokedEvent, ID: red, Type: "indicator", CreatorMSP: "Org1MSP", Markings: []string{tlpRed}, Restricted: true}},
+		{"revoke", func(ctx contractapi.TransactionContextInterface) error {
+			return contract.RevokeObject(ctx, green, "false positive")
+		}, ObjectEvent{Event: objectRevokedEvent, ID: green, Type: "indicator", CreatorMSP: "Org1MSP", Markings: []string{tlpGreen},
+			Observables: []string{"ipv4-addr:203.0.113.45/32"}, Reason: "false positive"}},
+	}
+	for _, step := range steps {
+		if err := l.invoke(org1, step.fn); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		names, events, payloads := l.objectEvents()
+		if len(names) != 1 || names[0] != step.want.Event || len(events) != 1 {
+			t.Fatalf("%s: events %v", step.name, names)
+		}
+		got := events[0]
+		if want := fmt.Sprintf("tx%d", l.txNum); got.TxID != want {
+			t.Errorf("%s: tx_id %q, want %q", step.name, got.TxID, want)
+		}
+		// Modified is set by the chaincode for sightings and revocations
+		if step.want.Modified == "" {
+			got.Modified = ""
+		}
+		got.TxID = ""
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: event %+v, want %+v", step.name, got, step.want)
+		}
+		for _, secret := range []string{"198.51.100.7", "internal sensor", "sensor misfired"} {
+			if strings.Contains(payloads, secret) {
+				t.Errorf("%s: payload %s discloses %q", step.name, payloads, secret)
+			}
+		}
+	}
+
+	// A rejected transaction sets no event
+	if err := l.invoke(org1, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CreateObject(ctx, string(stixObject(t, greenProps)))
+	}); err == nil {
+		t.Fatal("a duplicate id was accepted")
+	}
+	if names, _, _ := l.objectEvents(); len(names) != 0 {
+		t.Errorf("rejected transaction set events %v", names)
+	}
+}
+
+// A transaction that stores several objects sets one batch event listing them all
+func TestObjectEventBatch(t *testing.T) {
+	malware := sdo("malware", "31b940d4-6f7f-459a-80ea-9c1f17b5891b")
+	malware["name"], malware["is_family"] = "Poison Ivy", true
+	bundle := stixObject(t, map[string]interface{}{
+		"type": "bundle",
+		"id":   "bundle--5d0092c5-5f74-4287-9642-33f4c354e56d",
+		"objects": []json.RawMessage{
+			stixObject(t, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45")),
+			stixObject(t, malware),
+		},
+	})
+	l := newMockLedger(t)
+	err := l.invoke(newMockIdentity("Org1MSP", "analyst", "producer", false), func(ctx contractapi.TransactionContextInterface) error {
+		_, err := (&CTIStixContract{}).CreateBundle(ctx, string(bundle))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, events, _ := l.objectEvents()
+	if len(names) != 1 || names[0] != objectEventBatch || len(events) != 2 {
+		t.Fatalf("events %v: %+v", names, events)
+	}
+	for i, id := range []string{"indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", malware["id"].(string)} {
+		if events[i].Event != objectCreatedEvent || events[i].ID != id || events[i].TxID != events[0].TxID {
+			t.Errorf("event %d: %+v", i, events[i])
+		}
+	}
+}
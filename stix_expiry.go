@@ -0,0 +1,234 @@
+// File: stix_expiry.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Expiry Index
+// ──────────────────────────────────────────────────────────────────────────────
+
+// expiryIndexPrefix starts the keys of the expiry index, expiry:<day>:<indicator ID>,
+// where <day> is the UTC date of valid_until. Fabric does not allow range queries
+// over composite keys, so these are simple keys that GetStateByRange can scan.
+const expiryIndexPrefix = "expiry"
+
+// openEndedBucket is the day bucket of indicators without valid_until
+const openEndedBucket = "9999-12-31"
+
+// maxExpiryWindow bounds the window of ListExpiringIndicators
+const maxExpiryWindow = 366 * 24 * time.Hour
+
+// expiryBucket returns the day bucket of a point in time
+func expiryBucket(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// expiryKey returns the expiry-index key of an unrevoked indicator, or "" for any
+// other object
+func expiryKey(id string, props map[string]json.RawMessage) string {
+	if stringProperty(props, "type") != "indicator" {
+		return ""
+	}
+	var revoked bool
+	if err := json.Unmarshal(props["revoked"], &revoked); err == nil && revoked {
+		return ""
+	}
+	bucket := openEndedBucket
+	if t, err := parseStixTimestamp(stringProperty(props, "valid_until")); err == nil {
+		bucket = expiryBucket(t)
+	}
+	return expiryIndexPrefix + ":" + bucket + ":" + id
+}
+
+// expiryRangeEnd returns the exclusive end key of a scan that includes the given day
+func expiryRangeEnd(bucket string) string {
+	return expiryIndexPrefix + ":" + bucket + ";" // ';' sorts right after ':'
+}
+
+// indicatorValidity returns the valid_from and valid_until of an indicator; until is
+// the zero time if the indicator does not expire
+func indicatorValidity(obj json.RawMessage) (from, until time.Time, err error) {
+	var ind Indicator
+	if err := json.Unmarshal(obj, &ind); err != nil {
+		return from, until, fmt.Errorf("failed to unmarshal indicator JSON: %v", err)
+	}
+	if from, err = parseStixTimestamp(ind.ValidFrom); err != nil {
+		return from, until, fmt.Errorf("invalid valid_from '%s'", ind.ValidFrom)
+	}
+	if ind.ValidUntil != "" {
+		if until, err = parseStixTimestamp(ind.ValidUntil); err != nil {
+			return from, until, fmt.Errorf("invalid valid_until '%s'", ind.ValidUntil)
+		}
+	}
+	return from, until, nil
+}
+
+// parseExpiryWindow parses a window such as "72h" or "30d"
+func parseExpiryWindow(window string) (time.Duration, error) {
+	if days := strings.TrimSuffix(window, "d"); days != window {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid window '%s'", window)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil {
+		return 0, fmt.Errorf("invalid window '%s': %v", window, err)
+	}
+	return d, nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Expiry Methods
+// ──────────────────────────────────────────────────────────────────────────────
+
+// ListActiveIndicators returns one page of the unrevoked indicators that are valid at
+// asOf (a STIX timestamp, the transaction time if empty): valid_from is not later
+// and valid_until, if set, is later. Only the expiry buckets from asOf onwards are
+// scanned. A page may hold fewer than pageSize indicators.
+func (c *CTIStixContract) ListActiveIndicators(
+	ctx contractapi.TransactionContextInterface,
+	asOf string,
+	pageSize int32,
+	bookmark string,
+) (string, error) {
+	v, err := c.newObjectViewer(ctx)
+	if err != nil {
+		return "", err
+	}
+	at, err := c.txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	if asOf != "" {
+		if at, err = parseStixTimestamp(asOf); err != nil {
+			return "", fmt.Errorf("asOf must be an RFC 3339 timestamp, got '%s'", asOf)
+		}
+	}
+
+	iterator, meta, err := ctx.GetStub().GetStateByRangeWithPagination(
+		expiryIndexPrefix+":"+expiryBucket(at), expiryRangeEnd(openEndedBucket), clampPageSize(pageSize), bookmark)
+	if err != nil {
+		return "", fmt.Errorf("failed to read expiry index: %v", err)
+	}
+	defer iterator.Close()
+
+	page := &ObjectPage{Objects: []json.RawMessage{}}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return "", fmt.Errorf("failed to iterate: %v", err)
+		}
+		id := queryResponse.Key[strings.LastIndex(queryResponse.Key, ":")+1:]
+		rec, err := c.getStixObject(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		from, until, err := indicatorValidity(rec.Object)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", id, err)
+		}
+		if from.After(at) || (!until.IsZero() && !until.After(at)) {
+			continue
+		}
+		obj, err := v.view(rec)
+		if err != nil {
+			return "", err
+		}
+		if obj != nil {
+			page.Objects = append(page.Objects, obj)
+		}
+	}
+	page.Bookmark = meta.Bookmark
+	return resultJSON(page)
+}
+
+// ListExpiringIndicators returns one page of the unrevoked indicators whose
+// valid_until falls within window (e.g. "72h" or "30d", at most a year) after the
+// transaction time. Pages follow the expiry day; within a page the indicators come
+// soonest first. A page may hold fewer than pageSize indicators.
+func (c *CTIStixContract) ListExpiringIndicators(
+	ctx contractapi.TransactionContextInterface,
+	window string,
+	pageSize int32,
+	bookmark string,
+) (string, error) {
+	v, err := c.newObjectViewer(ctx)
+	if err != nil {
+		return "", err
+	}
+	d, err := parseExpiryWindow(window)
+	if err != nil {
+		return "", err
+	}
+	if d <= 0 || d > maxExpiryWindow {
+		return "", fmt.Errorf("window must be positive and at most %s", maxExpiryWindow)
+	}
+	now, err := c.txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	end := now.Add(d)
+
+	iterator, meta, err := ctx.GetStub().GetStateByRangeWithPagination(
+		expiryIndexPrefix+":"+expiryBucket(now), expiryRangeEnd(expiryBucket(end)), clampPageSize(pageSize), bookmark)
+	if err != nil {
+		return "", fmt.Errorf("failed to read expiry index: %v", err)
+	}
+	defer iterator.Close()
+
+	type expiring struct {
+		until time.Time
+		id    string
+		obj   json.RawMessage
+	}
+	var found []expiring
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return "", fmt.Errorf("failed to iterate: %v", err)
+		}
+		id := queryResponse.Key[strings.LastIndex(queryResponse.Key, ":")+1:]
+		rec, err := c.getStixObject(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		_, until, err := indicatorValidity(rec.Object)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", id, err)
+		}
+		if until.IsZero() || !until.After(now) || until.After(end) {
+			continue
+		}
+		obj, err := v.view(rec)
+		if err != nil {
+			return "", err
+		}
+		if obj != nil {
+			found = append(found, expiring{until: until, id: id, obj: obj})
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if !found[i].until.Equal(found[j].until) {
+			return found[i].until.Before(found[j].until)
+		}
+		return found[i].id < found[j].id
+	})
+	page := &ObjectPage{Objects: make([]json.RawMessage, 0, len(found)), Bookmark: meta.Bookmark}
+	for _, f := range found {
+		page.Objects = append(page.Objects, f.obj)
+	}
+	return resultJSON(page)
+}
@@ -0,0 +1,193 @@
+// File: stix_expiry_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// expiryIndicator returns an indicator valid from 2025-05-01 until validUntil, or
+// without an end if validUntil is empty
+func expiryIndicator(uuid, validUntil string) map[string]interface{} {
+	props := testIndicator(uuid, "203.0.113.45")
+	if validUntil != "" {
+		props["valid_until"] = validUntil
+	}
+	return props
+}
+
+// pageIDs returns the ids of the objects of a page, in order
+func pageIDs(t *testing.T, out string) []string {
+	t.Helper()
+	var page ObjectPage
+	if err := json.Unmarshal([]byte(out), &page); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, obj := range page.Objects {
+		var header struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(obj, &header); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, strings.TrimPrefix(header.ID, "indicator--"))
+	}
+	return ids
+}
+
+func TestIndicatorExpiry(t *testing.T) {
+	const (
+		expiredToday = "00000000-0000-4000-8000-00000000000a"
+		laterToday   = "00000000-0000-4000-8000-00000000000b"
+		tomorrowAM   = "00000000-0000-4000-8000-00000000000c"
+		tomorrowPM   = "00000000-0000-4000-8000-00000000000d"
+		openEnded    = "00000000-0000-4000-8000-00000000000e"
+		notYetValid  = "00000000-0000-4000-8000-00000000000f"
+		expiredEarly = "00000000-0000-4000-8000-000000000010"
+		revoked      = "00000000-0000-4000-8000-000000000011"
+		withheld     = "00000000-0000-4000-8000-000000000012"
+	)
+	l := newMockLedger(t)
+	org1 := newMockIdentity("Org1MSP", "lead", "", false)
+	for _, ind := range []map[string]interface{}{
+		expiryIndicator(expiredToday, "2025-06-10T06:00:00Z"),
+		expiryIndicator(laterToday, "2025-06-10T18:00:00Z"),
+		expiryIndicator(tomorrowAM, "2025-06-11T11:00:00Z"),
+		expiryIndicator(tomorrowPM, "2025-06-11T13:00:00Z"),
+		expiryIndicator(openEnded, ""),
+		expiryIndicator(expiredEarly, "2025-06-01T00:00:00Z"),
+		expiryIndicator(revoked, "2025-06-10T20:00:00Z"),
+	} {
+		l.create(org1, ind)
+	}
+	future := expiryIndicator(notYetValid, "2025-08-01T00:00:00Z")
+	future["valid_from"] = "2025-07-01T00:00:00Z"
+	l.create(org1, future)
+	red := expiryIndicator(withheld, "2025-06-10T19:00:00Z")
+	red["object_marking_refs"] = []string{tlpRed}
+	l.create(org1, red)
+	err := l.invoke(org1, func(ctx contractapi.TransactionContextInterface) error {
+		return (&CTIStixContract{}).RevokeObject(ctx, "indicator--"+revoked, "reassigned")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every listing runs at noon on 2025-06-10, as Org2MSP
+	l.clock = time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	org2 := newMockIdentity("Org2MSP", "reader", "", false)
+	active := func(asOf string) ([]string, error) {
+		var out string
+		err := l.invoke(org2, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			out, err = (&CTIStixContract{}).ListActiveIndicators(ctx, asOf, 0, "")
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return pageIDs(t, out), nil
+	}
+	expiring := func(window string) ([]string, error) {
+		var out string
+		err := l.invoke(org2, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			out, err = (&CTIStixContract{}).ListExpiringIndicators(ctx, window, 0, "")
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return pageIDs(t, out), nil
+	}
+
+	for _, tt := range []struct {
+		asOf string
+		want []string
+	}{
+		{"", []string{laterToday, tomorrowAM, tomorrowPM, openEnded}},
+		{"2025-06-10T18:00:00Z", []string{tomorrowAM, tomorrowPM, openEnded}}, // valid_until is exclusive
+		{"2025-06-11T12:00:00Z", []string{tomorrowPM, openEnded}},
+		{"2025-07-15T00:00:00Z", []string{notYetValid, openEnded}},
+		{"2025-04-30T00:00:00Z", nil},
+	} {
+		got, err := active(tt.asOf)
+		if err != nil || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("ListActiveIndicators(%q) = %v, %v; want %v", tt.asOf, got, err, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		window string
+		want   []string
+	}{
+		{"24h", []string{laterToday, tomorrowAM}},
+		{"1d", []string{laterToday, tomorrowAM}},
+		{"25h", []string{laterToday, tomorrowAM, tomorrowPM}},
+		{"60d", []string{laterToday, tomorrowAM, tomorrowPM, notYetValid}},
+	} {
+		got, err := expiring(tt.window)
+		if err != nil || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("ListExpiringIndicators(%q) = %v, %v; want %v", tt.window, got, err, tt.want)
+		}
+	}
+
+	if _, err := active("2025-06-10"); err == nil || !strings.Contains(err.Error(), "asOf must be an RFC 3339 timestamp") {
+		t.Errorf("invalid asOf: error %v", err)
+	}
+	for _, window := range []string{"0h", "-1d", "367d", "a week"} {
+		if _, err := expiring(window); err == nil {
+			t.Errorf("window %q was accepted", window)
+		}
+	}
+}
+
+func TestValidUntil(t *testing.T) {
+	l := newMockLedger(t)
+	org1 := newMockIdentity("Org1MSP", "analyst", "producer", false)
+	for _, until := range []string{"2025-05-01T00:00:00Z", "2025-04-30T23:59:59Z"} {
+		err := l.invoke(org1, func(ctx contractapi.TransactionContextInterface) error {
+			return (&CTIStixContract{}).CreateObject(ctx, string(stixObject(t, expiryIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", until))))
+		})
+		if err == nil || !strings.Contains(err.Error(), "must be later than valid_from") {
+			t.Errorf("valid_until %s: error %v", until, err)
+		}
+	}
+
+	// Versioning moves an indicator between expiry buckets
+	l.create(org1, expiryIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "2025-06-10T18:00:00Z"))
+	extended := expiryIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "2025-09-01T00:00:00Z")
+	extended["modified"] = "2025-06-01T00:00:00.000Z"
+	if err := l.update(org1, extended); err != nil {
+		t.Fatal(err)
+	}
+	l.clock = time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	var out string
+	err := l.invoke(org1, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		out, err = (&CTIStixContract{}).ListExpiringIndicators(ctx, "2d", 0, "")
+		return err
+	})
+	if err != nil || len(pageIDs(t, out)) != 0 {
+		t.Errorf("extended indicator still expiring: %s, %v", out, err)
+	}
+	keys, err := l.stub.GetStateByRange(expiryIndexPrefix+":", expiryRangeEnd(openEndedBucket))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer keys.Close()
+	var found []string
+	for keys.HasNext() {
+		kv, _ := keys.Next()
+		found = append(found, kv.Key)
+	}
+	if want := "expiry:2025-09-01:indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"; strings.Join(found, ",") != want {
+		t.Errorf("expiry keys %v, want %s", found, want)
+	}
+}
@@ -0,0 +1,335 @@
+// File: stix_export.go
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Export Filter
+// ──────────────────────────────────────────────────────────────────────────────
+
+// ExportFilter is the filter accepted by ExportBundle. Every field is optional; the
+// set fields are combined with AND.
+type ExportFilter struct {
+	Types          []string `json:"types,omitempty"`         // STIX types, any of them
+	Labels         []string `json:"labels,omitempty"`        // objects must carry all of these labels
+	CreatorMSP     string   `json:"creator_msp,omitempty"`   // MSP ID of the creating org
+	MarkingRef     string   `json:"marking_ref,omitempty"`   // must be among object_marking_refs
+	AddedAfter     string   `json:"added_after,omitempty"`   // exclusive, RFC 3339; versions stored later
+	RelatedDepth   int      `json:"related_depth,omitempty"` // 0-4 edges of related objects to add
+	IncludeRevoked bool     `json:"include_revoked,omitempty"`
+}
+
+// matches reports whether a stored object, as the caller sees it, passes the filter.
+// Labels and markings are matched on the visible object, so that a property withheld
+// by a granular marking cannot be probed with the filter.
+func (f *ExportFilter) matches(rec *ledgerRecord, obj json.RawMessage, addedAfter string) (bool, error) {
+	if rec.Revoked && !f.IncludeRevoked {
+		return false, nil
+	}
+	if f.CreatorMSP != "" && rec.CreatorMSP != f.CreatorMSP {
+		return false, nil
+	}
+	if addedAfter != "" && rec.Added <= addedAfter {
+		return false, nil // also leaves out versions stored before records were stamped
+	}
+	if len(f.Labels) == 0 && f.MarkingRef == "" {
+		return true, nil
+	}
+
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(obj, &props); err != nil {
+		return false, fmt.Errorf("failed to unmarshal %s JSON: %v", rec.ID, err)
+	}
+	labels := map[string]bool{}
+	for _, label := range stringListProperty(props, "labels") {
+		labels[label] = true
+	}
+	for _, label := range f.Labels {
+		if !labels[label] {
+			return false, nil
+		}
+	}
+	if f.MarkingRef == "" {
+		return true, nil
+	}
+	for _, ref := range stringListProperty(props, "object_marking_refs") {
+		if ref == f.MarkingRef {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Bundle Export
+// ──────────────────────────────────────────────────────────────────────────────
+
+// ExportPage is the result of ExportBundle: one bundle of the export, and the
+// bookmark that continues it
+type ExportPage struct {
+	Bundle    *Bundle `json:"bundle"`
+	Bookmark  string  `json:"bookmark,omitempty"`  // continues the export, empty once it is complete
+	Truncated bool    `json:"truncated,omitempty"` // a match's related objects alone exceeded the bounds and were cut
+}
+
+// exportScanPageSize is how many objects ExportBundle reads from the ledger at a time
+const exportScanPageSize = 200
+
+// maxExportScan bounds the objects one ExportBundle call reads. A selective filter
+// would otherwise read the whole ledger to fill a page; the call returns what it
+// found with a bookmark instead.
+const maxExportScan = 5 * exportScanPageSize
+
+// exportBookmark is where an export continues: the type being scanned ("" when every
+// type is), the ledger bookmark of the page holding the next object, and how many
+// objects of that page were already scanned. Callers get it base64-encoded.
+type exportBookmark struct {
+	Type string `json:"type,omitempty"`
+	Page string `json:"page,omitempty"`
+	Skip int    `json:"skip,omitempty"`
+}
+
+// encode renders the bookmark as ExportBundle returns it
+func (b *exportBookmark) encode() (string, error) {
+	raw, err := json.Marshal(b)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal export bookmark: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// parseExportBookmark decodes a bookmark returned by ExportBundle
+func parseExportBookmark(bookmark string) (*exportBookmark, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bookmark '%s'", bookmark)
+	}
+	var b exportBookmark
+	if err := json.Unmarshal(raw, &b); err != nil || b.Skip < 0 || b.Skip >= exportScanPageSize {
+		return nil, fmt.Errorf("invalid bookmark '%s'", bookmark)
+	}
+	return &b, nil
+}
+
+// exportScan is the state of an export while it fills a page
+type exportScan struct {
+	g          *subgraph
+	f          *ExportFilter
+	addedAfter string
+	full       bool            // no more matches fit; the next object scanned starts the next page
+	deferred   bool            // the object just scanned did not fit and starts the next page
+	scanned    int             // objects read from the ledger by this call
+	next       *exportBookmark // where the next page starts, once this one is done
+	truncated  bool
+}
+
+// ExportBundle returns the stored objects that match a filter (see ExportFilter) as
+// a STIX 2.1 bundle for partners outside the channel. The bundle gets a fresh id,
+// derived from the transaction ID. With related_depth set, the objects up to that
+// many relationships or sightings away from each match are added, as by
+// GetRelatedObjects. Only what the caller may see under the marking rules is
+// exported, and bundle manifests never are. A bundle holds at most 500 objects or
+// 2 MiB; a larger export is split into pages, each continued by passing the
+// returned bookmark. A match and its related objects always share a page. A call
+// reads at most maxExportScan objects, so a page may hold fewer objects than fit,
+// or none, and still have a bookmark.
+func (c *CTIStixContract) ExportBundle(
+	ctx contractapi.TransactionContextInterface,
+	filterJSON string,
+	bookmark string,
+) (string, error) {
+	var f ExportFilter
+	if filterJSON != "" {
+		if err := json.Unmarshal([]byte(filterJSON), &f); err != nil {
+			return "", fmt.Errorf("failed to parse export filter JSON: %v", err)
+		}
+	}
+	if f.RelatedDepth < 0 || f.RelatedDepth > maxGraphDepth {
+		return "", fmt.Errorf("related_depth must be between 0 and %d, got %d", maxGraphDepth, f.RelatedDepth)
+	}
+	addedAfter := ""
+	if f.AddedAfter != "" {
+		t, err := parseStixTimestamp(f.AddedAfter)
+		if err != nil {
+			return "", fmt.Errorf("added_after must be an RFC 3339 timestamp, got '%s'", f.AddedAfter)
+		}
+		addedAfter = formatStixTimestamp(t)
+	}
+	types := []string{""}
+	if len(f.Types) > 0 {
+		types = sortedUnique(append([]string(nil), f.Types...))
+	}
+	start, resume := &exportBookmark{Type: types[0]}, 0
+	if bookmark != "" {
+		var err error
+		if start, err = parseExportBookmark(bookmark); err != nil {
+			return "", err
+		}
+		resume = -1
+		for i, typ := range types {
+			if typ == start.Type {
+				resume = i
+			}
+		}
+		if resume < 0 {
+			return "", fmt.Errorf("bookmark does not belong to this filter")
+		}
+	}
+	v, err := c.newObjectViewer(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// The bookmark's type is resumed where it stopped, earlier types are done and
+	// later ones start from the beginning
+	s := &exportScan{g: newSubgraph(v), f: &f, addedAfter: addedAfter}
+	for i := resume; i < len(types) && s.next == nil; i++ {
+		from := &exportBookmark{Type: types[i]}
+		if i == resume {
+			from = start
+		}
+		if err := c.exportScanKeys(ctx, s, from); err != nil {
+			return "", err
+		}
+		if s.next == nil && s.scanned >= maxExportScan && i+1 < len(types) {
+			s.next = &exportBookmark{Type: types[i+1]}
+		}
+	}
+	next := ""
+	if s.next != nil {
+		var err error
+		if next, err = s.next.encode(); err != nil {
+			return "", err
+		}
+	}
+
+	objects := s.g.objects
+	if objects == nil {
+		objects = []json.RawMessage{}
+	}
+	return resultJSON(&ExportPage{
+		Bundle: &Bundle{
+			Type:    "bundle",
+			ID:      derivedID("bundle", ctx.GetStub().GetTxID()),
+			Objects: objects,
+		},
+		Bookmark:  next,
+		Truncated: s.truncated,
+	})
+}
+
+// exportScanKeys scans the objects of one type, or of every type, from a bookmark
+// until the page is full, the scan limit is reached or there are no more
+func (c *CTIStixContract) exportScanKeys(
+	ctx contractapi.TransactionContextInterface,
+	s *exportScan,
+	from *exportBookmark,
+) error {
+	page, skip := from.Page, from.Skip
+	for {
+		next, err := c.exportScanPage(ctx, s, from.Type, page, skip)
+		if err != nil || next == "" || s.next != nil {
+			return err
+		}
+		if s.full || s.scanned >= maxExportScan {
+			s.next = &exportBookmark{Type: from.Type, Page: next}
+			return nil
+		}
+		page, skip = next, 0
+	}
+}
+
+// exportScanPage scans one ledger page of objects, after the first skip of them, and
+// returns the ledger bookmark of the next page, or "" if there are no more
+func (c *CTIStixContract) exportScanPage(
+	ctx contractapi.TransactionContextInterface,
+	s *exportScan,
+	typ string,
+	page string,
+	skip int,
+) (string, error) {
+	var attributes []string
+	if typ != "" {
+		attributes = []string{typ}
+	}
+	iterator, meta, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(stixObjectType, attributes, exportScanPageSize, page)
+	if err != nil {
+		return "", fmt.Errorf("failed to list objects: %v", err)
+	}
+	defer iterator.Close()
+
+	for i := 0; iterator.HasNext(); i++ {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return "", fmt.Errorf("failed to iterate: %v", err)
+		}
+		if i < skip {
+			continue
+		}
+		if s.full {
+			s.next = &exportBookmark{Type: typ, Page: page, Skip: i}
+			return "", nil
+		}
+		rec, err := parseLedgerRecord(queryResponse.Value)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %v", queryResponse.Key, err)
+		}
+		s.scanned++
+		if err := c.exportObject(ctx, s, rec); err != nil {
+			return "", err
+		}
+		if s.deferred {
+			s.next = &exportBookmark{Type: typ, Page: page, Skip: i}
+			return "", nil
+		}
+	}
+	if meta == nil || meta.FetchedRecordsCount < exportScanPageSize {
+		return "", nil
+	}
+	return meta.Bookmark, nil
+}
+
+// exportObject adds a stored object to the page if it matches, with its related
+// objects. If they do not all fit, the object is left for the next page, unless the
+// page is empty: then as much as fits is kept and the page is marked truncated.
+func (c *CTIStixContract) exportObject(
+	ctx contractapi.TransactionContextInterface,
+	s *exportScan,
+	rec *ledgerRecord,
+) error {
+	if rec.Type == "bundle" {
+		return nil // a manifest, not a STIX object
+	}
+	obj, err := s.g.viewer.view(rec)
+	if err != nil || obj == nil {
+		return err
+	}
+	ok, err := s.f.matches(rec, obj, s.addedAfter)
+	if err != nil || !ok {
+		return err
+	}
+
+	n := len(s.g.objects)
+	if s.g.addObjects(map[string]json.RawMessage{rec.ID: obj}) {
+		if err := s.g.expand(c, ctx, []string{rec.ID}, s.f.RelatedDepth, nil, nil); err != nil {
+			return err
+		}
+	}
+	if !s.g.truncated {
+		return nil
+	}
+	if n > 0 {
+		s.g.rollback(n)
+		s.deferred = true
+		return nil
+	}
+	s.full, s.truncated = true, true
+	return nil
+}
@@ -0,0 +1,131 @@
+// File: stix_export_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// exportLedger stores n indicators with ordered ids; those for which labelled
+// returns true carry the label "needle"
+func exportLedger(t *testing.T, n int, labelled func(i int) bool) (*mockLedger, []string) {
+	l := newMockLedger(t)
+	producer := newMockIdentity("Org1MSP", "analyst", "producer", false)
+	ids := make([]string, n)
+	for i := range ids {
+		props := testIndicator(fmt.Sprintf("00000000-0000-4000-8000-%012d", i), fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+		if labelled(i) {
+			props["labels"] = []string{"needle"}
+		}
+		l.create(producer, props)
+		l.events() // the MockStub blocks once its event channel is full
+		ids[i] = props["id"].(string)
+	}
+	return l, ids
+}
+
+// export runs ExportBundle as an Org1 reader
+func (l *mockLedger) export(filter, bookmark string) (*ExportPage, error) {
+	var page ExportPage
+	err := l.invoke(newMockIdentity("Org1MSP", "reader", "", false), func(ctx contractapi.TransactionContextInterface) error {
+		result, err := (&CTIStixContract{}).ExportBundle(ctx, filter, bookmark)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(result), &page)
+	})
+	return &page, err
+}
+
+// bundleIDs returns the ids of the objects of an exported bundle
+func bundleIDs(t *testing.T, page *ExportPage) []string {
+	t.Helper()
+	var ids []string
+	for _, obj := range page.Bundle.Objects {
+		var header struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(obj, &header); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, header.ID)
+	}
+	return ids
+}
+
+func TestExportBundlePages(t *testing.T) {
+	l, ids := exportLedger(t, 650, func(int) bool { return false })
+	const filter = `{"types":["indicator"]}`
+
+	var pages [][]string
+	bookmark := ""
+	for {
+		page, err := l.export(filter, bookmark)
+		if err != nil {
+			t.Fatalf("page %d: %v", len(pages)+1, err)
+		}
+		pages = append(pages, bundleIDs(t, page))
+		if bookmark = page.Bookmark; bookmark == "" {
+			break
+		}
+		if strings.Contains(bookmark, "indicator--") {
+			t.Errorf("bookmark %s discloses an object id", bookmark)
+		}
+		if len(pages) > 3 {
+			t.Fatal("export does not end")
+		}
+	}
+	if len(pages) != 2 || len(pages[0]) != maxGraphObjects || len(pages[1]) != len(ids)-maxGraphObjects {
+		t.Fatalf("pages of %d objects, want %d and %d", len(pages), maxGraphObjects, len(ids)-maxGraphObjects)
+	}
+	if got := strings.Join(append(pages[0], pages[1]...), ","); got != strings.Join(ids, ",") {
+		t.Errorf("pages do not hold every indicator once, in key order")
+	}
+
+	// A bookmark only continues the export it came from
+	page, err := l.export(filter, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct{ filter, bookmark, errText string }{
+		{`{"types":["malware"]}`, page.Bookmark, "does not belong to this filter"},
+		{filter, ids[maxGraphObjects], "invalid bookmark"},
+	} {
+		if _, err := l.export(tt.filter, tt.bookmark); err == nil || !strings.Contains(err.Error(), tt.errText) {
+			t.Errorf("ExportBundle(%s, %s): error %v, want %q", tt.filter, tt.bookmark, err, tt.errText)
+		}
+	}
+}
+
+// A selective filter stops after maxExportScan objects and returns a bookmark
+func TestExportBundleBoundsTheScan(t *testing.T) {
+	n := maxExportScan + exportScanPageSize/2
+	l, ids := exportLedger(t, n, func(i int) bool { return i == 3 || i == n-1 })
+
+	for _, filter := range []string{`{"labels":["needle"]}`, `{"types":["indicator"],"labels":["needle"]}`} {
+		var found []string
+		calls, bookmark := 0, ""
+		for {
+			page, err := l.export(filter, bookmark)
+			if err != nil {
+				t.Fatalf("%s: %v", filter, err)
+			}
+			calls++
+			found = append(found, bundleIDs(t, page)...)
+			if bookmark = page.Bookmark; bookmark == "" || calls > 3 {
+				break
+			}
+		}
+		if calls != 2 {
+			t.Errorf("%s: export took %d calls, want 2", filter, calls)
+		}
+		if got, want := strings.Join(found, ","), ids[3]+","+ids[n-1]; got != want {
+			t.Errorf("%s: exported %s, want %s", filter, got, want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+// File: stix_graph.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Adjacency Indexes
+// ──────────────────────────────────────────────────────────────────────────────
+
+// Composite-key namespaces of the adjacency indexes. Every unrevoked relationship
+// and sighting adds one edge to each:
+//
+//	edgeOutObjectType + [source, relationship type, target, SRO ID]
+//	edgeInObjectType  + [target, relationship type, source, SRO ID]
+//
+// A sighting is an edge of type "sighting-of" from itself to its sighting_of_ref.
+const (
+	edgeOutObjectType = "edgeout"
+	edgeInObjectType  = "edgein"
+)
+
+// Bounds of GetRelatedObjects, which keep the endorsement response small
+const (
+	maxGraphDepth   = 4
+	maxGraphObjects = 500
+	maxGraphBytes   = 2 << 20 // total size of the returned objects
+)
+
+// edgeKeys returns the adjacency-index keys of an unrevoked relationship or sighting
+func edgeKeys(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+	props map[string]json.RawMessage,
+) ([]string, error) {
+	var revoked bool
+	if err := json.Unmarshal(props["revoked"], &revoked); err == nil && revoked {
+		return nil, nil
+	}
+	var source, relType, target string
+	switch stringProperty(props, "type") {
+	case "relationship":
+		source, relType, target = stringProperty(props, "source_ref"), stringProperty(props, "relationship_type"), stringProperty(props, "target_ref")
+	case "sighting":
+		source, relType, target = id, "sighting-of", stringProperty(props, "sighting_of_ref")
+	}
+	if source == "" || relType == "" || target == "" {
+		return nil, nil
+	}
+
+	out, err := ctx.GetStub().CreateCompositeKey(edgeOutObjectType, []string{source, relType, target, id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create edge key for %s: %v", id, err)
+	}
+	in, err := ctx.GetStub().CreateCompositeKey(edgeInObjectType, []string{target, relType, source, id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create edge key for %s: %v", id, err)
+	}
+	return []string{out, in}, nil
+}
+
+// graphEdge is one entry of an adjacency index, seen from the object it was read for
+type graphEdge struct {
+	RelType string
+	Other   string // the object at the other end
+	SROID   string // the relationship or sighting that makes the edge
+}
+
+// objectEdges returns the outgoing or incoming edges of an object
+func (c *CTIStixContract) objectEdges(
+	ctx contractapi.TransactionContextInterface,
+	objectType string,
+	id string,
+) ([]graphEdge, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(objectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s index: %v", objectType, err)
+	}
+	defer iterator.Close()
+
+	var edges []graphEdge
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate: %v", err)
+		}
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 4 {
+			return nil, fmt.Errorf("malformed %s index key %q", objectType, queryResponse.Key)
+		}
+		edges = append(edges, graphEdge{RelType: parts[1], Other: parts[2], SROID: parts[3]})
+	}
+	return edges, nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Graph Traversal
+// ──────────────────────────────────────────────────────────────────────────────
+
+// RelatedObjects is the result of GetRelatedObjects: a STIX bundle with the objects
+// and the relationships and sightings between them
+type RelatedObjects struct {
+	Bundle    *Bundle `json:"bundle"`
+	Truncated bool    `json:"truncated"` // a size bound was hit and the subgraph is incomplete
+}
+
+// subgraph collects the objects of a traversal within the size bounds
+type subgraph struct {
+	viewer    *objectViewer
+	resolved  map[string]json.RawMessage // object ID → object as the caller sees it, nil if left out
+	included  map[string]bool
+	ids       []string // IDs of objects, in the same order
+	objects   []json.RawMessage
+	size      int
+	truncated bool
+}
+
+// newSubgraph starts an empty subgraph for a viewer
+func newSubgraph(v *objectViewer) *subgraph {
+	return &subgraph{viewer: v, resolved: map[string]json.RawMessage{}, included: map[string]bool{}}
+}
+
+// resolve returns an object as the caller sees it, or nil if it is not on the ledger
+// (e.g. a pending forward reference), revoked or withheld from the caller
+func (g *subgraph) resolve(c *CTIStixContract, ctx contractapi.TransactionContextInterface, id string) (json.RawMessage, error) {
+	if obj, ok := g.resolved[id]; ok {
+		return obj, nil
+	}
+	exists, err := c.stixObjectExists(ctx, id)
+	if err != nil || !exists {
+		return nil, err
+	}
+	rec, err := c.getStixObject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var obj json.RawMessage
+	if !rec.Revoked {
+		if obj, err = g.viewer.view(rec); err != nil {
+			return nil, err
+		}
+	}
+	g.resolved[id] = obj
+	return obj, nil
+}
+
+// add reads an object and adds it to the subgraph if the caller may see it and the
+// bounds allow; it reports whether the object is part of the subgraph
+func (g *subgraph) add(c *CTIStixContract, ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+	obj, err := g.resolve(c, ctx, id)
+	if err != nil || obj == nil {
+		return false, err
+	}
+	return g.addObjects(map[string]json.RawMessage{id: obj}), nil
+}
+
+// addObjects adds objects the caller may see, all of them or, if the bounds do not
+// allow that, none
+func (g *subgraph) addObjects(objects map[string]json.RawMessage) bool {
+	count, size := 0, 0
+	for id, obj := range objects {
+		if !g.included[id] {
+			count++
+			size += len(obj)
+		}
+	}
+	if len(g.objects)+count > maxGraphObjects || g.size+size > maxGraphBytes {
+		g.truncated = true
+		return false
+	}
+	for _, id := range sortedKeys(objects) {
+		if !g.included[id] {
+			g.included[id] = true
+			g.ids = append(g.ids, id)
+			g.objects = append(g.objects, objects[id])
+			g.size += len(objects[id])
+		}
+	}
+	return true
+}
+
+// rollback removes the objects added after the first n and clears truncated
+func (g *subgraph) rollback(n int) {
+	for i := n; i < len(g.objects); i++ {
+		delete(g.included, g.ids[i])
+		g.size -= len(g.objects[i])
+	}
+	g.ids, g.objects, g.truncated = g.ids[:n], g.objects[:n], false
+}
+
+// expand walks up to depth edges out from the frontier objects, in either
+// direction, adding the objects reached and the relationships and sightings that
+// reach them. An edge is followed only if the caller may see both the relationship
+// or sighting and the object at the other end, and the bounds allow both. Empty
+// filter maps allow every relationship or object type.
+func (g *subgraph) expand(
+	c *CTIStixContract,
+	ctx contractapi.TransactionContextInterface,
+	frontier []string,
+	depth int,
+	relAllowed map[string]bool,
+	typeAllowed map[string]bool,
+) error {
+	seen := make(map[string]bool, len(frontier))
+	for _, id := range frontier {
+		seen[id] = true
+	}
+	for level := 0; level < depth && len(frontier) > 0 && !g.truncated; level++ {
+		var next []string
+		for _, node := range frontier {
+			for _, index := range []string{edgeOutObjectType, edgeInObjectType} {
+				edges, err := c.objectEdges(ctx, index, node)
+				if err != nil {
+					return err
+				}
+				for _, e := range edges {
+					if len(relAllowed) > 0 && !relAllowed[e.RelType] {
+						continue
+					}
+					if typ, err := stixTypeOf(e.Other); err != nil || (len(typeAllowed) > 0 && !typeAllowed[typ]) {
+						continue
+					}
+					sro, err := g.resolve(c, ctx, e.SROID)
+					if err != nil {
+						return err
+					}
+					if sro == nil {
+						continue // a withheld relationship must not link the two objects
+					}
+					other, err := g.resolve(c, ctx, e.Other)
+					if err != nil {
+						return err
+					}
+					if other == nil {
+						continue
+					}
+					if !g.addObjects(map[string]json.RawMessage{e.SROID: sro, e.Other: other}) {
+						continue
+					}
+					if !seen[e.Other] {
+						seen[e.Other] = true
+						next = append(next, e.Other)
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+	return nil
+}
+
+// GetRelatedObjects returns the subgraph around an object: every object reachable
+// over at most depth (1-4) relationships or sightings, in either direction, with
+// the relationships and sightings that connect them. relationshipTypes limits the
+// edges followed ("sighting-of" for sightings) and objectTypes the objects
+// reached; empty lists allow all. Objects withheld from the caller, and revoked
+// ones, are left out and not traversed; a revoked starting object is refused, as by
+// ReadObject. The result stops at 500 objects or 2 MiB and is then marked truncated.
+func (c *CTIStixContract) GetRelatedObjects(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+	depth int,
+	relationshipTypes []string,
+	objectTypes []string,
+) (string, error) {
+	if depth < 1 || depth > maxGraphDepth {
+		return "", fmt.Errorf("depth must be between 1 and %d, got %d", maxGraphDepth, depth)
+	}
+	rec, _, err := c.readVisibleObject(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if rec.Revoked {
+		return "", fmt.Errorf("%s has been revoked: %s", id, rec.Reason)
+	}
+	v, err := c.newObjectViewer(ctx)
+	if err != nil {
+		return "", err
+	}
+	relAllowed := make(map[string]bool, len(relationshipTypes))
+	for _, t := range relationshipTypes {
+		relAllowed[t] = true
+	}
+	typeAllowed := make(map[string]bool, len(objectTypes))
+	for _, t := range objectTypes {
+		typeAllowed[t] = true
+	}
+
+	g := newSubgraph(v)
+	if _, err := g.add(c, ctx, id); err != nil {
+		return "", err
+	}
+	if err := g.expand(c, ctx, []string{id}, depth, relAllowed, typeAllowed); err != nil {
+		return "", err
+	}
+
+	return resultJSON(&RelatedObjects{
+		Bundle: &Bundle{
+			Type:    "bundle",
+			ID:      derivedID("bundle", ctx.GetStub().GetTxID()),
+			Objects: g.objects,
+		},
+		Truncated: g.truncated,
+	})
+}
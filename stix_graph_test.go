@@ -0,0 +1,178 @@
+// File: stix_graph_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// testRelationship returns a relationship between two objects
+func testRelationship(id, relType, source, target string) map[string]interface{} {
+	props := sdo("relationship", id)
+	props["relationship_type"], props["source_ref"], props["target_ref"] = relType, source, target
+	return props
+}
+
+// related runs GetRelatedObjects and returns the sorted ids of the bundle
+func (l *mockLedger) related(id *mockIdentity, root string, depth int, relTypes, objTypes []string) ([]string, bool, error) {
+	var result RelatedObjects
+	err := l.invoke(id, func(ctx contractapi.TransactionContextInterface) error {
+		out, err := (&CTIStixContract{}).GetRelatedObjects(ctx, root, depth, relTypes, objTypes)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(out), &result)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	var ids []string
+	for _, obj := range result.Bundle.Objects {
+		var header struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(obj, &header); err != nil {
+			return nil, false, err
+		}
+		ids = append(ids, header.ID)
+	}
+	sort.Strings(ids)
+	return ids, result.Truncated, nil
+}
+
+func TestGetRelatedObjects(t *testing.T) {
+	const (
+		indicator = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+		malware   = "malware--31b940d4-6f7f-459a-80ea-9c1f17b5891b"
+		attack    = "attack-pattern--7e33a43e-e34b-40ec-89da-36c9bb2cacd5"
+		secret    = "malware--0c7b5b88-8ff7-4a4d-aa9d-feb398cd0061"
+		indicates = "relationship--44298a74-ba52-4f0c-87a3-1824e67d7fad"
+		uses      = "relationship--a5e5f7c1-1a3e-4f6e-8b8b-2a1f3c4d5e6f"
+		hidden    = "relationship--b6f6a8d2-2b4f-4a7f-9c9c-3b2a4d5e6f70"
+	)
+	l := newMockLedger(t)
+	org1 := newMockIdentity("Org1MSP", "lead", "", false)
+	l.create(org1, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45"))
+	for _, m := range []struct{ id, name, marking string }{
+		{"31b940d4-6f7f-459a-80ea-9c1f17b5891b", "Poison Ivy", tlpGreen},
+		{"0c7b5b88-8ff7-4a4d-aa9d-feb398cd0061", "Internal name", tlpRed},
+	} {
+		props := sdo("malware", m.id)
+		props["name"], props["is_family"], props["object_marking_refs"] = m.name, true, []string{m.marking}
+		l.create(org1, props)
+	}
+	ap := sdo("attack-pattern", "7e33a43e-e34b-40ec-89da-36c9bb2cacd5")
+	ap["name"] = "Spearphishing Attachment"
+	l.create(org1, ap)
+	l.create(org1, testRelationship("44298a74-ba52-4f0c-87a3-1824e67d7fad", "indicates", indicator, malware))
+	l.create(org1, testRelationship("a5e5f7c1-1a3e-4f6e-8b8b-2a1f3c4d5e6f", "uses", malware, attack))
+	l.create(org1, testRelationship("b6f6a8d2-2b4f-4a7f-9c9c-3b2a4d5e6f70", "indicates", indicator, secret))
+
+	org2 := newMockIdentity("Org2MSP", "reader", "", false)
+	tests := []struct {
+		name     string
+		reader   *mockIdentity
+		depth    int
+		relTypes []string
+		objTypes []string
+		want     []string
+	}{
+		{"one hop", org2, 1, nil, nil, []string{indicator, indicates, malware}},
+		{"two hops", org2, 2, nil, nil, []string{attack, indicator, malware, indicates, uses}},
+		{"creator sees its RED malware", org1, 1, nil, nil, []string{indicator, malware, secret, indicates, hidden}},
+		{"relationship types", org2, 2, []string{"indicates"}, nil, []string{indicator, malware, indicates}},
+		{"object types", org2, 2, nil, []string{"indicator", "malware"}, []string{indicator, malware, indicates}},
+	}
+	for _, tt := range tests {
+		got, truncated, err := l.related(tt.reader, indicator, tt.depth, tt.relTypes, tt.objTypes)
+		sort.Strings(tt.want)
+		if err != nil || truncated || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: got %v, %v, %v, want %v", tt.name, got, truncated, err, tt.want)
+		}
+	}
+
+	for _, depth := range []int{0, maxGraphDepth + 1} {
+		if _, _, err := l.related(org2, indicator, depth, nil, nil); err == nil {
+			t.Errorf("depth %d was accepted", depth)
+		}
+	}
+	if _, _, err := l.related(org2, secret, 1, nil, nil); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("withheld root: error %v", err)
+	}
+
+	// A revoked object is refused as a root and left out as a neighbour
+	err := l.invoke(org1, func(ctx contractapi.TransactionContextInterface) error {
+		return (&CTIStixContract{}).RevokeObject(ctx, malware, "misattributed")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := l.related(org2, malware, 1, nil, nil); err == nil || !strings.Contains(err.Error(), malware+" has been revoked: misattributed") {
+		t.Errorf("revoked root: error %v", err)
+	}
+	if got, _, err := l.related(org2, indicator, 2, nil, nil); err != nil || strings.Join(got, ",") != indicator {
+		t.Errorf("around a revoked neighbour: got %v, %v", got, err)
+	}
+}
+
+// An edge that no longer fits is dropped with its relationship, and the result is
+// marked truncated
+func TestGetRelatedObjectsIsBounded(t *testing.T) {
+	const hub = "malware--31b940d4-6f7f-459a-80ea-9c1f17b5891b"
+	l := newMockLedger(t)
+	org1 := newMockIdentity("Org1MSP", "analyst", "producer", false)
+	props := sdo("malware", "31b940d4-6f7f-459a-80ea-9c1f17b5891b")
+	props["name"], props["is_family"] = "Poison Ivy", true
+	l.create(org1, props)
+	edges := maxGraphObjects / 2
+	for i := 0; i < edges; i++ {
+		ind := testIndicator(fmt.Sprintf("00000000-0000-4000-8000-%012d", i), fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+		l.create(org1, ind)
+		l.create(org1, testRelationship(fmt.Sprintf("00000000-0000-4000-9000-%012d", i), "indicates", ind["id"].(string), hub))
+		l.events() // the MockStub blocks once its event channel is full
+	}
+
+	got, truncated, err := l.related(org1, hub, 1, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !truncated || len(got) != 1+2*(edges-1) {
+		t.Errorf("got %d objects, truncated %v; want %d, truncated", len(got), truncated, 1+2*(edges-1))
+	}
+	indicators, relationships := 0, 0
+	for _, id := range got {
+		switch {
+		case strings.HasPrefix(id, "indicator--"):
+			indicators++
+		case strings.HasPrefix(id, "relationship--"):
+			relationships++
+		}
+	}
+	if indicators != relationships {
+		t.Errorf("%d indicators but %d relationships: an edge was split", indicators, relationships)
+	}
+}
+
+func TestSubgraphRollback(t *testing.T) {
+	g := newSubgraph(&objectViewer{})
+	if !g.addObjects(map[string]json.RawMessage{"a": json.RawMessage(`{}`), "b": json.RawMessage(`{"x":1}`)}) {
+		t.Fatal("first objects were refused")
+	}
+	if g.addObjects(map[string]json.RawMessage{"c": make(json.RawMessage, maxGraphBytes)}) || !g.truncated {
+		t.Fatal("an object over the size bound was added")
+	}
+	g.addObjects(map[string]json.RawMessage{"c": json.RawMessage(`{}`)})
+	g.rollback(2)
+	if g.truncated || len(g.objects) != 2 || g.size != len(`{}`)+len(`{"x":1}`) || g.included["c"] {
+		t.Errorf("after rollback: %d objects, size %d, truncated %v", len(g.objects), g.size, g.truncated)
+	}
+	if !g.addObjects(map[string]json.RawMessage{"c": json.RawMessage(`{}`)}) || len(g.objects) != 3 {
+		t.Error("a rolled-back object cannot be added again")
+	}
+}
@@ -0,0 +1,250 @@
+// File: stix_identity.go
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Client Identity
+// ──────────────────────────────────────────────────────────────────────────────
+
+// submitter is the client identity of a transaction, as vouched for by its MSP
+type submitter struct {
+	MSPID      string
+	Subject    string // certificate subject DN, e.g. "CN=user1,OU=client,O=Org1"
+	CommonName string
+}
+
+// getSubmitterIdentity derives the submitter's MSP ID and certificate subject from
+// the client identity of the transaction; nothing the caller sends is trusted
+func (c *CTIStixContract) getSubmitterIdentity(ctx contractapi.TransactionContextInterface) (*submitter, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get submitter MSP ID: %v", err)
+	}
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get submitter certificate: %v", err)
+	}
+	return &submitter{
+		MSPID:      mspID,
+		Subject:    cert.Subject.String(),
+		CommonName: cert.Subject.CommonName,
+	}, nil
+}
+
+// getSubmitter returns the MSP ID and certificate common name of the transaction submitter
+func (c *CTIStixContract) getSubmitter(ctx contractapi.TransactionContextInterface) (string, string, error) {
+	sub, err := c.getSubmitterIdentity(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	return sub.MSPID, sub.CommonName, nil
+}
+
+// isAdmin reports whether the submitter is an admin of its organization, i.e. its
+// certificate carries the “admin” NodeOU
+func (c *CTIStixContract) isAdmin(ctx contractapi.TransactionContextInterface) (bool, error) {
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return false, fmt.Errorf("failed to get submitter certificate: %v", err)
+	}
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if ou == "admin" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Organization Identities
+// ──────────────────────────────────────────────────────────────────────────────
+
+// derivedID returns a STIX id of the given type whose UUID is derived from names, so
+// that every peer computes the same id for the same object without a lookup
+func derivedID(typ string, names ...string) string {
+	sum := sha256.Sum256([]byte("fabric-cti-sharing/" + typ + "/" + strings.Join(names, "/")))
+	u := sum[:16]
+	u[6] = (u[6] & 0x0f) | 0x40 // version 4 layout, as SDO ids require
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%s--%x-%x-%x-%x-%x", typ, u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// orgIdentityID returns the id of the STIX identity object of an organization,
+// derived from its MSP ID
+func orgIdentityID(mspID string) string {
+	return derivedID("identity", mspID)
+}
+
+// ensureOrgIdentity returns the identity id of the submitter's organization, storing
+// its identity object first if the organization has not written anything before
+func (c *CTIStixContract) ensureOrgIdentity(ctx contractapi.TransactionContextInterface, mspID string) (string, error) {
+	id := orgIdentityID(mspID)
+	exists, err := c.stixObjectExists(ctx, id)
+	if err != nil || exists {
+		return id, err
+	}
+
+	now, err := c.txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	ts := formatStixTimestamp(now)
+	identityJSON, err := json.Marshal(map[string]interface{}{
+		"type":            "identity",
+		"spec_version":    "2.1",
+		"id":              id,
+		"created":         ts,
+		"modified":        ts,
+		"created_by_ref":  id,
+		"name":            mspID,
+		"identity_class":  "organization",
+		"x_fabric_msp_id": mspID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal identity of %s: %v", mspID, err)
+	}
+	rec, err := newLedgerRecord(identityJSON)
+	if err != nil {
+		return "", err
+	}
+	rec.CreatorMSP = mspID
+	if err := c.putStixObject(ctx, rec); err != nil {
+		return "", fmt.Errorf("failed to store identity of %s: %v", mspID, err)
+	}
+	return id, nil
+}
+
+// prependProperty adds a string property at the start of a JSON object, keeping the
+// rest of the submitted JSON as it was
+func prependProperty(raw []byte, name, value string) ([]byte, error) {
+	trimmed := bytes.TrimLeft(raw, " \t\r\n")
+	if len(trimmed) < 2 || trimmed[0] != '{' {
+		return nil, fmt.Errorf("STIX object must be a JSON object")
+	}
+	rest := bytes.TrimLeft(trimmed[1:], " \t\r\n")
+	sep := ","
+	if len(rest) > 0 && rest[0] == '}' {
+		sep = ""
+	}
+	nameJSON, _ := json.Marshal(name)
+	valueJSON, _ := json.Marshal(value)
+	return []byte(fmt.Sprintf("{%s:%s%s%s", nameJSON, valueJSON, sep, rest)), nil
+}
+
+// setProperty sets a top-level property of a JSON object to a JSON value. An existing
+// value is replaced in place and a new property is appended, so the order of the
+// other properties is kept.
+func setProperty(raw []byte, name string, value json.RawMessage) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("STIX object must be a JSON object")
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse STIX object JSON: %v", err)
+		}
+		start := dec.InputOffset()
+		var old json.RawMessage
+		if err := dec.Decode(&old); err != nil {
+			return nil, fmt.Errorf("failed to parse STIX object JSON: %v", err)
+		}
+		if key != name {
+			continue
+		}
+		end := dec.InputOffset()
+		valueStart := start + int64(bytes.IndexByte(raw[start:end], ':')) + 1
+		for raw[valueStart] == ' ' || raw[valueStart] == '\t' || raw[valueStart] == '\r' || raw[valueStart] == '\n' {
+			valueStart++
+		}
+		return append(append(append([]byte(nil), raw[:valueStart]...), value...), raw[end:]...), nil
+	}
+
+	closing := bytes.LastIndexByte(raw, '}')
+	sep := ","
+	if len(bytes.TrimSpace(raw[bytes.IndexByte(raw, '{')+1:closing])) == 0 {
+		sep = ""
+	}
+	nameJSON, _ := json.Marshal(name)
+	return []byte(fmt.Sprintf("%s%s%s:%s%s", raw[:closing], sep, nameJSON, value, raw[closing:])), nil
+}
+
+// propertyNames returns the top-level property names of a JSON object in the order
+// they appear
+func propertyNames(raw []byte) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("STIX object must be a JSON object")
+	}
+	var names []string
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse STIX object JSON: %v", err)
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("failed to parse STIX object JSON: %v", err)
+		}
+		names = append(names, key.(string))
+	}
+	return names, nil
+}
+
+// stampCreatedByRef sets created_by_ref to the submitting organization's identity.
+// A payload that names a different creator is rejected. SCOs, which have no
+// created_by_ref, and predefined TLP markings are returned unchanged.
+func stampCreatedByRef(raw []byte, identityID string) ([]byte, error) {
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &props); err != nil {
+		return raw, nil // reported by decodeStixObject
+	}
+	typ := stringProperty(props, "type")
+	if typ == "bundle" || isObservableObject(typ, props) {
+		return raw, nil
+	}
+	if _, ok := tlpLevels[stringProperty(props, "id")]; ok {
+		return raw, nil
+	}
+	if hasProperty(props, "created_by_ref") {
+		if claimed := stringProperty(props, "created_by_ref"); claimed != identityID {
+			return nil, fmt.Errorf("created_by_ref '%s' does not match the submitting organization, whose identity is %s", claimed, identityID)
+		}
+		return raw, nil
+	}
+	return prependProperty(raw, "created_by_ref", identityID)
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 3) Identity Methods
+// ──────────────────────────────────────────────────────────────────────────────
+
+// GetOrgIdentity returns the STIX identity object of an organization (the
+// submitter's own if mspID is empty). The object is created the first time the
+// organization writes a STIX object.
+func (c *CTIStixContract) GetOrgIdentity(
+	ctx contractapi.TransactionContextInterface,
+	mspID string,
+) (string, error) {
+	if mspID == "" {
+		var err error
+		if mspID, _, err = c.getSubmitter(ctx); err != nil {
+			return "", err
+		}
+	}
+	rec, err := c.getStixObject(ctx, orgIdentityID(mspID))
+	if err != nil {
+		return "", fmt.Errorf("%s has no identity on the ledger yet: %v", mspID, err)
+	}
+	return string(rec.Object), nil
+}
@@ -0,0 +1,100 @@
+// File: stix_identity_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// record reads the ledger record of a stored STIX object
+func (l *mockLedger) record(id string) *ledgerRecord {
+	l.t.Helper()
+	var rec *ledgerRecord
+	err := l.invoke(newMockIdentity("Org1MSP", "reader", "", false), func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		rec, err = (&CTIStixContract{}).getStixObject(ctx, id)
+		return err
+	})
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	return rec
+}
+
+func TestCreatorAttribution(t *testing.T) {
+	const indicator = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+	l := newMockLedger(t)
+	org1 := newMockIdentity("Org1MSP", "analyst", "producer", false)
+	l.create(org1, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45"))
+
+	// The submitter's organization is stamped as the creator and gets an identity
+	rec := l.record(indicator)
+	if !strings.HasPrefix(string(rec.Object), `{"created_by_ref":"`+orgIdentityID("Org1MSP")+`",`) {
+		t.Errorf("stored %s", rec.Object)
+	}
+	if rec.CreatorMSP != "Org1MSP" || rec.CreatorSubject != org1.cert.Subject.String() {
+		t.Errorf("creator %s, %s", rec.CreatorMSP, rec.CreatorSubject)
+	}
+	var identity map[string]interface{}
+	if err := json.Unmarshal(l.record(orgIdentityID("Org1MSP")).Object, &identity); err != nil {
+		t.Fatal(err)
+	}
+	if identity["name"] != "Org1MSP" || identity["identity_class"] != "organization" || identity["x_fabric_msp_id"] != "Org1MSP" {
+		t.Errorf("identity %v", identity)
+	}
+
+	// Another organization cannot claim Org1MSP as the creator
+	org2 := newMockIdentity("Org2MSP", "analyst", "producer", false)
+	malware := sdo("malware", "31b940d4-6f7f-459a-80ea-9c1f17b5891b")
+	malware["name"], malware["is_family"], malware["created_by_ref"] = "Poison Ivy", true, orgIdentityID("Org1MSP")
+	err := l.invoke(org2, func(ctx contractapi.TransactionContextInterface) error {
+		return (&CTIStixContract{}).CreateObject(ctx, string(stixObject(t, malware)))
+	})
+	if err == nil || !strings.Contains(err.Error(), "does not match the submitting organization, whose identity is "+orgIdentityID("Org2MSP")) {
+		t.Errorf("impersonation: error %v", err)
+	}
+	if l.exists(malware["id"].(string)) {
+		t.Error("an impersonating object was stored")
+	}
+
+	// Naming the submitter's own identity is accepted
+	malware["created_by_ref"] = orgIdentityID("Org2MSP")
+	l.create(org2, malware)
+
+	// Observables have no creator
+	l.create(org2, map[string]interface{}{"type": "ipv4-addr", "id": "ipv4-addr--cb9d70c5-d244-531c-9efb-bcae57adc793", "value": "203.0.113.45"})
+	if obj := l.record("ipv4-addr--cb9d70c5-d244-531c-9efb-bcae57adc793").Object; strings.Contains(string(obj), "created_by_ref") {
+		t.Errorf("observable stamped: %s", obj)
+	}
+}
+
+func TestDerivedID(t *testing.T) {
+	a, b := orgIdentityID("Org1MSP"), orgIdentityID("Org2MSP")
+	if a == b || a != orgIdentityID("Org1MSP") {
+		t.Errorf("identity ids %s, %s", a, b)
+	}
+	if msg := checkIdentifier(a, "identity", "4"); msg != "" {
+		t.Errorf("%s: %s", a, msg)
+	}
+}
+
+func TestPrependProperty(t *testing.T) {
+	tests := []struct{ raw, want string }{
+		{`{"type":"malware"}`, `{"created_by_ref":"identity--x","type":"malware"}`},
+		{` { "type" : "malware" }`, `{"created_by_ref":"identity--x","type" : "malware" }`},
+		{`{}`, `{"created_by_ref":"identity--x"}`},
+	}
+	for _, tt := range tests {
+		got, err := prependProperty([]byte(tt.raw), "created_by_ref", "identity--x")
+		if err != nil || string(got) != tt.want {
+			t.Errorf("prependProperty(%s) = %s, %v; want %s", tt.raw, got, err, tt.want)
+		}
+	}
+	if _, err := prependProperty([]byte(`["type"]`), "created_by_ref", "identity--x"); err == nil {
+		t.Error("a JSON array was accepted")
+	}
+}
@@ -0,0 +1,143 @@
+// File: stix_index.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Secondary Indexes
+// ──────────────────────────────────────────────────────────────────────────────
+
+// indexMarker is the value of every secondary-index entry; the information lives in the key
+var indexMarker = []byte{0x00}
+
+// sightingOfObjectType is the composite-key namespace that links an object to the
+// sightings of it: sightingOfObjectType + [sighting_of_ref, sighting ID]
+const sightingOfObjectType = "sightingof"
+
+// opinionOfObjectType is the composite-key namespace that links an object to the
+// opinions about it: opinionOfObjectType + [object ref, opinion's created_by_ref, opinion ID]
+const opinionOfObjectType = "opinionof"
+
+// objectIndexKeys returns the secondary-index keys of one version of a STIX object
+func (c *CTIStixContract) objectIndexKeys(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+	props map[string]json.RawMessage,
+) ([]string, error) {
+	if props == nil {
+		return nil, nil
+	}
+	var keys []string
+	for _, obs := range objectObservables(stringProperty(props, "type"), props) {
+		key, err := ctx.GetStub().CreateCompositeKey(observableObjectType, []string{obs.Type, obs.Value, id})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create observable key for %s: %v", id, err)
+		}
+		keys = append(keys, key)
+	}
+	if ref := stringProperty(props, "sighting_of_ref"); ref != "" && stringProperty(props, "type") == "sighting" {
+		key, err := ctx.GetStub().CreateCompositeKey(sightingOfObjectType, []string{ref, id})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create sighting key for %s: %v", id, err)
+		}
+		keys = append(keys, key)
+	}
+	if stringProperty(props, "type") == "opinion" {
+		author := stringProperty(props, "created_by_ref")
+		for _, ref := range stringListProperty(props, "object_refs") {
+			key, err := ctx.GetStub().CreateCompositeKey(opinionOfObjectType, []string{ref, author, id})
+			if err != nil {
+				return nil, fmt.Errorf("failed to create opinion key for %s: %v", id, err)
+			}
+			keys = append(keys, key)
+		}
+	}
+	if key := expiryKey(id, props); key != "" {
+		keys = append(keys, key)
+	}
+	if key, err := fingerprintKey(ctx, id, props); err != nil {
+		return nil, err
+	} else if key != "" {
+		keys = append(keys, key)
+	}
+	edges, err := edgeKeys(ctx, id, props)
+	if err != nil {
+		return nil, err
+	}
+	keys = append(keys, edges...)
+	reputation, err := c.reputationKeys(ctx, id, props)
+	if err != nil {
+		return nil, err
+	}
+	return append(keys, reputation...), nil
+}
+
+// updateObjectIndexes brings the secondary indexes of an object from its previous
+// version (nil on creation) to its new one, dropping the entries that no longer apply
+func (c *CTIStixContract) updateObjectIndexes(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+	previous, next map[string]json.RawMessage,
+) error {
+	oldKeys, err := c.objectIndexKeys(ctx, id, previous)
+	if err != nil {
+		return err
+	}
+	newKeys, err := c.objectIndexKeys(ctx, id, next)
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[string]bool, len(newKeys))
+	for _, key := range newKeys {
+		keep[key] = true
+	}
+	for _, key := range oldKeys {
+		if keep[key] {
+			continue
+		}
+		if err := ctx.GetStub().DelState(key); err != nil {
+			return fmt.Errorf("failed to update indexes of %s: %v", id, err)
+		}
+	}
+	for _, key := range newKeys {
+		if err := c.putAsset(ctx, key, indexMarker); err != nil {
+			return fmt.Errorf("failed to update indexes of %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// indexedIDs returns the last attribute of every key under a partial composite key,
+// i.e. the IDs of the objects an index entry points at
+func (c *CTIStixContract) indexedIDs(
+	ctx contractapi.TransactionContextInterface,
+	objectType string,
+	attributes []string,
+) ([]string, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s index: %v", objectType, err)
+	}
+	defer iterator.Close()
+
+	var ids []string
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate: %v", err)
+		}
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) == 0 {
+			return nil, fmt.Errorf("malformed %s index key %q", objectType, queryResponse.Key)
+		}
+		ids = append(ids, parts[len(parts)-1])
+	}
+	return ids, nil
+}
@@ -0,0 +1,406 @@
+// File: stix_markings.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Marking Definitions
+// ──────────────────────────────────────────────────────────────────────────────
+
+// tlpLevels maps the ids of the predefined TLP marking definitions to their level.
+// TLP 1.0 WHITE is treated as TLP 2.0 CLEAR.
+var tlpLevels = map[string]string{
+	// TLP 2.0
+	"marking-definition--94868c89-83c2-464b-929b-a1a8aa3c8487": "clear",
+	"marking-definition--bab4a63c-aed9-4cf5-a766-dfca5abac2bb": "green",
+	"marking-definition--55d920b0-5e8b-4f79-9ee9-91f868d9b421": "amber",
+	"marking-definition--939a9414-2ddd-4d32-a0cd-375ea402b003": "amber+strict",
+	"marking-definition--e828b379-4e03-4974-9ac4-e53a884c97c1": "red",
+	// TLP 1.0
+	"marking-definition--613f2e26-407d-48c7-9eca-b8e91df99dc9": "clear",
+	"marking-definition--34098fce-860f-48ae-8e50-ebd3cc5e41da": "green",
+	"marking-definition--f88d31f6-486f-44da-b317-01333bde0b82": "amber",
+	"marking-definition--5e57c739-391a-4eb3-b6be-7d15ca92d5ed": "red",
+}
+
+// markingRequired lists the common properties a marking definition must carry; unlike
+// other SDOs it has no “modified”, since marking definitions cannot be versioned
+var markingRequired = []string{"type", "spec_version", "id", "created"}
+
+// MarkingDefinition represents a STIX 2.1 “marking-definition” object
+type MarkingDefinition struct {
+	Type               string                     `json:"type"`
+	ID                 string                     `json:"id"`
+	SpecVersion        string                     `json:"spec_version"`
+	Created            string                     `json:"created"`
+	CreatedByRef       string                     `json:"created_by_ref,omitempty"`
+	ExternalReferences []ExternalReference        `json:"external_references,omitempty"`
+	ObjectMarkingRefs  []string                   `json:"object_marking_refs,omitempty"`
+	Name               string                     `json:"name,omitempty"`
+	DefinitionType     string                     `json:"definition_type,omitempty"` // "statement" or "tlp"
+	Definition         json.RawMessage            `json:"definition,omitempty"`
+	Extensions         map[string]json.RawMessage `json:"extensions,omitempty"`
+}
+
+// GranularMarking applies a marking (or a language) to selected properties of an object
+type GranularMarking struct {
+	Lang       string   `json:"lang,omitempty"`
+	MarkingRef string   `json:"marking_ref,omitempty"`
+	Selectors  []string `json:"selectors"`
+}
+
+// checkMarkingDefinition enforces that a marking definition is either a statement, a
+// predefined TLP marking or defined through an extension
+func checkMarkingDefinition(props map[string]json.RawMessage) error {
+	id := stringProperty(props, "id")
+	switch stringProperty(props, "definition_type") {
+	case "statement":
+		var def struct {
+			Statement string `json:"statement"`
+		}
+		if err := json.Unmarshal(props["definition"], &def); err != nil || strings.TrimSpace(def.Statement) == "" {
+			return fmt.Errorf("statement marking requires definition.statement")
+		}
+	case "tlp":
+		if _, ok := tlpLevels[id]; !ok {
+			return fmt.Errorf("TLP markings are predefined; %s is not a TLP marking-definition id", id)
+		}
+	case "":
+		if !hasProperty(props, "extensions") {
+			return fmt.Errorf("marking-definition requires definition_type and definition, or extensions")
+		}
+	default:
+		if !hasProperty(props, "definition") {
+			return fmt.Errorf("marking-definition with definition_type requires definition")
+		}
+	}
+	return nil
+}
+
+// selectorProperty returns the top-level property a granular-marking selector points
+// into: "description" for "description", "external_references" for
+// "external_references.[0].url"
+func selectorProperty(selector string) string {
+	if i := strings.IndexAny(selector, ".["); i >= 0 {
+		return selector[:i]
+	}
+	return selector
+}
+
+// validateGranularMarkings checks the structure of “granular_markings”
+func validateGranularMarkings(props map[string]json.RawMessage, verr *ValidationError) {
+	if !hasProperty(props, "granular_markings") {
+		return
+	}
+	var markings []GranularMarking
+	if err := json.Unmarshal(props["granular_markings"], &markings); err != nil {
+		verr.add("granular_markings", "must be a list of granular markings")
+		return
+	}
+	for i, m := range markings {
+		field := fmt.Sprintf("granular_markings[%d]", i)
+		if (m.MarkingRef == "") == (m.Lang == "") {
+			verr.add(field, "needs exactly one of marking_ref or lang")
+		}
+		if m.MarkingRef != "" {
+			if msg := checkIdentifier(m.MarkingRef, "marking-definition", ""); msg != "" {
+				verr.add(field, "%s", msg)
+			}
+		}
+		if len(m.Selectors) == 0 {
+			verr.add(field, "selectors must not be empty")
+		}
+		for _, selector := range m.Selectors {
+			if !hasProperty(props, selectorProperty(selector)) {
+				verr.add(field, "selector '%s' does not refer to a property of the object", selector)
+			}
+		}
+	}
+}
+
+// markingRefs returns every marking-definition id an object refers to, at object
+// level and in granular markings
+func markingRefs(props map[string]json.RawMessage) []string {
+	refs := stringListProperty(props, "object_marking_refs")
+	var granular []GranularMarking
+	if err := json.Unmarshal(props["granular_markings"], &granular); err == nil {
+		for _, m := range granular {
+			if m.MarkingRef != "" {
+				refs = append(refs, m.MarkingRef)
+			}
+		}
+	}
+	return refs
+}
+
+// checkMarkingRefs enforces that every marking an object uses is a predefined TLP
+// marking or a marking definition pending in this transaction or on the ledger
+func (c *CTIStixContract) checkMarkingRefs(
+	ctx contractapi.TransactionContextInterface,
+	props map[string]json.RawMessage,
+	pending map[string]json.RawMessage,
+) error {
+	for _, ref := range markingRefs(props) {
+		if _, ok := tlpLevels[ref]; ok {
+			continue
+		}
+		obj, err := c.lookupObject(ctx, ref, pending)
+		if err != nil {
+			return err
+		}
+		if obj == nil {
+			return fmt.Errorf("marking definition %s does not exist", ref)
+		}
+	}
+	return nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Sharing Rules
+// ──────────────────────────────────────────────────────────────────────────────
+
+// SharingRule lists the organizations that may read objects carrying a marking
+type SharingRule struct {
+	MarkingRef  string   `json:"marking_ref"`  // marking-definition id, TLP or custom
+	AllowedMSPs []string `json:"allowed_msps"` // MSP IDs, or "*" for every channel member
+}
+
+// SharingPolicy holds the marking-based sharing rules of the channel. Markings without
+// a rule fall back to their TLP level: CLEAR and GREEN objects are shared with every
+// channel member, AMBER, AMBER+STRICT and RED objects only with their creator.
+// Statement and other custom markings do not restrict reading unless a rule says so.
+type SharingPolicy struct {
+	Rules []SharingRule `json:"rules"`
+}
+
+// getSharingPolicy reads the sharing policy, which is empty until an admin sets it
+func (c *CTIStixContract) getSharingPolicy(ctx contractapi.TransactionContextInterface) (*SharingPolicy, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{"sharing"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key for sharing policy: %v", err)
+	}
+	bytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sharing policy: %v", err)
+	}
+
+	policy := &SharingPolicy{Rules: []SharingRule{}}
+	if bytes == nil {
+		return policy, nil
+	}
+	if err := json.Unmarshal(bytes, policy); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sharing policy: %v", err)
+	}
+	return policy, nil
+}
+
+// GetSharingPolicy returns the marking-based sharing rules of the channel
+func (c *CTIStixContract) GetSharingPolicy(ctx contractapi.TransactionContextInterface) (*SharingPolicy, error) {
+	return c.getSharingPolicy(ctx)
+}
+
+// SetSharingPolicy replaces the marking-based sharing rules; only channel admins may
+// call it
+func (c *CTIStixContract) SetSharingPolicy(ctx contractapi.TransactionContextInterface, jsonStr string) error {
+	if err := c.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	var policy SharingPolicy
+	if err := json.Unmarshal([]byte(jsonStr), &policy); err != nil {
+		return fmt.Errorf("failed to parse sharing policy JSON: %v", err)
+	}
+	seen := map[string]bool{}
+	for i, rule := range policy.Rules {
+		if msg := checkIdentifier(rule.MarkingRef, "marking-definition", ""); msg != "" {
+			return fmt.Errorf("rule %d: %s", i, msg)
+		}
+		if seen[rule.MarkingRef] {
+			return fmt.Errorf("rule %d: %s already has a rule", i, rule.MarkingRef)
+		}
+		seen[rule.MarkingRef] = true
+	}
+	if policy.Rules == nil {
+		policy.Rules = []SharingRule{}
+	}
+
+	bytes, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sharing policy: %v", err)
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{"sharing"})
+	if err != nil {
+		return fmt.Errorf("failed to create key for sharing policy: %v", err)
+	}
+	return c.putAsset(ctx, key, bytes)
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 3) Marking-based Read Filtering
+// ──────────────────────────────────────────────────────────────────────────────
+
+// objectViewer decides which stored objects, and which of their properties, the
+// submitter of a transaction may read
+type objectViewer struct {
+	mspID string
+	rules map[string][]string // marking ref → allowed MSP IDs
+}
+
+// newObjectViewer prepares the read filter for the submitter of the transaction
+func (c *CTIStixContract) newObjectViewer(ctx contractapi.TransactionContextInterface) (*objectViewer, error) {
+	if err := c.requirePermission(ctx, actionRead); err != nil {
+		return nil, err
+	}
+
+	mspID, _, err := c.getSubmitter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.orgViewer(ctx, mspID)
+}
+
+// orgViewer prepares the read filter for an organization without checking the read
+// permission, for checks made on behalf of a submitter while it writes
+func (c *CTIStixContract) orgViewer(ctx contractapi.TransactionContextInterface, mspID string) (*objectViewer, error) {
+	v, err := c.publicViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v.mspID = mspID
+	return v, nil
+}
+
+// publicViewer returns a viewer without an organization, which sees only what the
+// sharing policy opens to every channel member
+func (c *CTIStixContract) publicViewer(ctx contractapi.TransactionContextInterface) (*objectViewer, error) {
+	policy, err := c.getSharingPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v := &objectViewer{rules: make(map[string][]string, len(policy.Rules))}
+	for _, rule := range policy.Rules {
+		v.rules[rule.MarkingRef] = rule.AllowedMSPs
+	}
+	return v, nil
+}
+
+// allowed reports whether the viewer may read content carrying a marking
+func (v *objectViewer) allowed(markingRef string) bool {
+	if msps, ok := v.rules[markingRef]; ok {
+		for _, msp := range msps {
+			if msp == "*" || msp == v.mspID {
+				return true
+			}
+		}
+		return false
+	}
+	switch tlpLevels[markingRef] {
+	case "amber", "amber+strict", "red":
+		return false
+	}
+	return true
+}
+
+// view returns the object of a record as the viewer may see it: unchanged, with the
+// properties under a restricted granular marking removed, or nil if an object-level
+// marking withholds it. The creating organization sees everything. The reports other
+// organizations made of an SCO are viewed the same way, each under its own markings,
+// and what remains of them is added to the object.
+func (v *objectViewer) view(rec *ledgerRecord) (json.RawMessage, error) {
+	obj, err := v.viewObject(rec.ID, rec.CreatorMSP, rec.Object)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rec.Reports {
+		report, err := v.viewObject(rec.ID, r.MSPID, r.Object)
+		switch {
+		case err != nil:
+			return nil, err
+		case report == nil:
+		case obj == nil:
+			obj = report
+		default:
+			if obj, err = addMissingProperties(obj, report); err != nil {
+				return nil, fmt.Errorf("failed to merge the report of %s on %s: %v", r.MSPID, rec.ID, err)
+			}
+		}
+	}
+	return obj, nil
+}
+
+// viewObject applies the marking rules to one submission of an object; the
+// organization that submitted it sees it unchanged
+func (v *objectViewer) viewObject(id, submitterMSP string, object json.RawMessage) (json.RawMessage, error) {
+	if submitterMSP == v.mspID {
+		return object, nil
+	}
+
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(object, &props); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s JSON: %v", id, err)
+	}
+	for _, ref := range stringListProperty(props, "object_marking_refs") {
+		if !v.allowed(ref) {
+			return nil, nil
+		}
+	}
+
+	var granular []GranularMarking
+	if err := json.Unmarshal(props["granular_markings"], &granular); err != nil {
+		return object, nil
+	}
+	redacted := false
+	for _, m := range granular {
+		if m.MarkingRef == "" || v.allowed(m.MarkingRef) {
+			continue
+		}
+		for _, selector := range m.Selectors {
+			switch name := selectorProperty(selector); name {
+			case "type", "id", "spec_version":
+				// identity properties are never withheld
+			default:
+				delete(props, name)
+				redacted = true
+			}
+		}
+	}
+	if !redacted {
+		return object, nil
+	}
+	obj, err := json.Marshal(props)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal redacted %s: %v", id, err)
+	}
+	return obj, nil
+}
+
+// readVisibleObject reads a stored object as the submitter may see it. An object that
+// a marking withholds fails with the same error as one that does not exist.
+func (c *CTIStixContract) readVisibleObject(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+) (*ledgerRecord, json.RawMessage, error) {
+	rec, err := c.getStixObject(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	v, err := c.newObjectViewer(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	obj, err := v.view(rec)
+	if err != nil {
+		return nil, nil, err
+	}
+	if obj == nil {
+		return nil, nil, objectNotFound(id)
+	}
+	return rec, obj, nil
+}
@@ -0,0 +1,281 @@
+// File: stix_markings_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Predefined TLP 2.0 markings and a custom marking used by the tests
+const (
+	tlpGreen       = "marking-definition--bab4a63c-aed9-4cf5-a766-dfca5abac2bb"
+	tlpAmber       = "marking-definition--55d920b0-5e8b-4f79-9ee9-91f868d9b421"
+	tlpAmberStrict = "marking-definition--939a9414-2ddd-4d32-a0cd-375ea402b003"
+	tlpRed         = "marking-definition--e828b379-4e03-4974-9ac4-e53a884c97c1"
+	customMarking  = "marking-definition--f0d5a8b8-2b8e-4c2a-9d8e-3c6f1a7d9e21"
+)
+
+func TestObjectViewerView(t *testing.T) {
+	rules := map[string][]string{
+		tlpAmber:      {"Org2MSP"},
+		customMarking: {"Org3MSP"},
+	}
+	tests := []struct {
+		name   string
+		viewer string // MSP ID of the viewer, "" for the public viewer
+		rules  map[string][]string
+		object string
+		want   string // "full", "hidden" or "redacted"
+	}{
+		{"creator sees RED", "Org1MSP", nil, `{"object_marking_refs":["` + tlpRed + `"]}`, "full"},
+		{"RED is withheld", "Org2MSP", nil, `{"object_marking_refs":["` + tlpRed + `"]}`, "hidden"},
+		{"GREEN is shared", "Org2MSP", nil, `{"object_marking_refs":["` + tlpGreen + `"]}`, "full"},
+		{"AMBER is withheld without a rule", "Org2MSP", nil, `{"object_marking_refs":["` + tlpAmber + `"]}`, "hidden"},
+		{"AMBER+STRICT is withheld without a rule", "Org2MSP", nil, `{"object_marking_refs":["` + tlpAmberStrict + `"]}`, "hidden"},
+		{"rule opens AMBER", "Org2MSP", rules, `{"object_marking_refs":["` + tlpAmber + `"]}`, "full"},
+		{"rule opens AMBER to listed orgs only", "Org3MSP", rules, `{"object_marking_refs":["` + tlpAmber + `"]}`, "hidden"},
+		{"custom marking without a rule", "Org2MSP", nil, `{"object_marking_refs":["` + customMarking + `"]}`, "full"},
+		{"rule restricts a custom marking", "Org2MSP", rules, `{"object_marking_refs":["` + customMarking + `"]}`, "hidden"},
+		{"every marking must allow", "Org2MSP", rules, `{"object_marking_refs":["` + tlpAmber + `","` + customMarking + `"]}`, "hidden"},
+		{"wildcard rule", "Org2MSP", map[string][]string{tlpRed: {"*"}}, `{"object_marking_refs":["` + tlpRed + `"]}`, "full"},
+		{"public viewer and GREEN", "", nil, `{"object_marking_refs":["` + tlpGreen + `"]}`, "full"},
+		{"public viewer and a listed org", "", rules, `{"object_marking_refs":["` + tlpAmber + `"]}`, "hidden"},
+		{"unmarked", "Org2MSP", nil, `{}`, "full"},
+		{
+			"granular RED removes the property", "Org2MSP", nil,
+			`{"description":"x","granular_markings":[{"marking_ref":"` + tlpRed + `","selectors":["description"]}]}`,
+			"redacted",
+		},
+		{
+			"granular marking opened by a rule", "Org2MSP", rules,
+			`{"description":"x","granular_markings":[{"marking_ref":"` + tlpAmber + `","selectors":["description"]}]}`,
+			"full",
+		},
+		{
+			"identity properties are never withheld", "Org2MSP", nil,
+			`{"granular_markings":[{"marking_ref":"` + tlpRed + `","selectors":["id","type"]}]}`,
+			"full",
+		},
+		{
+			"creator sees granular RED", "Org1MSP", nil,
+			`{"description":"x","granular_markings":[{"marking_ref":"` + tlpRed + `","selectors":["description"]}]}`,
+			"full",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var props map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.object), &props); err != nil {
+				t.Fatal(err)
+			}
+			props["type"], props["id"], props["name"] = "indicator", "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "n"
+			raw := stixObject(t, props)
+			rec := &ledgerRecord{ID: "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", CreatorMSP: "Org1MSP", Object: raw}
+			v := &objectViewer{mspID: tt.viewer, rules: tt.rules}
+
+			obj, err := v.view(rec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := "redacted"
+			switch {
+			case obj == nil:
+				got = "hidden"
+			case string(obj) == string(raw):
+				got = "full"
+			}
+			if got != tt.want {
+				t.Fatalf("view = %s (%s), want %s", got, obj, tt.want)
+			}
+			if got == "redacted" {
+				var seen map[string]json.RawMessage
+				if err := json.Unmarshal(obj, &seen); err != nil {
+					t.Fatal(err)
+				}
+				if hasProperty(seen, "description") || !hasProperty(seen, "name") || !hasProperty(seen, "id") {
+					t.Errorf("redacted object %s", obj)
+				}
+			}
+		})
+	}
+}
+
+func TestViewIgnoresReporters(t *testing.T) {
+	// Reporting the same SCO does not let an organization see what its creator marked
+	rec := &ledgerRecord{
+		ID:         "ipv4-addr--28bb3599-77cd-5a82-a950-b5bc3caf07c4",
+		CreatorMSP: "Org1MSP",
+		ReportedBy: []string{"Org1MSP", "Org2MSP"},
+		Object:     json.RawMessage(`{"type":"ipv4-addr","id":"ipv4-addr--28bb3599-77cd-5a82-a950-b5bc3caf07c4","value":"198.51.100.3","object_marking_refs":["` + tlpRed + `"]}`),
+	}
+	obj, err := (&objectViewer{mspID: "Org2MSP"}).view(rec)
+	if err != nil || obj != nil {
+		t.Errorf("view = %s, %v, want the object withheld", obj, err)
+	}
+}
+
+func TestReadObjectAppliesSharingPolicy(t *testing.T) {
+	l := newMockLedger(t)
+	admin := newMockIdentity("Org1MSP", "admin", "", true)
+	l.initChannelAdmins(admin, "Org1MSP")
+	producer := newMockIdentity("Org1MSP", "analyst", "producer", false)
+	org2 := newMockIdentity("Org2MSP", "soc", "consumer", false)
+	org3 := newMockIdentity("Org3MSP", "soc", "consumer", false)
+	auditor := newMockIdentity("Org2MSP", "auditor", "auditor", false)
+
+	err := l.invoke(admin, func(ctx contractapi.TransactionContextInterface) error {
+		return (&CTIStixContract{}).SetSharingPolicy(ctx, `{"rules":[{"marking_ref":"`+tlpAmber+`","allowed_msps":["Org2MSP"]}]}`)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.invoke(org2, func(ctx contractapi.TransactionContextInterface) error {
+		return (&CTIStixContract{}).SetSharingPolicy(ctx, `{"rules":[]}`)
+	}); err == nil {
+		t.Error("a client outside the channel-admin organizations changed the sharing policy")
+	}
+
+	amber := testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45")
+	amber["object_marking_refs"] = []string{tlpAmber}
+	red := testIndicator("0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21", "198.51.100.7")
+	red["object_marking_refs"] = []string{tlpRed}
+	granular := testIndicator("b2cc9b2c-6c5d-4d5a-8a4f-6b4bd0c7c2a9", "192.0.2.1")
+	granular["description"] = "seen in the payroll network"
+	granular["granular_markings"] = []map[string]interface{}{{"marking_ref": tlpRed, "selectors": []string{"description"}}}
+	for _, obj := range []map[string]interface{}{amber, red, granular} {
+		if err := l.invoke(producer, func(ctx contractapi.TransactionContextInterface) error {
+			return (&CTIStixContract{}).CreateObject(ctx, string(stixObject(t, obj)))
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		reader  *mockIdentity
+		id      string
+		errText string // "" if the read succeeds
+		redact  bool   // description withheld from the granular-marking indicator
+	}{
+		{producer, "indicator--0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21", "", false},
+		{producer, "indicator--b2cc9b2c-6c5d-4d5a-8a4f-6b4bd0c7c2a9", "", false},
+		{org2, "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "", false},
+		{org3, "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "asset indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f does not exist", false},
+		{org2, "indicator--0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21", "asset indicator--0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21 does not exist", false},
+		{org2, "indicator--b2cc9b2c-6c5d-4d5a-8a4f-6b4bd0c7c2a9", "", true},
+		{auditor, "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "may not read", false},
+	}
+	for _, tt := range tests {
+		var obj string
+		err := l.invoke(tt.reader, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			obj, err = (&CTIStixContract{}).ReadObject(ctx, tt.id, false)
+			return err
+		})
+		who := tt.reader.mspID + "/" + tt.reader.cert.Subject.CommonName
+		if tt.errText != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errText) {
+				t.Errorf("%s reading %s: error %v, want %q", who, tt.id, err, tt.errText)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s reading %s: %v", who, tt.id, err)
+			continue
+		}
+		var props map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(obj), &props); err != nil {
+			t.Fatal(err)
+		}
+		if tt.id == "indicator--b2cc9b2c-6c5d-4d5a-8a4f-6b4bd0c7c2a9" && hasProperty(props, "description") == tt.redact {
+			t.Errorf("%s reading %s: got %s, want description redacted %v", who, tt.id, obj, tt.redact)
+		}
+	}
+}
+
+// A withheld object must look exactly like one that does not exist, whether it is
+// read or referenced
+func TestWithheldObjectsLookMissing(t *testing.T) {
+	const (
+		withheld = "indicator--0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21"
+		absent   = "indicator--d3c1e0a6-8a4f-4e8b-9f5e-1a2b3c4d5e6f"
+	)
+	l := newMockLedger(t)
+	org1 := newMockIdentity("Org1MSP", "analyst", "producer", false)
+	org2 := newMockIdentity("Org2MSP", "analyst", "producer", false)
+	red := testIndicator("0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21", "198.51.100.7")
+	red["object_marking_refs"] = []string{tlpRed}
+	l.create(org1, red)
+	malware := sdo("malware", "31b940d4-6f7f-459a-80ea-9c1f17b5891b")
+	malware["name"], malware["is_family"] = "Poison Ivy", true
+	l.create(org2, malware)
+
+	c := &CTIStixContract{}
+	probes := []struct {
+		name  string
+		probe func(ctx contractapi.TransactionContextInterface, ref string) error
+	}{
+		{"ReadObject", func(ctx contractapi.TransactionContextInterface, ref string) error {
+			_, err := c.ReadObject(ctx, ref, true)
+			return err
+		}},
+		{"ReadIndicator", func(ctx contractapi.TransactionContextInterface, ref string) error {
+			_, err := c.ReadIndicator(ctx, ref, true)
+			return err
+		}},
+		{"GetRelatedObjects", func(ctx contractapi.TransactionContextInterface, ref string) error {
+			_, err := c.GetRelatedObjects(ctx, ref, 1, nil, nil)
+			return err
+		}},
+		{"relationship", func(ctx contractapi.TransactionContextInterface, ref string) error {
+			rel := sdo("relationship", "44298a74-ba52-4f0c-87a3-1824e67d7fad")
+			rel["relationship_type"], rel["source_ref"], rel["target_ref"] = "indicates", ref, "malware--31b940d4-6f7f-459a-80ea-9c1f17b5891b"
+			return c.CreateObject(ctx, string(stixObject(t, rel)))
+		}},
+		{"ReportSighting", func(ctx contractapi.TransactionContextInterface, ref string) error {
+			_, err := c.ReportSighting(ctx, ref, 1, "", "")
+			return err
+		}},
+	}
+	for _, p := range probes {
+		var errs []string
+		for _, ref := range []string{withheld, absent} {
+			err := l.invoke(org2, func(ctx contractapi.TransactionContextInterface) error {
+				return p.probe(ctx, ref)
+			})
+			if err == nil {
+				t.Fatalf("%s accepted %s", p.name, ref)
+			}
+			errs = append(errs, strings.ReplaceAll(err.Error(), ref, "<id>"))
+		}
+		if errs[0] != errs[1] {
+			t.Errorf("%s tells a withheld object (%s) from a missing one (%s)", p.name, errs[0], errs[1])
+		}
+	}
+
+	// With forward references allowed, both are tracked as pending alike
+	admin := newMockIdentity("Org2MSP", "admin", "", true)
+	l.initChannelAdmins(admin, "Org2MSP")
+	if err := l.invoke(admin, func(ctx contractapi.TransactionContextInterface) error {
+		return c.SetChannelConfig(ctx, `{"allow_forward_refs":true}`)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range []string{withheld, absent} {
+		var pending []PendingRef
+		err := l.invoke(org2, func(ctx contractapi.TransactionContextInterface) error {
+			if _, err := c.ReportSighting(ctx, ref, 1, "", ""); err != nil {
+				return err
+			}
+			var err error
+			pending, err = c.GetPendingRefs(ctx, ref)
+			return err
+		})
+		if err != nil || len(pending) != 1 || pending[0].ReferencingID != orgSightingID("Org2MSP", ref) {
+			t.Errorf("sighting of %s: pending %v, %v", ref, pending, err)
+		}
+	}
+}
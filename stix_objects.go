@@ -0,0 +1,591 @@
+// File: stix_objects.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Common STIX 2.1 Properties
+// ──────────────────────────────────────────────────────────────────────────────
+
+// CommonProperties holds the properties shared by every STIX 2.1 SDO and SRO
+type CommonProperties struct {
+	Type               string              `json:"type"`
+	ID                 string              `json:"id"`
+	SpecVersion        string              `json:"spec_version"`
+	Created            string              `json:"created"`
+	Modified           string              `json:"modified"`
+	CreatedByRef       string              `json:"created_by_ref,omitempty"`
+	Revoked            bool                `json:"revoked,omitempty"`
+	Labels             []string            `json:"labels,omitempty"`
+	Confidence         int                 `json:"confidence,omitempty"`
+	Lang               string              `json:"lang,omitempty"`
+	ExternalReferences []ExternalReference `json:"external_references,omitempty"`
+	ObjectMarkingRefs  []string            `json:"object_marking_refs,omitempty"`
+}
+
+// KillChainPhase represents a STIX 2.1 kill chain phase
+type KillChainPhase struct {
+	KillChainName string `json:"kill_chain_name"` // e.g. "mitre-attack"
+	PhaseName     string `json:"phase_name"`      // e.g. "command-and-control"
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) STIX 2.1 Domain Objects (besides Indicator, see cti_stix_chaincode.go)
+// ──────────────────────────────────────────────────────────────────────────────
+
+// AttackPattern represents a STIX 2.1 “attack-pattern” object
+type AttackPattern struct {
+	CommonProperties
+	Name            string           `json:"name"`
+	Description     string           `json:"description,omitempty"`
+	Aliases         []string         `json:"aliases,omitempty"`
+	KillChainPhases []KillChainPhase `json:"kill_chain_phases,omitempty"`
+}
+
+// Campaign represents a STIX 2.1 “campaign” object
+type Campaign struct {
+	CommonProperties
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"`
+	FirstSeen   string   `json:"first_seen,omitempty"`
+	LastSeen    string   `json:"last_seen,omitempty"`
+	Objective   string   `json:"objective,omitempty"`
+}
+
+// CourseOfAction represents a STIX 2.1 “course-of-action” object
+type CourseOfAction struct {
+	CommonProperties
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Grouping represents a STIX 2.1 “grouping” object
+type Grouping struct {
+	CommonProperties
+	Name        string   `json:"name,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Context     string   `json:"context"` // e.g. "suspicious-activity"
+	ObjectRefs  []string `json:"object_refs"`
+}
+
+// Identity represents a STIX 2.1 “identity” object
+type Identity struct {
+	CommonProperties
+	Name               string   `json:"name"`
+	Description        string   `json:"description,omitempty"`
+	Roles              []string `json:"roles,omitempty"`
+	IdentityClass      string   `json:"identity_class,omitempty"` // e.g. "organization"
+	Sectors            []string `json:"sectors,omitempty"`
+	ContactInformation string   `json:"contact_information,omitempty"`
+}
+
+// Incident represents a STIX 2.1 “incident” object
+type Incident struct {
+	CommonProperties
+	Name            string           `json:"name"`
+	Description     string           `json:"description,omitempty"`
+	KillChainPhases []KillChainPhase `json:"kill_chain_phases,omitempty"`
+}
+
+// Infrastructure represents a STIX 2.1 “infrastructure” object
+type Infrastructure struct {
+	CommonProperties
+	Name                string           `json:"name"`
+	Description         string           `json:"description,omitempty"`
+	InfrastructureTypes []string         `json:"infrastructure_types,omitempty"`
+	Aliases             []string         `json:"aliases,omitempty"`
+	KillChainPhases     []KillChainPhase `json:"kill_chain_phases,omitempty"`
+	FirstSeen           string           `json:"first_seen,omitempty"`
+	LastSeen            string           `json:"last_seen,omitempty"`
+}
+
+// IntrusionSet represents a STIX 2.1 “intrusion-set” object
+type IntrusionSet struct {
+	CommonProperties
+	Name                 string   `json:"name"`
+	Description          string   `json:"description,omitempty"`
+	Aliases              []string `json:"aliases,omitempty"`
+	FirstSeen            string   `json:"first_seen,omitempty"`
+	LastSeen             string   `json:"last_seen,omitempty"`
+	Goals                []string `json:"goals,omitempty"`
+	ResourceLevel        string   `json:"resource_level,omitempty"`
+	PrimaryMotivation    string   `json:"primary_motivation,omitempty"`
+	SecondaryMotivations []string `json:"secondary_motivations,omitempty"`
+}
+
+// Location represents a STIX 2.1 “location” object
+type Location struct {
+	CommonProperties
+	Name               string   `json:"name,omitempty"`
+	Description        string   `json:"description,omitempty"`
+	Latitude           *float64 `json:"latitude,omitempty"`
+	Longitude          *float64 `json:"longitude,omitempty"`
+	Precision          *float64 `json:"precision,omitempty"`
+	Region             string   `json:"region,omitempty"`
+	Country            string   `json:"country,omitempty"`
+	AdministrativeArea string   `json:"administrative_area,omitempty"`
+	City               string   `json:"city,omitempty"`
+	StreetAddress      string   `json:"street_address,omitempty"`
+	PostalCode         string   `json:"postal_code,omitempty"`
+}
+
+// Malware represents a STIX 2.1 “malware” object
+type Malware struct {
+	CommonProperties
+	Name                      string           `json:"name,omitempty"`
+	Description               string           `json:"description,omitempty"`
+	MalwareTypes              []string         `json:"malware_types,omitempty"`
+	IsFamily                  bool             `json:"is_family"`
+	Aliases                   []string         `json:"aliases,omitempty"`
+	KillChainPhases           []KillChainPhase `json:"kill_chain_phases,omitempty"`
+	FirstSeen                 string           `json:"first_seen,omitempty"`
+	LastSeen                  string           `json:"last_seen,omitempty"`
+	OperatingSystemRefs       []string         `json:"operating_system_refs,omitempty"`
+	ArchitectureExecutionEnvs []string         `json:"architecture_execution_envs,omitempty"`
+	ImplementationLanguages   []string         `json:"implementation_languages,omitempty"`
+	Capabilities              []string         `json:"capabilities,omitempty"`
+	SampleRefs                []string         `json:"sample_refs,omitempty"`
+}
+
+// MalwareAnalysis represents a STIX 2.1 “malware-analysis” object
+type MalwareAnalysis struct {
+	CommonProperties
+	Product                   string   `json:"product"`
+	Version                   string   `json:"version,omitempty"`
+	HostVMRef                 string   `json:"host_vm_ref,omitempty"`
+	OperatingSystemRef        string   `json:"operating_system_ref,omitempty"`
+	InstalledSoftwareRefs     []string `json:"installed_software_refs,omitempty"`
+	ConfigurationVersion      string   `json:"configuration_version,omitempty"`
+	Modules                   []string `json:"modules,omitempty"`
+	AnalysisEngineVersion     string   `json:"analysis_engine_version,omitempty"`
+	AnalysisDefinitionVersion string   `json:"analysis_definition_version,omitempty"`
+	Submitted                 string   `json:"submitted,omitempty"`
+	AnalysisStarted           string   `json:"analysis_started,omitempty"`
+	AnalysisEnded             string   `json:"analysis_ended,omitempty"`
+	ResultName                string   `json:"result_name,omitempty"`
+	Result                    string   `json:"result,omitempty"`
+	AnalysisSCORefs           []string `json:"analysis_sco_refs,omitempty"`
+	SampleRef                 string   `json:"sample_ref,omitempty"`
+}
+
+// Note represents a STIX 2.1 “note” object
+type Note struct {
+	CommonProperties
+	Abstract   string   `json:"abstract,omitempty"`
+	Content    string   `json:"content"`
+	Authors    []string `json:"authors,omitempty"`
+	ObjectRefs []string `json:"object_refs"`
+}
+
+// ObservedData represents a STIX 2.1 “observed-data” object
+type ObservedData struct {
+	CommonProperties
+	FirstObserved  string   `json:"first_observed"`
+	LastObserved   string   `json:"last_observed"`
+	NumberObserved int      `json:"number_observed"`
+	ObjectRefs     []string `json:"object_refs,omitempty"`
+}
+
+// Opinion represents a STIX 2.1 “opinion” object
+type Opinion struct {
+	CommonProperties
+	Explanation string   `json:"explanation,omitempty"`
+	Authors     []string `json:"authors,omitempty"`
+	Opinion     string   `json:"opinion"` // e.g. "agree"
+	ObjectRefs  []string `json:"object_refs"`
+}
+
+// Report represents a STIX 2.1 “report” object
+type Report struct {
+	CommonProperties
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	ReportTypes []string `json:"report_types,omitempty"`
+	Published   string   `json:"published"`
+	ObjectRefs  []string `json:"object_refs"`
+}
+
+// ThreatActor represents a STIX 2.1 “threat-actor” object
+type ThreatActor struct {
+	CommonProperties
+	Name                 string   `json:"name"`
+	Description          string   `json:"description,omitempty"`
+	ThreatActorTypes     []string `json:"threat_actor_types,omitempty"`
+	Aliases              []string `json:"aliases,omitempty"`
+	FirstSeen            string   `json:"first_seen,omitempty"`
+	LastSeen             string   `json:"last_seen,omitempty"`
+	Roles                []string `json:"roles,omitempty"`
+	Goals                []string `json:"goals,omitempty"`
+	Sophistication       string   `json:"sophistication,omitempty"`
+	ResourceLevel        string   `json:"resource_level,omitempty"`
+	PrimaryMotivation    string   `json:"primary_motivation,omitempty"`
+	SecondaryMotivations []string `json:"secondary_motivations,omitempty"`
+	PersonalMotivations  []string `json:"personal_motivations,omitempty"`
+}
+
+// Tool represents a STIX 2.1 “tool” object
+type Tool struct {
+	CommonProperties
+	Name            string           `json:"name"`
+	Description     string           `json:"description,omitempty"`
+	ToolTypes       []string         `json:"tool_types,omitempty"`
+	Aliases         []string         `json:"aliases,omitempty"`
+	KillChainPhases []KillChainPhase `json:"kill_chain_phases,omitempty"`
+	ToolVersion     string           `json:"tool_version,omitempty"`
+}
+
+// Vulnerability represents a STIX 2.1 “vulnerability” object
+type Vulnerability struct {
+	CommonProperties
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 3) Type Registry
+// ──────────────────────────────────────────────────────────────────────────────
+
+// stixTypeSpec describes how objects of one STIX type are decoded and checked
+type stixTypeSpec struct {
+	required  []string                                     // properties that must be present
+	newObject func() interface{}                           // returns a pointer to the typed struct
+	check     func(props map[string]json.RawMessage) error // optional type-specific rules
+	sco       bool                                         // a cyber-observable, see stix_sco.go
+	common    []string                                     // overrides commonRequired, if set
+}
+
+// commonRequired lists the properties every SDO and SRO must carry
+var commonRequired = []string{"type", "spec_version", "id", "created", "modified"}
+
+// stixTypeSpecs maps each supported STIX 2.1 SDO/SRO/SCO type to its spec
+var stixTypeSpecs = map[string]stixTypeSpec{
+	"attack-pattern":   {required: []string{"name"}, newObject: func() interface{} { return &AttackPattern{} }},
+	"campaign":         {required: []string{"name"}, newObject: func() interface{} { return &Campaign{} }},
+	"course-of-action": {required: []string{"name"}, newObject: func() interface{} { return &CourseOfAction{} }},
+	"grouping":         {required: []string{"context", "object_refs"}, newObject: func() interface{} { return &Grouping{} }},
+	"identity":         {required: []string{"name"}, newObject: func() interface{} { return &Identity{} }},
+	"incident":         {required: []string{"name"}, newObject: func() interface{} { return &Incident{} }},
+	"indicator":        {required: []string{"pattern", "pattern_type", "valid_from"}, newObject: func() interface{} { return &Indicator{} }},
+	"infrastructure":   {required: []string{"name"}, newObject: func() interface{} { return &Infrastructure{} }},
+	"intrusion-set":    {required: []string{"name"}, newObject: func() interface{} { return &IntrusionSet{} }},
+	"location":         {newObject: func() interface{} { return &Location{} }, check: checkLocation},
+	"malware":          {required: []string{"is_family"}, newObject: func() interface{} { return &Malware{} }, check: checkMalware},
+	"malware-analysis": {required: []string{"product"}, newObject: func() interface{} { return &MalwareAnalysis{} }, check: checkMalwareAnalysis},
+	"note":             {required: []string{"content", "object_refs"}, newObject: func() interface{} { return &Note{} }},
+	"observed-data":    {required: []string{"first_observed", "last_observed", "number_observed"}, newObject: func() interface{} { return &ObservedData{} }, check: checkObservedData},
+	"opinion":          {required: []string{"opinion", "object_refs"}, newObject: func() interface{} { return &Opinion{} }},
+	"report":           {required: []string{"name", "published", "object_refs"}, newObject: func() interface{} { return &Report{} }},
+	"threat-actor":     {required: []string{"name"}, newObject: func() interface{} { return &ThreatActor{} }},
+	"tool":             {required: []string{"name"}, newObject: func() interface{} { return &Tool{} }},
+	"vulnerability":    {required: []string{"name"}, newObject: func() interface{} { return &Vulnerability{} }},
+	"relationship":     {required: []string{"relationship_type", "source_ref", "target_ref"}, newObject: func() interface{} { return &Relationship{} }},
+	"sighting":         {required: []string{"sighting_of_ref"}, newObject: func() interface{} { return &Sighting{} }},
+
+	"marking-definition":   {common: markingRequired, newObject: func() interface{} { return &MarkingDefinition{} }, check: checkMarkingDefinition},
+	"extension-definition": {required: []string{"created_by_ref", "name", "schema", "version", "extension_types"}, newObject: func() interface{} { return &ExtensionDefinition{} }, check: checkExtensionDefinition},
+
+	"artifact":             {sco: true, newObject: func() interface{} { return &Artifact{} }, check: checkArtifact},
+	"autonomous-system":    {sco: true, required: []string{"number"}, newObject: func() interface{} { return &AutonomousSystem{} }},
+	"directory":            {sco: true, required: []string{"path"}, newObject: func() interface{} { return &Directory{} }},
+	"domain-name":          {sco: true, required: []string{"value"}, newObject: func() interface{} { return &DomainName{} }},
+	"email-addr":           {sco: true, required: []string{"value"}, newObject: func() interface{} { return &EmailAddr{} }},
+	"email-message":        {sco: true, required: []string{"is_multipart"}, newObject: func() interface{} { return &EmailMessage{} }},
+	"file":                 {sco: true, newObject: func() interface{} { return &File{} }, check: checkFile},
+	"ipv4-addr":            {sco: true, required: []string{"value"}, newObject: func() interface{} { return &IPv4Addr{} }, check: checkIPAddr("ipv4-addr")},
+	"ipv6-addr":            {sco: true, required: []string{"value"}, newObject: func() interface{} { return &IPv6Addr{} }, check: checkIPAddr("ipv6-addr")},
+	"mac-addr":             {sco: true, required: []string{"value"}, newObject: func() interface{} { return &MACAddr{} }},
+	"mutex":                {sco: true, required: []string{"name"}, newObject: func() interface{} { return &Mutex{} }},
+	"network-traffic":      {sco: true, required: []string{"protocols"}, newObject: func() interface{} { return &NetworkTraffic{} }, check: checkNetworkTraffic},
+	"process":              {sco: true, newObject: func() interface{} { return &Process{} }},
+	"software":             {sco: true, required: []string{"name"}, newObject: func() interface{} { return &Software{} }},
+	"url":                  {sco: true, required: []string{"value"}, newObject: func() interface{} { return &URL{} }},
+	"user-account":         {sco: true, newObject: func() interface{} { return &UserAccount{} }},
+	"windows-registry-key": {sco: true, newObject: func() interface{} { return &WindowsRegistryKey{} }},
+	"x509-certificate":     {sco: true, newObject: func() interface{} { return &X509Certificate{} }},
+}
+
+// extensionTypeSpec is used for object types introduced by a “new-sdo”, “new-sco” or
+// “new-sro” extension definition; only the common properties can be checked
+var extensionTypeSpec = stixTypeSpec{newObject: func() interface{} { return &map[string]interface{}{} }}
+
+// hasProperty reports whether a property is present and not null
+func hasProperty(props map[string]json.RawMessage, name string) bool {
+	value, ok := props[name]
+	return ok && string(value) != "null"
+}
+
+// checkLocation enforces that a location carries a region, a country or coordinates
+func checkLocation(props map[string]json.RawMessage) error {
+	if !hasProperty(props, "region") && !hasProperty(props, "country") &&
+		!(hasProperty(props, "latitude") && hasProperty(props, "longitude")) {
+		return fmt.Errorf("location requires region, country, or latitude and longitude")
+	}
+	if hasProperty(props, "latitude") != hasProperty(props, "longitude") {
+		return fmt.Errorf("location latitude and longitude must be given together")
+	}
+	return nil
+}
+
+// checkMalware enforces that a malware family carries a name
+func checkMalware(props map[string]json.RawMessage) error {
+	var isFamily bool
+	if err := json.Unmarshal(props["is_family"], &isFamily); err != nil {
+		return fmt.Errorf("malware is_family must be a boolean")
+	}
+	if isFamily && !hasProperty(props, "name") {
+		return fmt.Errorf("malware with is_family true requires name")
+	}
+	return nil
+}
+
+// checkMalwareAnalysis enforces that an analysis carries a result or analysed SCOs
+func checkMalwareAnalysis(props map[string]json.RawMessage) error {
+	if !hasProperty(props, "result") && !hasProperty(props, "analysis_sco_refs") {
+		return fmt.Errorf("malware-analysis requires result or analysis_sco_refs")
+	}
+	return nil
+}
+
+// checkObservedData enforces that observed data references what was observed
+func checkObservedData(props map[string]json.RawMessage) error {
+	if !hasProperty(props, "object_refs") {
+		return fmt.Errorf("observed-data requires object_refs")
+	}
+	return nil
+}
+
+// decodeStixObject parses raw JSON as a STIX object of the expected type and runs
+// the validation layer over it: required properties, naming rules, the STIX 2.1
+// MUST rules and type-specific rules. It returns the typed object, the decoded
+// property map and the object's ID, or a *ValidationError listing every violation.
+func decodeStixObject(expectedType string, raw []byte) (interface{}, map[string]json.RawMessage, string, error) {
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &props); err != nil {
+		return nil, nil, "", fmt.Errorf("failed to parse %s JSON: %v", expectedType, err)
+	}
+
+	typ, id := stringProperty(props, "type"), stringProperty(props, "id")
+	if typ != expectedType {
+		return nil, nil, "", fmt.Errorf("asset type must be '%s', got '%s'", expectedType, typ)
+	}
+
+	required := append([]string{}, commonRequired...)
+	uuidVersions := "4"
+	spec, ok := stixTypeSpecs[expectedType]
+	if spec.sco {
+		required, uuidVersions = []string{"type", "id"}, "45"
+	}
+	if spec.common != nil {
+		required = append([]string{}, spec.common...)
+	}
+	if !ok {
+		// Types outside the registry are only accepted when an extension defines them
+		switch newTypeExtension(props) {
+		case "new-sdo", "new-sro":
+		case "new-sco":
+			required, uuidVersions = []string{"type", "id"}, "45"
+		default:
+			return nil, nil, "", fmt.Errorf("unsupported STIX object type '%s'", expectedType)
+		}
+		spec = extensionTypeSpec
+	}
+
+	verr := &ValidationError{Type: typ, ID: id}
+	for _, name := range append(required, spec.required...) {
+		if !hasProperty(props, name) {
+			verr.add(name, "required property is missing")
+		}
+	}
+	checkPropertyNames(props, verr)
+	validateStixObject(typ, props, uuidVersions, verr)
+	if spec.check != nil && len(verr.Errors) == 0 {
+		if err := spec.check(props); err != nil {
+			verr.add("", "%v", err)
+		}
+	}
+	if spec.sco && len(verr.Errors) == 0 {
+		checkObservableID(typ, props, verr)
+	}
+
+	obj := spec.newObject()
+	if err := json.Unmarshal(raw, obj); err != nil {
+		verr.add("", "property has the wrong JSON type: %v", err)
+	}
+	if len(verr.Errors) > 0 {
+		return nil, nil, "", verr
+	}
+	return obj, props, id, nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 4) Generic Object Methods
+// ──────────────────────────────────────────────────────────────────────────────
+
+// createTypedObject validates raw JSON as a STIX object of the expected type and
+// writes it, unmodified, into world state under its type and STIX ID
+func (c *CTIStixContract) createTypedObject(
+	ctx contractapi.TransactionContextInterface,
+	expectedType string,
+	raw []byte,
+) error {
+	if err := c.requirePermission(ctx, actionCreate); err != nil {
+		return err
+	}
+
+	sub, err := c.getSubmitterIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	identityID, err := c.ensureOrgIdentity(ctx, sub.MSPID)
+	if err != nil {
+		return err
+	}
+	if raw, err = stampCreatedByRef(raw, identityID); err != nil {
+		return err
+	}
+	if raw, err = withObservableID(raw); err != nil {
+		return err
+	}
+	_, props, id, err := decodeStixObject(expectedType, raw)
+	if err != nil {
+		return err
+	}
+	if _, ok := tlpLevels[id]; ok {
+		return nil // predefined TLP marking, never stored
+	}
+	if err := c.checkExtensions(ctx, props, nil); err != nil {
+		return err
+	}
+	if err := c.checkMarkingRefs(ctx, props, nil); err != nil {
+		return err
+	}
+	if err := c.checkSingleOpinion(ctx, id, props, nil); err != nil {
+		return err
+	}
+	if err := c.requireRevokePermission(ctx, props); err != nil {
+		return err
+	}
+	exists, err := c.stixObjectExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if exists && isObservableObject(expectedType, props) {
+		public, err := c.publicViewer(ctx)
+		if err != nil {
+			return err
+		}
+		event, err := c.mergeObservable(ctx, id, raw, props, sub.MSPID, public)
+		if err != nil {
+			return err
+		}
+		return c.emitEvents(ctx, []ObjectEvent{event})
+	}
+	stub, err := c.getPrivateStub(ctx, id)
+	if err != nil {
+		return err
+	}
+	if exists || stub != nil {
+		return fmt.Errorf("%s with ID %s already exists", expectedType, id)
+	}
+
+	cfg, err := c.getChannelConfig(ctx)
+	if err != nil {
+		return err
+	}
+	v, err := c.orgViewer(ctx, sub.MSPID)
+	if err != nil {
+		return err
+	}
+	missing, err := c.checkObjectRefs(ctx, v, expectedType, props, nil, cfg.AllowForwardRefs)
+	if err != nil {
+		return err
+	}
+	original, err := c.findDuplicate(ctx, cfg, v, id, props, nil)
+	if err != nil {
+		return err
+	}
+
+	rec, err := newLedgerRecord(raw)
+	if err != nil {
+		return err
+	}
+	rec.CreatorMSP, rec.CreatorSubject = sub.MSPID, sub.Subject
+	if isObservableObject(expectedType, props) {
+		rec.ReportedBy = []string{sub.MSPID}
+	}
+	if err := c.putStixObject(ctx, rec); err != nil {
+		return err
+	}
+	if err := c.updateObjectIndexes(ctx, id, nil, props); err != nil {
+		return err
+	}
+	if err := c.resolvePendingRefs(ctx, id); err != nil {
+		return err
+	}
+	if err := c.recordPendingRefs(ctx, id, missing); err != nil {
+		return err
+	}
+
+	public, err := c.publicViewer(ctx)
+	if err != nil {
+		return err
+	}
+	events := []ObjectEvent{newObjectEvent(ctx, createdEventName(expectedType), rec, props, public)}
+	if original != nil {
+		link, linkProps, err := c.linkDuplicate(ctx, sub.MSPID, sub.Subject, id, props, original)
+		if err != nil {
+			return err
+		}
+		events = append(events, newObjectEvent(ctx, objectCreatedEvent, link, linkProps, public))
+	}
+	return c.emitEvents(ctx, events)
+}
+
+// CreateObject writes any supported STIX 2.1 SDO, SRO, SCO or bundle into world state,
+// dispatching on the object's “type” property
+func (c *CTIStixContract) CreateObject(
+	ctx contractapi.TransactionContextInterface,
+	jsonStr string,
+) error {
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal([]byte(jsonStr), &header); err != nil {
+		return fmt.Errorf("failed to parse STIX object JSON: %v", err)
+	}
+	if header.Type == "bundle" {
+		_, err := c.CreateBundle(ctx, jsonStr)
+		return err
+	}
+	return c.createTypedObject(ctx, header.Type, []byte(jsonStr))
+}
+
+// ReadObject retrieves any stored STIX object by its STIX ID, exactly as it was
+// submitted, after checking it against the typed struct for its type. Revoked
+// objects are refused unless includeRevoked is set.
+func (c *CTIStixContract) ReadObject(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+	includeRevoked bool,
+) (string, error) {
+	rec, obj, err := c.readVisibleObject(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if rec.Revoked && !includeRevoked {
+		return "", fmt.Errorf("%s has been revoked: %s", id, rec.Reason)
+	}
+	if spec, ok := stixTypeSpecs[rec.Type]; ok {
+		if err := json.Unmarshal(obj, spec.newObject()); err != nil {
+			return "", fmt.Errorf("failed to unmarshal %s JSON: %v", rec.Type, err)
+		}
+	}
+	return string(obj), nil
+}
@@ -0,0 +1,199 @@
+// File: stix_objects_test.go
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// readObject runs ReadObject as id and decodes the object it returns
+func (l *mockLedger) readObject(id *mockIdentity, objectID string) (map[string]json.RawMessage, error) {
+	var props map[string]json.RawMessage
+	err := l.invoke(id, func(ctx contractapi.TransactionContextInterface) error {
+		out, err := (&CTIStixContract{}).ReadObject(ctx, objectID, false)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(out), &props)
+	})
+	return props, err
+}
+
+// sameProperties returns the properties of want that got lacks or holds differently
+func sameProperties(t *testing.T, got map[string]json.RawMessage, want map[string]interface{}) []string {
+	t.Helper()
+	var wantProps map[string]interface{}
+	if err := json.Unmarshal(stixObject(t, want), &wantProps); err != nil {
+		t.Fatal(err)
+	}
+	var differ []string
+	for name, value := range wantProps {
+		var stored interface{}
+		if err := json.Unmarshal(got[name], &stored); err != nil || !reflect.DeepEqual(stored, value) {
+			differ = append(differ, name)
+		}
+	}
+	sort.Strings(differ)
+	return differ
+}
+
+func TestCreateObjectEveryType(t *testing.T) {
+	const indicator = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+	const ip = "ipv4-addr--cb9d70c5-d244-531c-9efb-bcae57adc793"
+	withProps := func(typ, uuid string, props map[string]interface{}) map[string]interface{} {
+		obj := sdo(typ, uuid)
+		for name, value := range props {
+			obj[name] = value
+		}
+		return obj
+	}
+	objects := []map[string]interface{}{
+		withProps("attack-pattern", "7e33a43e-e34b-40ec-89da-36c9bb2cacd5", map[string]interface{}{"name": "Spearphishing Attachment"}),
+		withProps("campaign", "1b7b0c07-8b0e-4b8e-9a1f-0f1e2d3c4b5a", map[string]interface{}{"name": "Green Group Attacks"}),
+		withProps("course-of-action", "2c8c1d18-9c1f-4c9f-8b2a-1a2b3c4d5e6f", map[string]interface{}{"name": "Block 203.0.113.45"}),
+		withProps("grouping", "3d9d2e29-ad2a-4dad-9c3b-2b3c4d5e6f70", map[string]interface{}{"context": "suspicious-activity", "object_refs": []string{indicator}}),
+		withProps("identity", "4eae3f3a-be3b-4ebe-8d4c-3c4d5e6f7081", map[string]interface{}{"name": "ACME Widget", "identity_class": "organization"}),
+		withProps("incident", "5fbf404b-cf4c-4fcf-9e5d-4d5e6f708192", map[string]interface{}{"name": "Mail server compromise"}),
+		withProps("infrastructure", "60c0515c-d05d-40d0-af6e-5e6f708192a3", map[string]interface{}{"name": "Poison Ivy C2"}),
+		withProps("intrusion-set", "71d1626d-e16e-41e1-807f-6f708192a3b4", map[string]interface{}{"name": "Bobcat Breakin"}),
+		withProps("location", "82e2737e-f27f-42f2-9180-708192a3b4c5", map[string]interface{}{"country": "DE"}),
+		withProps("malware", "31b940d4-6f7f-459a-80ea-9c1f17b5891b", map[string]interface{}{"name": "Poison Ivy", "is_family": true}),
+		withProps("malware-analysis", "93f3848f-0380-43f3-a291-8192a3b4c5d6", map[string]interface{}{"product": "sandbox", "result": "malicious"}),
+		withProps("note", "a4049590-1491-4404-b3a2-92a3b4c5d6e7", map[string]interface{}{"content": "Seen in two campaigns", "object_refs": []string{indicator}}),
+		withProps("observed-data", "b515a6a1-25a2-4515-84b3-a3b4c5d6e7f8", map[string]interface{}{
+			"first_observed": "2025-05-01T10:00:00Z", "last_observed": "2025-05-01T11:00:00Z", "number_observed": 3, "object_refs": []string{ip},
+		}),
+		withProps("opinion", "c626b7b2-36b3-4626-95c4-b4c5d6e7f809", map[string]interface{}{"opinion": "agree", "object_refs": []string{indicator}}),
+		withProps("report", "d737c8c3-47c4-4737-a6d5-c5d6e7f8091a", map[string]interface{}{"name": "Poison Ivy", "published": "2025-05-02T00:00:00Z", "object_refs": []string{indicator}}),
+		withProps("threat-actor", "e848d9d4-58d5-4848-b7e6-d6e7f8091a2b", map[string]interface{}{"name": "Evil Org"}),
+		withProps("tool", "f959eae5-69e6-4959-88f7-e7f8091a2b3c", map[string]interface{}{"name": "VNC"}),
+		withProps("vulnerability", "0a6afbf6-7af7-4a6a-99a8-f8091a2b3c4d", map[string]interface{}{"name": "CVE-2016-1234"}),
+		withProps("relationship", "44298a74-ba52-4f0c-87a3-1824e67d7fad", map[string]interface{}{
+			"relationship_type": "indicates", "source_ref": indicator, "target_ref": "malware--31b940d4-6f7f-459a-80ea-9c1f17b5891b",
+		}),
+		withProps("sighting", "1b7b0c07-8b1a-4b7b-aab9-091a2b3c4d5e", map[string]interface{}{"sighting_of_ref": indicator}),
+	}
+
+	l := newMockLedger(t)
+	producer := newMockIdentity("Org1MSP", "analyst", "producer", false)
+	l.create(producer, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45"))
+	l.create(producer, map[string]interface{}{"type": "ipv4-addr", "spec_version": "2.1", "id": ip, "value": "203.0.113.45"})
+	for _, obj := range objects {
+		l.create(producer, obj)
+		l.events() // the MockStub blocks once its event channel is full
+		got, err := l.readObject(producer, obj["id"].(string))
+		if err != nil {
+			t.Errorf("ReadObject(%s): %v", obj["id"], err)
+			continue
+		}
+		if differ := sameProperties(t, got, obj); len(differ) > 0 {
+			t.Errorf("%s read back with different %v", obj["type"], differ)
+		}
+	}
+
+	// Each type's required properties are checked, and unknown types are refused
+	for _, tt := range []struct {
+		obj     map[string]interface{}
+		errText string
+	}{
+		{withProps("campaign", "11111111-1111-4111-8111-111111111111", nil), `{"field":"name","message":"required property is missing"}`},
+		{withProps("report", "22222222-2222-4222-8222-222222222222", map[string]interface{}{"name": "r", "object_refs": []string{indicator}}), `{"field":"published","message":"required property is missing"}`},
+		{withProps("opinion", "33333333-3333-4333-8333-333333333333", map[string]interface{}{"opinion": "agree"}), `{"field":"object_refs","message":"required property is missing"}`},
+		{withProps("location", "44444444-4444-4444-8444-444444444444", nil), "location requires region, country, or latitude and longitude"},
+		{withProps("malware", "55555555-5555-4555-8555-555555555555", map[string]interface{}{"is_family": true}), "malware with is_family true requires name"},
+		{withProps("x-unknown", "66666666-6666-4666-8666-666666666666", nil), "unsupported STIX object type 'x-unknown'"},
+	} {
+		err := l.invoke(producer, func(ctx contractapi.TransactionContextInterface) error {
+			return (&CTIStixContract{}).CreateObject(ctx, string(stixObject(t, tt.obj)))
+		})
+		if err == nil || !strings.Contains(err.Error(), tt.errText) {
+			t.Errorf("CreateObject(%s): error %v, want %q", tt.obj["id"], err, tt.errText)
+		}
+	}
+}
+
+// Objects are stored as submitted, minus insignificant whitespace: custom
+// properties, extensions and property order all survive
+func TestCreateObjectIsLossless(t *testing.T) {
+	const definition = "extension-definition--d83fce45-ef58-4c6c-a3f4-1fbc32e98c6e"
+	creator := orgIdentityID("Org1MSP")
+	objects := []string{
+		`{
+			"type": "extension-definition", "spec_version": "2.1", "id": "` + definition + `",
+			"created_by_ref": "` + creator + `",
+			"created": "2025-05-01T12:15:00.000Z", "modified": "2025-05-01T12:15:00.000Z",
+			"name": "Widget scoring", "schema": "https://example.com/widget.json", "version": "1.0.0",
+			"extension_types": ["property-extension", "new-sdo"]
+		}`,
+		`{
+			"valid_until": "2026-05-01T00:00:00Z",
+			"type": "indicator", "spec_version": "2.1", "id": "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f",
+			"created_by_ref": "` + creator + `",
+			"created": "2025-05-01T12:15:00.000Z", "modified": "2025-05-01T12:15:00.000Z",
+			"pattern": "[ipv4-addr:value = '203.0.113.45']", "pattern_type": "stix", "valid_from": "2025-05-01T00:00:00Z",
+			"kill_chain_phases": [{"kill_chain_name": "lockheed-martin-cyber-kill-chain", "phase_name": "delivery"}],
+			"object_marking_refs": ["` + tlpGreen + `"],
+			"x_acme_score": 1.50,
+			"x_acme_tags": {"b": [1, 2], "a": null},
+			"extensions": {"` + definition + `": {"extension_type": "property-extension", "rank": 5}}
+		}`,
+		`{
+			"type": "x-acme-widget", "spec_version": "2.1", "id": "x-acme-widget--1f2e3d4c-5b6a-4978-8897-a6b5c4d3e2f1",
+			"created_by_ref": "` + creator + `",
+			"created": "2025-05-01T12:15:00.000Z", "modified": "2025-05-01T12:15:00.000Z",
+			"serial": "W-42",
+			"extensions": {"` + definition + `": {"extension_type": "new-sdo"}}
+		}`,
+	}
+
+	l := newMockLedger(t)
+	producer := newMockIdentity("Org1MSP", "analyst", "producer", false)
+	for _, obj := range objects {
+		var want bytes.Buffer
+		if err := json.Compact(&want, []byte(obj)); err != nil {
+			t.Fatal(err)
+		}
+		var header struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(want.Bytes(), &header); err != nil {
+			t.Fatal(err)
+		}
+		err := l.invoke(producer, func(ctx contractapi.TransactionContextInterface) error {
+			return (&CTIStixContract{}).CreateObject(ctx, obj)
+		})
+		if err != nil {
+			t.Fatalf("CreateObject(%s): %v", header.ID, err)
+		}
+		var got string
+		err = l.invoke(producer, func(ctx contractapi.TransactionContextInterface) error {
+			got, err = (&CTIStixContract{}).ReadObject(ctx, header.ID, false)
+			return err
+		})
+		if err != nil || got != want.String() {
+			t.Errorf("ReadObject(%s) = %s, %v; want %s", header.ID, got, err, want.String())
+		}
+	}
+
+	// Extensions must be defined on the ledger, for the extension_type they claim
+	for _, tt := range []struct{ extensions, errText string }{
+		{`{"extension-definition--00000000-0000-4000-8000-000000000000": {"extension_type": "property-extension"}}`, "is not defined on the ledger"},
+		{`{"` + definition + `": {"extension_type": "toplevel-property-extension"}}`, "does not allow extension_type 'toplevel-property-extension'"},
+	} {
+		props := testIndicator("0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21", "198.51.100.7")
+		props["extensions"] = json.RawMessage(tt.extensions)
+		err := l.invoke(producer, func(ctx contractapi.TransactionContextInterface) error {
+			return (&CTIStixContract{}).CreateObject(ctx, string(stixObject(t, props)))
+		})
+		if err == nil || !strings.Contains(err.Error(), tt.errText) {
+			t.Errorf("extensions %s: error %v, want %q", tt.extensions, err, tt.errText)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+// File: stix_observables.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Observable Normalization
+// ──────────────────────────────────────────────────────────────────────────────
+
+// observableObjectType is the composite-key namespace of the observable-value index:
+// observableObjectType + [observable type, normalized value, object ID]
+const observableObjectType = "obs"
+
+// observableTypes are the observable types that are indexed; "file" is indexed by
+// the values of its hashes
+var observableTypes = map[string]bool{
+	"ipv4-addr":   true,
+	"ipv6-addr":   true,
+	"domain-name": true,
+	"url":         true,
+	"email-addr":  true,
+	"file":        true,
+}
+
+// observableValue is one normalized observable an object refers to
+type observableValue struct {
+	Type  string
+	Value string
+}
+
+// normalizeObservable brings a value into the form it is indexed under:
+//   - IP addresses become CIDR blocks (a single address is a /32 or /128);
+//   - domain names are lower-cased, without a trailing dot;
+//   - URLs get a lower-cased scheme and host;
+//   - e-mail addresses and file hashes are lower-cased.
+func normalizeObservable(typ, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", fmt.Errorf("empty %s value", typ)
+	}
+	switch typ {
+	case "ipv4-addr", "ipv6-addr":
+		block, err := parseIPBlock(typ, value)
+		if err != nil {
+			return "", err
+		}
+		return block.String(), nil
+
+	case "domain-name":
+		value = strings.TrimSuffix(strings.ToLower(value), ".")
+		if value == "" || strings.ContainsAny(value, " /:@") {
+			return "", fmt.Errorf("'%s' is not a domain name", value)
+		}
+		return value, nil
+
+	case "url":
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "", fmt.Errorf("'%s' is not an absolute URL", value)
+		}
+		u.Scheme = strings.ToLower(u.Scheme)
+		u.Host = strings.ToLower(u.Host)
+		return u.String(), nil
+
+	case "email-addr", "file":
+		return strings.ToLower(value), nil
+	}
+	return "", fmt.Errorf("observable type '%s' is not indexed", typ)
+}
+
+// parseIPBlock parses an address or CIDR block of the given IP version
+func parseIPBlock(typ, value string) (*net.IPNet, error) {
+	bits := 32
+	if typ == "ipv6-addr" {
+		bits = 128
+	}
+	var block *net.IPNet
+	if ip := net.ParseIP(value); ip != nil {
+		block = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	} else if _, b, err := net.ParseCIDR(value); err == nil {
+		block = b
+	} else {
+		return nil, fmt.Errorf("'%s' is not an IP address or CIDR block", value)
+	}
+
+	if isV4 := block.IP.To4() != nil; isV4 != (bits == 32) {
+		return nil, fmt.Errorf("'%s' is not a valid %s value", value, typ)
+	}
+	if bits == 32 {
+		block.IP = block.IP.To4()
+	}
+	ones, size := block.Mask.Size()
+	if size != bits {
+		return nil, fmt.Errorf("'%s' is not a valid %s value", value, typ)
+	}
+	block.Mask = net.CIDRMask(ones, bits)
+	block.IP = block.IP.Mask(block.Mask)
+	return block, nil
+}
+
+// objectObservables returns the normalized observables an object refers to: the
+// values an indicator's STIX pattern matches, or the value of an observable object
+// itself. Values that cannot be normalized are not indexed.
+func objectObservables(typ string, props map[string]json.RawMessage) []observableValue {
+	var raw []observableValue
+	switch {
+	case typ == "indicator":
+		found, err := validateIndicatorPattern(stringProperty(props, "pattern_type"), stringProperty(props, "pattern"))
+		if err != nil {
+			return nil
+		}
+		for _, o := range found {
+			switch {
+			case o.ObjectType == "file" && strings.HasPrefix(o.Property, "hashes."):
+				raw = append(raw, observableValue{Type: "file", Value: o.Value})
+			case observableTypes[o.ObjectType] && o.ObjectType != "file" && o.Property == "value":
+				raw = append(raw, observableValue{Type: o.ObjectType, Value: o.Value})
+			}
+		}
+
+	case typ == "file":
+		var hashes map[string]string
+		if err := json.Unmarshal(props["hashes"], &hashes); err == nil {
+			for _, algo := range sortedStringKeys(hashes) {
+				raw = append(raw, observableValue{Type: "file", Value: hashes[algo]})
+			}
+		}
+
+	case observableTypes[typ]:
+		raw = append(raw, observableValue{Type: typ, Value: stringProperty(props, "value")})
+	}
+
+	var out []observableValue
+	seen := map[observableValue]bool{}
+	for _, o := range raw {
+		value, err := normalizeObservable(o.Type, o.Value)
+		if err != nil {
+			continue
+		}
+		o.Value = value
+		if !seen[o] {
+			seen[o] = true
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// sortedStringKeys returns the keys of a string map in a stable order
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// lookupValues returns the index values to probe for a looked-up observable. For IP
+// addresses these are every block that contains it, from the value itself up to
+// 0.0.0.0/0 (or ::/0), so that an indicator on 203.0.113.0/24 matches 203.0.113.45.
+func lookupValues(typ, value string) ([]string, error) {
+	normalized, err := normalizeObservable(typ, value)
+	if err != nil {
+		return nil, err
+	}
+	if typ != "ipv4-addr" && typ != "ipv6-addr" {
+		return []string{normalized}, nil
+	}
+
+	block, err := parseIPBlock(typ, value)
+	if err != nil {
+		return nil, err
+	}
+	ones, bits := block.Mask.Size()
+	values := make([]string, 0, ones+1)
+	for prefix := ones; prefix >= 0; prefix-- {
+		mask := net.CIDRMask(prefix, bits)
+		values = append(values, (&net.IPNet{IP: block.IP.Mask(mask), Mask: mask}).String())
+	}
+	return values, nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Observable Lookup
+// ──────────────────────────────────────────────────────────────────────────────
+
+// ObservableMatches is the result of LookupObservable
+type ObservableMatches struct {
+	Type        string            `json:"type"`
+	Value       string            `json:"value"`       // the normalized value that was looked up
+	Indicators  []json.RawMessage `json:"indicators"`  // indicators whose pattern matches the value
+	Sightings   []json.RawMessage `json:"sightings"`   // sightings of those indicators
+	Observables []json.RawMessage `json:"observables"` // observable objects with the value
+}
+
+// LookupObservable returns the indicators, the sightings of those indicators and the
+// observable objects that refer to an observable value. typ is one of ipv4-addr,
+// ipv6-addr, domain-name, url, email-addr or file (value is then a hash). IP lookups
+// also match the CIDR blocks that contain the address. Revoked objects are skipped.
+func (c *CTIStixContract) LookupObservable(
+	ctx contractapi.TransactionContextInterface,
+	typ string,
+	value string,
+) (string, error) {
+	if !observableTypes[typ] {
+		return "", fmt.Errorf("observable type '%s' is not indexed", typ)
+	}
+	values, err := lookupValues(typ, value)
+	if err != nil {
+		return "", err
+	}
+
+	matches := &ObservableMatches{
+		Type:        typ,
+		Value:       values[0],
+		Indicators:  []json.RawMessage{},
+		Sightings:   []json.RawMessage{},
+		Observables: []json.RawMessage{},
+	}
+	v, err := c.newObjectViewer(ctx)
+	if err != nil {
+		return "", err
+	}
+	seen := map[string]bool{}
+	add := func(id string) (bool, error) {
+		if seen[id] {
+			return false, nil
+		}
+		seen[id] = true
+		rec, err := c.getStixObject(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		if rec.Revoked {
+			return false, nil
+		}
+		obj, err := v.view(rec)
+		if err != nil || obj == nil {
+			return false, err
+		}
+		switch rec.Type {
+		case "indicator":
+			matches.Indicators = append(matches.Indicators, obj)
+		case "sighting":
+			matches.Sightings = append(matches.Sightings, obj)
+		default:
+			matches.Observables = append(matches.Observables, obj)
+		}
+		return true, nil
+	}
+
+	for _, v := range values {
+		ids, err := c.indexedIDs(ctx, observableObjectType, []string{typ, v})
+		if err != nil {
+			return "", err
+		}
+		for _, id := range ids {
+			added, err := add(id)
+			if err != nil {
+				return "", err
+			}
+			if !added || !strings.HasPrefix(id, "indicator--") {
+				continue
+			}
+			sightings, err := c.indexedIDs(ctx, sightingOfObjectType, []string{id})
+			if err != nil {
+				return "", err
+			}
+			for _, sightingID := range sightings {
+				if _, err := add(sightingID); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+	return resultJSON(matches)
+}
@@ -0,0 +1,137 @@
+// File: stix_observables_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeObservable(t *testing.T) {
+	tests := []struct {
+		typ, value string
+		want       string // "" if the value is rejected
+	}{
+		{"ipv4-addr", "203.0.113.45", "203.0.113.45/32"},
+		{"ipv4-addr", " 203.0.113.45 ", "203.0.113.45/32"},
+		{"ipv4-addr", "203.0.113.45/24", "203.0.113.0/24"},
+		{"ipv4-addr", "::ffff:203.0.113.45", "203.0.113.45/32"},
+		{"ipv4-addr", "2001:db8::1", ""},
+		{"ipv4-addr", "203.0.113", ""},
+		{"ipv6-addr", "2001:DB8::1", "2001:db8::1/128"},
+		{"ipv6-addr", "2001:db8:0:0:1::/64", "2001:db8::/64"},
+		{"ipv6-addr", "203.0.113.45", ""},
+		{"domain-name", "Example.COM.", "example.com"},
+		{"domain-name", "evil example.com", ""},
+		{"url", "HTTP://Evil.Example/Path?q=A", "http://evil.example/Path?q=A"},
+		{"url", "/relative/path", ""},
+		{"email-addr", "John@Example.com", "john@example.com"},
+		{"file", "ABCDEF0123", "abcdef0123"},
+		{"mutex", "x", ""},
+		{"domain-name", "  ", ""},
+	}
+	for _, tt := range tests {
+		got, err := normalizeObservable(tt.typ, tt.value)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("normalizeObservable(%q, %q) = %q, want an error", tt.typ, tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("normalizeObservable(%q, %q) = %q, %v, want %q", tt.typ, tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestLookupValues(t *testing.T) {
+	tests := []struct {
+		typ, value string
+		count      int
+		first      []string // the most specific values, in order
+		last       string
+	}{
+		{"ipv4-addr", "203.0.113.45", 33, []string{"203.0.113.45/32", "203.0.113.44/31", "203.0.113.44/30"}, "0.0.0.0/0"},
+		{"ipv4-addr", "198.51.100.0/24", 25, []string{"198.51.100.0/24", "198.51.100.0/23"}, "0.0.0.0/0"},
+		{"ipv6-addr", "2001:db8::1", 129, []string{"2001:db8::1/128", "2001:db8::/127"}, "::/0"},
+		{"domain-name", "Example.com", 1, []string{"example.com"}, "example.com"},
+	}
+	for _, tt := range tests {
+		got, err := lookupValues(tt.typ, tt.value)
+		if err != nil {
+			t.Errorf("lookupValues(%q, %q): %v", tt.typ, tt.value, err)
+			continue
+		}
+		if len(got) != tt.count || !reflect.DeepEqual(got[:len(tt.first)], tt.first) || got[len(got)-1] != tt.last {
+			t.Errorf("lookupValues(%q, %q) = %v", tt.typ, tt.value, got)
+		}
+	}
+
+	// An indicator on a block is found by every address inside it, and only by those
+	values, err := lookupValues("ipv4-addr", "203.0.113.45")
+	if err != nil {
+		t.Fatal(err)
+	}
+	probes := map[string]bool{}
+	for _, v := range values {
+		probes[v] = true
+	}
+	for block, want := range map[string]bool{"203.0.113.0/24": true, "203.0.0.0/16": true, "203.0.112.0/24": false, "203.0.113.46/32": false} {
+		if probes[block] != want {
+			t.Errorf("probe of %s = %v, want %v", block, probes[block], want)
+		}
+	}
+
+	if _, err := lookupValues("ipv4-addr", "not-an-ip"); err == nil {
+		t.Error("lookupValues accepted an invalid address")
+	}
+}
+
+func TestObjectObservables(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  string
+		want []observableValue
+	}{
+		{
+			"indicator",
+			`{"type":"indicator","pattern_type":"stix","pattern":"[ipv4-addr:value ISSUBSET '198.51.100.7/24'] OR [file:hashes.'SHA-256' = 'ABCD'] OR [domain-name:value IN ('A.com', 'a.com.')] OR [process:name = 'x']"}`,
+			[]observableValue{{"ipv4-addr", "198.51.100.0/24"}, {"file", "abcd"}, {"domain-name", "a.com"}},
+		},
+		{
+			"indicator in another language",
+			`{"type":"indicator","pattern_type":"snort","pattern":"alert ip 198.51.100.7 any -> any any"}`,
+			nil,
+		},
+		{
+			"indicator with an unparsable value",
+			`{"type":"indicator","pattern_type":"stix","pattern":"[url:value = 'not a url'] OR [url:value = 'https://Example.com/a']"}`,
+			[]observableValue{{"url", "https://example.com/a"}},
+		},
+		{
+			"file",
+			`{"type":"file","hashes":{"SHA-256":"BB","MD5":"AA"}}`,
+			[]observableValue{{"file", "aa"}, {"file", "bb"}},
+		},
+		{
+			"email address",
+			`{"type":"email-addr","value":"Jane@Example.com"}`,
+			[]observableValue{{"email-addr", "jane@example.com"}},
+		},
+		{
+			"unindexed type",
+			`{"type":"mutex","name":"x"}`,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		var props map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(tt.obj), &props); err != nil {
+			t.Fatal(err)
+		}
+		if got := objectObservables(stringProperty(props, "type"), props); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: objectObservables = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// File: stix_opinions.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) One Opinion per Organization
+// ──────────────────────────────────────────────────────────────────────────────
+
+// checkSingleOpinion enforces that an organization holds at most one unrevoked
+// opinion about each object; it revises that opinion with UpdateObject instead of
+// adding another. seen tracks the opinions pending in the same transaction (nil if
+// there are none).
+func (c *CTIStixContract) checkSingleOpinion(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+	props map[string]json.RawMessage,
+	seen map[string]string,
+) error {
+	if stringProperty(props, "type") != "opinion" {
+		return nil
+	}
+	author := stringProperty(props, "created_by_ref")
+	for _, ref := range stringListProperty(props, "object_refs") {
+		ids, err := c.indexedIDs(ctx, opinionOfObjectType, []string{ref, author})
+		if err != nil {
+			return err
+		}
+		for _, other := range ids {
+			if other == id {
+				continue
+			}
+			rec, err := c.getStixObject(ctx, other)
+			if err != nil {
+				return err
+			}
+			if !rec.Revoked {
+				return fmt.Errorf("%s already holds opinion %s about %s; revise it with UpdateObject", author, other, ref)
+			}
+		}
+		if seen == nil {
+			continue
+		}
+		if other, ok := seen[ref+"|"+author]; ok && other != id {
+			return fmt.Errorf("%s states opinions %s and %s about %s; only one is allowed", author, other, id, ref)
+		}
+		seen[ref+"|"+author] = id
+	}
+	return nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Consensus Confidence
+// ──────────────────────────────────────────────────────────────────────────────
+
+// defaultConfidence stands in for the creator's confidence when the object has none
+const defaultConfidence = 50
+
+// ConsensusConfidence is the network-level confidence of an object, with the inputs
+// it was computed from
+type ConsensusConfidence struct {
+	ObjectID          string         `json:"object_id"`
+	CreatorMSP        string         `json:"creator_msp"`
+	CreatorConfidence int            `json:"creator_confidence"` // the object's confidence, or 50 if it has none
+	Opinions          map[string]int `json:"opinions"`           // opinion-enum value → opinions of other orgs
+	SightingOrgs      []string       `json:"sighting_orgs"`      // other orgs that reported a sighting
+	Score             int            `json:"score"`              // 0-100
+}
+
+// consensusScore combines the creator's confidence C, the opinion scores o1…on of
+// other organizations (strongly-disagree 0, disagree 25, neutral 50, agree 75,
+// strongly-agree 100) and the number s of other organizations that sighted the
+// object, each counting as a vote of 100:
+//
+//	score = (C + o1 + … + on + 100·s) / (1 + n + s), rounded to the nearest integer
+func consensusScore(creatorConfidence int, opinionScores []int, sightingOrgs int) int {
+	sum := creatorConfidence + 100*sightingOrgs
+	for _, score := range opinionScores {
+		sum += score
+	}
+	votes := 1 + len(opinionScores) + sightingOrgs
+	return (2*sum + votes) / (2 * votes)
+}
+
+// GetConsensusConfidence scores how far the channel backs an object, usually an
+// indicator, from the creator's confidence, the opinions of other organizations and
+// their independent sightings (see consensusScore). Opinions and sightings of the
+// creating organization, revoked ones and those withheld from the caller do not count.
+func (c *CTIStixContract) GetConsensusConfidence(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+) (*ConsensusConfidence, error) {
+	rec, obj, err := c.readVisibleObject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(obj, &props); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s JSON: %v", id, err)
+	}
+	result := &ConsensusConfidence{
+		ObjectID:          id,
+		CreatorMSP:        rec.CreatorMSP,
+		CreatorConfidence: defaultConfidence,
+		Opinions:          map[string]int{},
+		SightingOrgs:      []string{},
+	}
+	if hasProperty(props, "confidence") {
+		if err := json.Unmarshal(props["confidence"], &result.CreatorConfidence); err != nil {
+			return nil, fmt.Errorf("%s has an invalid confidence: %v", id, err)
+		}
+	}
+
+	v, err := c.newObjectViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opinionIDs, err := c.indexedIDs(ctx, opinionOfObjectType, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(opinionIDs)
+	var scores []int
+	for _, opinionID := range opinionIDs {
+		opinion, err := c.getStixObject(ctx, opinionID)
+		if err != nil {
+			return nil, err
+		}
+		if opinion.Revoked || opinion.CreatorMSP == rec.CreatorMSP {
+			continue
+		}
+		opinionObj, err := v.view(opinion)
+		if err != nil {
+			return nil, err
+		}
+		if opinionObj == nil {
+			continue
+		}
+		var opinionProps map[string]json.RawMessage
+		if err := json.Unmarshal(opinionObj, &opinionProps); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s JSON: %v", opinionID, err)
+		}
+		value := stringProperty(opinionProps, "opinion")
+		score, ok := opinionScores[value]
+		if !ok {
+			continue // withheld by a granular marking
+		}
+		result.Opinions[value]++
+		scores = append(scores, score)
+	}
+
+	sightings, err := c.summarizeSightings(ctx, v, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, msp := range sightings.Orgs {
+		if msp != rec.CreatorMSP {
+			result.SightingOrgs = append(result.SightingOrgs, msp)
+		}
+	}
+
+	result.Score = consensusScore(result.CreatorConfidence, scores, len(result.SightingOrgs))
+	return result, nil
+}
@@ -0,0 +1,155 @@
+// File: stix_opinions_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// testOpinion returns an opinion about one object
+func testOpinion(id, opinion, about string) map[string]interface{} {
+	props := sdo("opinion", id)
+	props["opinion"], props["object_refs"] = opinion, []string{about}
+	return props
+}
+
+// consensus runs GetConsensusConfidence as id
+func (l *mockLedger) consensus(id *mockIdentity, objectID string) *ConsensusConfidence {
+	l.t.Helper()
+	var result *ConsensusConfidence
+	err := l.invoke(id, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		result, err = (&CTIStixContract{}).GetConsensusConfidence(ctx, objectID)
+		return err
+	})
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	return result
+}
+
+func TestConsensusScore(t *testing.T) {
+	tests := []struct {
+		creator  int
+		opinions []int
+		sighted  int
+		want     int
+	}{
+		{80, nil, 0, 80},
+		{80, []int{0}, 0, 40},
+		{80, []int{75, 100}, 0, 85},
+		{50, nil, 1, 75},
+		{0, []int{25}, 1, 42},   // 125 / 3 = 41.67
+		{0, []int{25, 0}, 0, 8}, // 25 / 3 = 8.33
+		{1, []int{0}, 0, 1},     // 1 / 2 rounds half up
+	}
+	for _, tt := range tests {
+		if got := consensusScore(tt.creator, tt.opinions, tt.sighted); got != tt.want {
+			t.Errorf("consensusScore(%d, %v, %d) = %d, want %d", tt.creator, tt.opinions, tt.sighted, got, tt.want)
+		}
+	}
+}
+
+func TestOneOpinionPerOrganization(t *testing.T) {
+	const indicator = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+	const opinionID = "opinion--c626b7b2-36b3-4626-95c4-b4c5d6e7f809"
+	l := newMockLedger(t)
+	org2 := newMockIdentity("Org2MSP", "lead", "", false)
+	l.create(newMockIdentity("Org1MSP", "analyst", "producer", false), testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45"))
+	l.create(org2, testOpinion("c626b7b2-36b3-4626-95c4-b4c5d6e7f809", "agree", indicator))
+
+	create := func(props map[string]interface{}) error {
+		return l.invoke(org2, func(ctx contractapi.TransactionContextInterface) error {
+			return (&CTIStixContract{}).CreateObject(ctx, string(stixObject(t, props)))
+		})
+	}
+	second := testOpinion("d737c8c3-47c4-4737-a6d5-c5d6e7f8091a", "disagree", indicator)
+	if err := create(second); err == nil || !strings.Contains(err.Error(), "already holds opinion "+opinionID) {
+		t.Errorf("second opinion: error %v", err)
+	}
+
+	// The opinion is revised by versioning it
+	revised := testOpinion("c626b7b2-36b3-4626-95c4-b4c5d6e7f809", "strongly-disagree", indicator)
+	revised["modified"] = "2025-05-02T08:00:00.000Z"
+	if err := l.update(org2, revised); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.consensus(org2, indicator); got.Opinions["strongly-disagree"] != 1 || len(got.Opinions) != 1 {
+		t.Errorf("opinions after revision %v", got.Opinions)
+	}
+
+	// Two opinions in one bundle are refused as well
+	bundle := stixObject(t, map[string]interface{}{
+		"type": "bundle",
+		"id":   "bundle--5d0092c5-5f74-4287-9642-33f4c354e56d",
+		"objects": []json.RawMessage{
+			stixObject(t, testOpinion("0a6afbf6-7af7-4a6a-99a8-f8091a2b3c4d", "agree", "malware--31b940d4-6f7f-459a-80ea-9c1f17b5891b")),
+			stixObject(t, testOpinion("f959eae5-69e6-4959-88f7-e7f8091a2b3c", "neutral", "malware--31b940d4-6f7f-459a-80ea-9c1f17b5891b")),
+		},
+	})
+	err := l.invoke(org2, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := (&CTIStixContract{}).CreateBundle(ctx, string(bundle))
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "only one is allowed") {
+		t.Errorf("bundle with two opinions: error %v", err)
+	}
+
+	// Once revoked, the opinion makes way for a new one
+	err = l.invoke(org2, func(ctx contractapi.TransactionContextInterface) error {
+		return (&CTIStixContract{}).RevokeObject(ctx, opinionID, "superseded")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := create(second); err != nil {
+		t.Errorf("opinion after revocation: %v", err)
+	}
+}
+
+func TestGetConsensusConfidence(t *testing.T) {
+	const indicator = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+	l := newMockLedger(t)
+	org1 := newMockIdentity("Org1MSP", "analyst", "producer", false)
+	props := testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45")
+	props["confidence"] = 60
+	l.create(org1, props)
+
+	// The creator's own opinion and sighting do not count
+	l.create(org1, testOpinion("c626b7b2-36b3-4626-95c4-b4c5d6e7f809", "strongly-agree", indicator))
+	if err := l.reportSighting(org1, indicator, 5, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	l.create(newMockIdentity("Org2MSP", "analyst", "producer", false), testOpinion("d737c8c3-47c4-4737-a6d5-c5d6e7f8091a", "agree", indicator))
+	if err := l.reportSighting(newMockIdentity("Org2MSP", "sensor", "producer", false), indicator, 1, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.reportSighting(newMockIdentity("Org3MSP", "sensor", "producer", false), indicator, 1, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	// An opinion withheld from the caller does not count for it
+	red := testOpinion("e848d9d4-58d5-4848-b7e6-d6e7f8091a2b", "strongly-disagree", indicator)
+	red["object_marking_refs"] = []string{tlpRed}
+	l.create(newMockIdentity("Org4MSP", "analyst", "producer", false), red)
+
+	got := l.consensus(org1, indicator)
+	// (60 + 75 + 100·2) / 4 = 83.75
+	if got.CreatorMSP != "Org1MSP" || got.CreatorConfidence != 60 || len(got.Opinions) != 1 || got.Opinions["agree"] != 1 ||
+		strings.Join(got.SightingOrgs, ",") != "Org2MSP,Org3MSP" || got.Score != 84 {
+		t.Errorf("Org1MSP consensus %+v", got)
+	}
+	// (60 + 75 + 0 + 100·2) / 5 = 67
+	if got := l.consensus(newMockIdentity("Org4MSP", "reader", "", false), indicator); got.Score != 67 || got.Opinions["strongly-disagree"] != 1 {
+		t.Errorf("Org4MSP consensus %+v", got)
+	}
+
+	// Without a confidence the creator counts as 50
+	l.create(org1, testIndicator("0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21", "198.51.100.7"))
+	if got := l.consensus(org1, "indicator--0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21"); got.CreatorConfidence != defaultConfidence || got.Score != defaultConfidence {
+		t.Errorf("consensus without confidence %+v", got)
+	}
+}
@@ -0,0 +1,753 @@
+// File: stix_pattern.go
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Pattern AST
+// ──────────────────────────────────────────────────────────────────────────────
+
+// observationExpr is a node of the observation-expression layer of a STIX pattern
+type observationExpr interface{ isObservation() }
+
+// comparisonExpr is a node of the comparison-expression layer, inside [ … ]
+type comparisonExpr interface{ isComparison() }
+
+// obsBinary joins two observation expressions with AND, OR or FOLLOWEDBY
+type obsBinary struct {
+	Op          string
+	Left, Right observationExpr
+}
+
+// obsBracket is a single observation: [ comparison expression ]
+type obsBracket struct {
+	Comparison comparisonExpr
+}
+
+// obsQualified applies a WITHIN, REPEATS or START/STOP qualifier to an observation
+type obsQualified struct {
+	Expr      observationExpr
+	Qualifier string   // "WITHIN", "REPEATS" or "START"
+	Args      []string // seconds; times; start and stop timestamps
+}
+
+// cmpBinary joins two comparison expressions with AND or OR
+type cmpBinary struct {
+	Op          string
+	Left, Right comparisonExpr
+}
+
+// cmpTest compares an object path with a literal, e.g. ipv4-addr:value = '1.2.3.4'
+type cmpTest struct {
+	Path    objectPath
+	Negated bool
+	Op      string // "=", "!=", "<", "<=", ">", ">=", "IN", "LIKE", "MATCHES", "ISSUBSET", "ISSUPERSET"
+	Value   patternLiteral
+}
+
+// cmpExists is EXISTS object-path
+type cmpExists struct {
+	Path objectPath
+}
+
+func (obsBinary) isObservation()    {}
+func (obsBracket) isObservation()   {}
+func (obsQualified) isObservation() {}
+func (cmpBinary) isComparison()     {}
+func (cmpTest) isComparison()       {}
+func (cmpExists) isComparison()     {}
+
+// objectPath is <object type>:<property>(.<property> | [index])*
+type objectPath struct {
+	ObjectType string
+	Steps      []string // e.g. ["hashes", "'SHA-256'"] or ["values", "[*]", "name"]
+}
+
+// property renders the path after the colon, e.g. "hashes.'SHA-256'"
+func (p objectPath) property() string {
+	var sb strings.Builder
+	for i, step := range p.Steps {
+		if i > 0 && !strings.HasPrefix(step, "[") {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(step)
+	}
+	return sb.String()
+}
+
+// patternLiteral is a constant of a STIX pattern
+type patternLiteral struct {
+	Kind  string           // "string", "int", "float", "bool", "timestamp", "hex", "binary", "set"
+	Value string           // the decoded value, for every kind except "set"
+	Set   []patternLiteral // members of a set literal
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Lexer
+// ──────────────────────────────────────────────────────────────────────────────
+
+type patternToken struct {
+	kind  string // "word", "string", "number", "timestamp", "hex", "binary", or the punctuation itself
+	value string
+	pos   int
+}
+
+// patternKeywords are the reserved words of the STIX patterning grammar
+var patternKeywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "FOLLOWEDBY": true, "LIKE": true, "MATCHES": true,
+	"ISSUBSET": true, "ISSUPERSET": true, "IN": true, "EXISTS": true, "WITHIN": true,
+	"SECONDS": true, "REPEATS": true, "TIMES": true, "START": true, "STOP": true,
+}
+
+// lexPattern splits a STIX pattern into tokens
+func lexPattern(pattern string) ([]patternToken, error) {
+	var tokens []patternToken
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case strings.ContainsRune("[]():,.*", r):
+			tokens = append(tokens, patternToken{kind: string(r), value: string(r), pos: i})
+			i++
+
+		case strings.ContainsRune("=!<>", r):
+			op, width := string(r), 1
+			if i+1 < len(runes) && (runes[i+1] == '=' || (r == '<' && runes[i+1] == '>')) {
+				op, width = op+string(runes[i+1]), 2
+			}
+			switch op {
+			case "==":
+				op = "="
+			case "<>":
+				op = "!="
+			case "!":
+				return nil, fmt.Errorf("unexpected '!' at position %d", i)
+			}
+			tokens = append(tokens, patternToken{kind: "op", value: op, pos: i})
+			i += width
+
+		case r == '\'':
+			value, next, err := lexQuoted(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, patternToken{kind: "string", value: value, pos: i})
+			i = next
+
+		case (r == 't' || r == 'h' || r == 'b') && i+1 < len(runes) && runes[i+1] == '\'':
+			value, next, err := lexQuoted(runes, i+1)
+			if err != nil {
+				return nil, err
+			}
+			kind := map[rune]string{'t': "timestamp", 'h': "hex", 'b': "binary"}[r]
+			tokens = append(tokens, patternToken{kind: kind, value: value, pos: i})
+			i = next
+
+		case r == '-' || r == '+' || unicode.IsDigit(r):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			if _, err := strconv.ParseFloat(text, 64); err != nil {
+				return nil, fmt.Errorf("invalid number '%s' at position %d", text, start)
+			}
+			tokens = append(tokens, patternToken{kind: "number", value: text, pos: start})
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '-') {
+				i++
+			}
+			tokens = append(tokens, patternToken{kind: "word", value: string(runes[start:i]), pos: start})
+
+		default:
+			return nil, fmt.Errorf("unexpected character '%c' at position %d", r, i)
+		}
+	}
+	return tokens, nil
+}
+
+// lexQuoted reads a single-quoted string whose opening quote is at runes[start],
+// handling the \' and \\ escapes, and returns its value and the index after the closing quote
+func lexQuoted(runes []rune, start int) (string, int, error) {
+	var sb strings.Builder
+	for i := start + 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			if i+1 >= len(runes) || (runes[i+1] != '\'' && runes[i+1] != '\\') {
+				return "", 0, fmt.Errorf("invalid escape in string at position %d", i)
+			}
+			sb.WriteRune(runes[i+1])
+			i++
+		case '\'':
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteRune(runes[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string starting at position %d", start)
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 3) Parser
+// ──────────────────────────────────────────────────────────────────────────────
+
+type patternParser struct {
+	tokens []patternToken
+	pos    int
+}
+
+// parseStixPattern parses a STIX 2.1 pattern into its observation-expression tree
+func parseStixPattern(pattern string) (observationExpr, error) {
+	tokens, err := lexPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("pattern is empty")
+	}
+	p := &patternParser{tokens: tokens}
+	expr, err := p.observationExpressions()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.errorf("unexpected '%s'", p.tokens[p.pos].value)
+	}
+	return expr, nil
+}
+
+func (p *patternParser) errorf(format string, args ...interface{}) error {
+	pos := -1
+	if p.pos < len(p.tokens) {
+		pos = p.tokens[p.pos].pos
+	}
+	msg := fmt.Sprintf(format, args...)
+	if pos < 0 {
+		return fmt.Errorf("%s at end of pattern", msg)
+	}
+	return fmt.Errorf("%s at position %d", msg, pos)
+}
+
+// peek reports whether the next token has the given kind (or keyword value)
+func (p *patternParser) peek(kindOrKeyword string) bool {
+	if p.pos >= len(p.tokens) {
+		return false
+	}
+	t := p.tokens[p.pos]
+	if t.kind == "word" && kindOrKeyword != "word" {
+		return t.value == kindOrKeyword
+	}
+	return t.kind == kindOrKeyword
+}
+
+// expect consumes the next token if it matches, or fails
+func (p *patternParser) expect(kindOrKeyword string) (patternToken, error) {
+	if !p.peek(kindOrKeyword) {
+		if p.pos >= len(p.tokens) {
+			return patternToken{}, p.errorf("expected '%s'", kindOrKeyword)
+		}
+		return patternToken{}, p.errorf("expected '%s', got '%s'", kindOrKeyword, p.tokens[p.pos].value)
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+// observationExpressions := obsOr (FOLLOWEDBY obsOr)*
+func (p *patternParser) observationExpressions() (observationExpr, error) {
+	return p.observationBinary("FOLLOWEDBY", p.observationOr)
+}
+
+// observationOr := obsAnd (OR obsAnd)*
+func (p *patternParser) observationOr() (observationExpr, error) {
+	return p.observationBinary("OR", p.observationAnd)
+}
+
+// observationAnd := obsQualified (AND obsQualified)*
+func (p *patternParser) observationAnd() (observationExpr, error) {
+	return p.observationBinary("AND", p.observationQualified)
+}
+
+func (p *patternParser) observationBinary(op string, next func() (observationExpr, error)) (observationExpr, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek(op) {
+		p.pos++
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = obsBinary{Op: op, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// observationQualified := observation (WITHIN n SECONDS | REPEATS n TIMES | START t STOP t)*
+func (p *patternParser) observationQualified() (observationExpr, error) {
+	expr, err := p.observation()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.peek("WITHIN"):
+			p.pos++
+			n, err := p.expect("number")
+			if err != nil {
+				return nil, err
+			}
+			if v, _ := strconv.ParseFloat(n.value, 64); v <= 0 {
+				return nil, p.errorf("WITHIN needs a positive number of seconds")
+			}
+			if _, err := p.expect("SECONDS"); err != nil {
+				return nil, err
+			}
+			expr = obsQualified{Expr: expr, Qualifier: "WITHIN", Args: []string{n.value}}
+
+		case p.peek("REPEATS"):
+			p.pos++
+			n, err := p.expect("number")
+			if err != nil {
+				return nil, err
+			}
+			if v, err := strconv.Atoi(n.value); err != nil || v <= 0 {
+				return nil, p.errorf("REPEATS needs a positive integer")
+			}
+			if _, err := p.expect("TIMES"); err != nil {
+				return nil, err
+			}
+			expr = obsQualified{Expr: expr, Qualifier: "REPEATS", Args: []string{n.value}}
+
+		case p.peek("START"):
+			p.pos++
+			start, err := p.expect("timestamp")
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect("STOP"); err != nil {
+				return nil, err
+			}
+			stop, err := p.expect("timestamp")
+			if err != nil {
+				return nil, err
+			}
+			t1, err1 := parseStixTimestamp(start.value)
+			t2, err2 := parseStixTimestamp(stop.value)
+			if err1 != nil || err2 != nil {
+				return nil, p.errorf("START and STOP need valid timestamps")
+			}
+			if !t2.After(t1) {
+				return nil, p.errorf("STOP must be later than START")
+			}
+			expr = obsQualified{Expr: expr, Qualifier: "START", Args: []string{start.value, stop.value}}
+
+		default:
+			return expr, nil
+		}
+	}
+}
+
+// observation := '[' comparisonOr ']' | '(' observationExpressions ')'
+func (p *patternParser) observation() (observationExpr, error) {
+	switch {
+	case p.peek("["):
+		p.pos++
+		cmp, err := p.comparisonOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		if err := checkSameObjectType(cmp); err != nil {
+			return nil, err
+		}
+		return obsBracket{Comparison: cmp}, nil
+
+	case p.peek("("):
+		p.pos++
+		expr, err := p.observationExpressions()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+	return nil, p.errorf("expected '[' or '('")
+}
+
+// comparisonOr := comparisonAnd (OR comparisonAnd)*
+func (p *patternParser) comparisonOr() (comparisonExpr, error) {
+	return p.comparisonBinary("OR", p.comparisonAnd)
+}
+
+// comparisonAnd := propTest (AND propTest)*
+func (p *patternParser) comparisonAnd() (comparisonExpr, error) {
+	return p.comparisonBinary("AND", p.propTest)
+}
+
+func (p *patternParser) comparisonBinary(op string, next func() (comparisonExpr, error)) (comparisonExpr, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek(op) {
+		p.pos++
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = cmpBinary{Op: op, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// propTest := '(' comparisonOr ')' | EXISTS path | path NOT? operator literal
+func (p *patternParser) propTest() (comparisonExpr, error) {
+	if p.peek("(") {
+		p.pos++
+		cmp, err := p.comparisonOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return cmp, nil
+	}
+	if p.peek("EXISTS") {
+		p.pos++
+		path, err := p.objectPath()
+		if err != nil {
+			return nil, err
+		}
+		return cmpExists{Path: path}, nil
+	}
+
+	path, err := p.objectPath()
+	if err != nil {
+		return nil, err
+	}
+	test := cmpTest{Path: path}
+	if p.peek("NOT") {
+		p.pos++
+		test.Negated = true
+	}
+	if p.pos >= len(p.tokens) {
+		return nil, p.errorf("expected a comparison operator")
+	}
+
+	t := p.tokens[p.pos]
+	switch {
+	case t.kind == "op":
+		p.pos++
+		test.Op = t.value
+		if test.Value, err = p.literal(); err != nil {
+			return nil, err
+		}
+		if test.Value.Kind == "set" {
+			return nil, p.errorf("'%s' cannot compare with a set", test.Op)
+		}
+		if test.Op != "=" && test.Op != "!=" && test.Value.Kind == "bool" {
+			return nil, p.errorf("'%s' cannot compare with a boolean", test.Op)
+		}
+
+	case t.kind == "word" && t.value == "IN":
+		p.pos++
+		test.Op = "IN"
+		if test.Value, err = p.setLiteral(); err != nil {
+			return nil, err
+		}
+
+	case t.kind == "word" && (t.value == "LIKE" || t.value == "MATCHES" || t.value == "ISSUBSET" || t.value == "ISSUPERSET"):
+		p.pos++
+		test.Op = t.value
+		s, err := p.expect("string")
+		if err != nil {
+			return nil, err
+		}
+		test.Value = patternLiteral{Kind: "string", Value: s.value}
+		if (t.value == "ISSUBSET" || t.value == "ISSUPERSET") && !isIPOrCIDR(s.value) {
+			return nil, p.errorf("%s needs an IP address or CIDR, got '%s'", t.value, s.value)
+		}
+
+	default:
+		return nil, p.errorf("expected a comparison operator, got '%s'", t.value)
+	}
+	return test, nil
+}
+
+// objectPath := object-type ':' property ('.' property | '.' 'quoted' | '[' (int | '*') ']')*
+func (p *patternParser) objectPath() (objectPath, error) {
+	typ, err := p.expect("word")
+	if err != nil {
+		return objectPath{}, err
+	}
+	if patternKeywords[typ.value] || !typeNamePattern.MatchString(typ.value) {
+		return objectPath{}, p.errorf("'%s' is not a valid object type", typ.value)
+	}
+	if _, err := p.expect(":"); err != nil {
+		return objectPath{}, err
+	}
+
+	path := objectPath{ObjectType: typ.value}
+	first, err := p.expect("word")
+	if err != nil {
+		return objectPath{}, err
+	}
+	path.Steps = append(path.Steps, first.value)
+
+	for {
+		switch {
+		case p.peek("."):
+			p.pos++
+			if p.peek("string") {
+				path.Steps = append(path.Steps, "'"+p.tokens[p.pos].value+"'")
+				p.pos++
+				continue
+			}
+			step, err := p.expect("word")
+			if err != nil {
+				return objectPath{}, err
+			}
+			path.Steps = append(path.Steps, step.value)
+
+		case p.peek("["):
+			p.pos++
+			if p.peek("*") {
+				p.pos++
+				path.Steps = append(path.Steps, "[*]")
+			} else {
+				n, err := p.expect("number")
+				if err != nil {
+					return objectPath{}, err
+				}
+				if v, err := strconv.Atoi(n.value); err != nil || v < 0 {
+					return objectPath{}, p.errorf("list index must be a non-negative integer or *")
+				}
+				path.Steps = append(path.Steps, "["+n.value+"]")
+			}
+			if _, err := p.expect("]"); err != nil {
+				return objectPath{}, err
+			}
+
+		default:
+			return path, nil
+		}
+	}
+}
+
+// literal := string | number | true | false | t'…' | h'…' | b'…'
+func (p *patternParser) literal() (patternLiteral, error) {
+	if p.pos >= len(p.tokens) {
+		return patternLiteral{}, p.errorf("expected a literal")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch t.kind {
+	case "string":
+		return patternLiteral{Kind: "string", Value: t.value}, nil
+	case "number":
+		if strings.Contains(t.value, ".") {
+			return patternLiteral{Kind: "float", Value: t.value}, nil
+		}
+		return patternLiteral{Kind: "int", Value: t.value}, nil
+	case "timestamp":
+		if !timestampPattern.MatchString(t.value) {
+			return patternLiteral{}, fmt.Errorf("invalid timestamp literal '%s' at position %d", t.value, t.pos)
+		}
+		return patternLiteral{Kind: "timestamp", Value: t.value}, nil
+	case "hex":
+		if _, err := hex.DecodeString(t.value); err != nil {
+			return patternLiteral{}, fmt.Errorf("invalid hex literal at position %d", t.pos)
+		}
+		return patternLiteral{Kind: "hex", Value: strings.ToLower(t.value)}, nil
+	case "binary":
+		if _, err := base64.StdEncoding.DecodeString(t.value); err != nil {
+			return patternLiteral{}, fmt.Errorf("invalid binary literal at position %d", t.pos)
+		}
+		return patternLiteral{Kind: "binary", Value: t.value}, nil
+	case "word":
+		if t.value == "true" || t.value == "false" {
+			return patternLiteral{Kind: "bool", Value: t.value}, nil
+		}
+	case "(":
+		p.pos--
+		return p.setLiteral()
+	}
+	return patternLiteral{}, fmt.Errorf("expected a literal, got '%s' at position %d", t.value, t.pos)
+}
+
+// setLiteral := '(' ')' | '(' literal (',' literal)* ')'
+func (p *patternParser) setLiteral() (patternLiteral, error) {
+	if _, err := p.expect("("); err != nil {
+		return patternLiteral{}, err
+	}
+	set := patternLiteral{Kind: "set"}
+	for !p.peek(")") {
+		if len(set.Set) > 0 {
+			if _, err := p.expect(","); err != nil {
+				return patternLiteral{}, err
+			}
+		}
+		member, err := p.literal()
+		if err != nil {
+			return patternLiteral{}, err
+		}
+		if member.Kind == "set" {
+			return patternLiteral{}, p.errorf("sets cannot be nested")
+		}
+		set.Set = append(set.Set, member)
+	}
+	p.pos++
+	return set, nil
+}
+
+// checkSameObjectType enforces that comparisons joined by AND inside one observation
+// all refer to the same object type
+func checkSameObjectType(cmp comparisonExpr) error {
+	_, err := andObjectType(cmp)
+	return err
+}
+
+// andObjectType returns the object type shared by an AND-joined comparison, or "" if
+// the expression contains an OR (each side of which is checked on its own)
+func andObjectType(cmp comparisonExpr) (string, error) {
+	switch c := cmp.(type) {
+	case cmpTest:
+		return c.Path.ObjectType, nil
+	case cmpExists:
+		return c.Path.ObjectType, nil
+	case cmpBinary:
+		left, err := andObjectType(c.Left)
+		if err != nil {
+			return "", err
+		}
+		right, err := andObjectType(c.Right)
+		if err != nil {
+			return "", err
+		}
+		if c.Op == "OR" {
+			return "", nil
+		}
+		if left != "" && right != "" && left != right {
+			return "", fmt.Errorf("comparisons joined by AND must use the same object type, got %s and %s", left, right)
+		}
+		if left == "" {
+			return right, nil
+		}
+		return left, nil
+	}
+	return "", nil
+}
+
+// isIPOrCIDR reports whether value is an IPv4/IPv6 address or CIDR block
+func isIPOrCIDR(value string) bool {
+	if net.ParseIP(value) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(value)
+	return err == nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 4) Validation and Observable Extraction
+// ──────────────────────────────────────────────────────────────────────────────
+
+// PatternObservable is an observable value that a pattern tests for equality (or
+// subnet membership), e.g. {ipv4-addr, value, 203.0.113.45}
+type PatternObservable struct {
+	ObjectType string `json:"object_type"` // e.g. "ipv4-addr"
+	Property   string `json:"property"`    // e.g. "value" or "hashes.'SHA-256'"
+	Value      string `json:"value"`
+}
+
+// extractObservables walks a parsed pattern and returns the observable values it
+// references positively: “=” and IN comparisons on strings, and ISSUBSET on IPs.
+// Negated comparisons are skipped, since they do not identify an observable.
+func extractObservables(expr observationExpr) []PatternObservable {
+	var out []PatternObservable
+	seen := map[PatternObservable]bool{}
+	add := func(o PatternObservable) {
+		if !seen[o] {
+			seen[o] = true
+			out = append(out, o)
+		}
+	}
+
+	var walkCmp func(comparisonExpr)
+	walkCmp = func(cmp comparisonExpr) {
+		switch c := cmp.(type) {
+		case cmpBinary:
+			walkCmp(c.Left)
+			walkCmp(c.Right)
+		case cmpTest:
+			if c.Negated {
+				return
+			}
+			base := PatternObservable{ObjectType: c.Path.ObjectType, Property: c.Path.property()}
+			switch c.Op {
+			case "=", "ISSUBSET":
+				if c.Value.Kind == "string" || c.Value.Kind == "hex" {
+					base.Value = c.Value.Value
+					add(base)
+				}
+			case "IN":
+				for _, member := range c.Value.Set {
+					if member.Kind == "string" || member.Kind == "hex" {
+						o := base
+						o.Value = member.Value
+						add(o)
+					}
+				}
+			}
+		}
+	}
+
+	var walkObs func(observationExpr)
+	walkObs = func(obs observationExpr) {
+		switch o := obs.(type) {
+		case obsBinary:
+			walkObs(o.Left)
+			walkObs(o.Right)
+		case obsQualified:
+			walkObs(o.Expr)
+		case obsBracket:
+			walkCmp(o.Comparison)
+		}
+	}
+	walkObs(expr)
+	return out
+}
+
+// validateIndicatorPattern parses the pattern of an indicator whose pattern_type is
+// "stix" and returns the observables it references; other pattern languages are
+// stored as given and yield no observables
+func validateIndicatorPattern(patternType, pattern string) ([]PatternObservable, error) {
+	if patternType != "stix" {
+		return nil, nil
+	}
+	expr, err := parseStixPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return extractObservables(expr), nil
+}
@@ -0,0 +1,147 @@
+// File: stix_pattern_test.go
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLexPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		kinds   []string
+		values  []string
+	}{
+		{
+			"[ipv4-addr:value = '1.2.3.4']",
+			[]string{"[", "word", ":", "word", "op", "string", "]"},
+			[]string{"[", "ipv4-addr", ":", "value", "=", "1.2.3.4", "]"},
+		},
+		{
+			"[x:y == 1 AND x:z <> -2.5]",
+			[]string{"[", "word", ":", "word", "op", "number", "word", "word", ":", "word", "op", "number", "]"},
+			[]string{"[", "x", ":", "y", "=", "1", "AND", "x", ":", "z", "!=", "-2.5", "]"},
+		},
+		{
+			`[file:name = 'it\'s \\ here'] START t'2020-01-01T00:00:00Z' STOP t'2021-01-01T00:00:00Z'`,
+			[]string{"[", "word", ":", "word", "op", "string", "]", "word", "timestamp", "word", "timestamp"},
+			[]string{"[", "file", ":", "name", "=", `it's \ here`, "]", "START", "2020-01-01T00:00:00Z", "STOP", "2021-01-01T00:00:00Z"},
+		},
+		{
+			"[artifact:payload_bin = b'AAEC' OR file:hashes.MD5 = h'00ff']",
+			[]string{"[", "word", ":", "word", "op", "binary", "word", "word", ":", "word", ".", "word", "op", "hex", "]"},
+			[]string{"[", "artifact", ":", "payload_bin", "=", "AAEC", "OR", "file", ":", "hashes", ".", "MD5", "=", "00ff", "]"},
+		},
+	}
+	for _, tt := range tests {
+		tokens, err := lexPattern(tt.pattern)
+		if err != nil {
+			t.Errorf("lexPattern(%q): %v", tt.pattern, err)
+			continue
+		}
+		var kinds, values []string
+		for _, tok := range tokens {
+			kinds = append(kinds, tok.kind)
+			values = append(values, tok.value)
+		}
+		if !reflect.DeepEqual(kinds, tt.kinds) || !reflect.DeepEqual(values, tt.values) {
+			t.Errorf("lexPattern(%q)\n kinds %v\n want  %v\n values %q\n want   %q", tt.pattern, kinds, tt.kinds, values, tt.values)
+		}
+	}
+}
+
+func TestParseStixPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		valid   bool
+	}{
+		{"[ipv4-addr:value = '203.0.113.45']", true},
+		{"[ipv4-addr:value = '203.0.113.45' OR ipv4-addr:value = '198.51.100.7']", true},
+		{"[file:hashes.'SHA-256' = 'abc' OR file:name = 'x.exe'] AND [domain-name:value IN ('a.com', 'b.com')] WITHIN 300 SECONDS", true},
+		{"([ipv4-addr:value ISSUBSET '10.0.0.0/8'] FOLLOWEDBY [url:value LIKE 'http%']) REPEATS 3 TIMES", true},
+		{"[network-traffic:dst_port >= 443 AND network-traffic:protocols[*] = 'tcp'] START t'2020-01-01T00:00:00Z' STOP t'2021-01-01T00:00:00Z'", true},
+		{"[windows-registry-key:values[0].name MATCHES '^Run']", true},
+		{"[file:name NOT = 'a.exe' AND EXISTS file:size]", true},
+		{"[x-foo:bar = 'it\\'s']", true},
+
+		{"", false},
+		{"   ", false},
+		{"ipv4-addr:value = '1.2.3.4'", false},                 // no brackets
+		{"[ipv4-addr:value = ]", false},                        // missing literal
+		{"[ipv4-addr:value = '1.2.3.4'", false},                // unclosed bracket
+		{"[ipv4-addr:value = '1.2.3.4]", false},                // unterminated string
+		{"[ipv4-addr:value ! '1.2.3.4']", false},               // bare '!'
+		{"[file:name = 'a' AND ipv4-addr:value = 'b']", false}, // AND across object types
+		{"[ipv4-addr:value ISSUBSET 'foo']", false},            // ISSUBSET needs an IP or CIDR
+		{"[a:b = '1'] WITHIN -1 SECONDS", false},               // negative WITHIN
+		{"[a:b = '1'] REPEATS 0 TIMES", false},                 // REPEATS below one
+		{"[a:b = '1'] AND", false},                             // dangling operator
+		{"[a:b = '1'] [c:d = '2']", false},                     // missing operator
+		{"[a:b = 'x' OR]", false},
+		{"[a:b IN ()]", false},
+		{"[a:b = 'x'] #", false},
+
+		// STOP before START
+		{"[a:b = '1'] START t'2021-01-01T00:00:00Z' STOP t'2020-01-01T00:00:00Z'", false},
+	}
+	for _, tt := range tests {
+		_, err := parseStixPattern(tt.pattern)
+		if (err == nil) != tt.valid {
+			t.Errorf("parseStixPattern(%q) error = %v, want valid %v", tt.pattern, err, tt.valid)
+		}
+	}
+}
+
+func TestExtractObservables(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []PatternObservable
+	}{
+		{
+			"[ipv4-addr:value = '203.0.113.45']",
+			[]PatternObservable{{"ipv4-addr", "value", "203.0.113.45"}},
+		},
+		{
+			"[domain-name:value IN ('a.com', 'b.com')] AND [file:hashes.'SHA-256' = 'abcd'] WITHIN 60 SECONDS",
+			[]PatternObservable{{"domain-name", "value", "a.com"}, {"domain-name", "value", "b.com"}, {"file", "hashes.'SHA-256'", "abcd"}},
+		},
+		{
+			"[ipv4-addr:value ISSUBSET '198.51.100.0/24'] FOLLOWEDBY [ipv4-addr:value = '198.51.100.0/24']",
+			[]PatternObservable{{"ipv4-addr", "value", "198.51.100.0/24"}},
+		},
+		{
+			"[file:name NOT = 'a.exe' OR url:value LIKE 'http%' OR network-traffic:dst_port = 443]",
+			nil, // negations, LIKE and numbers identify no observable
+		},
+	}
+	for _, tt := range tests {
+		expr, err := parseStixPattern(tt.pattern)
+		if err != nil {
+			t.Errorf("parseStixPattern(%q): %v", tt.pattern, err)
+			continue
+		}
+		if got := extractObservables(expr); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("extractObservables(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestValidateIndicatorPattern(t *testing.T) {
+	tests := []struct {
+		patternType, pattern string
+		valid                bool
+		observables          int
+	}{
+		{"stix", "[url:value = 'http://example.com/x']", true, 1},
+		{"stix", "[url:value = ", false, 0},
+		{"snort", "alert tcp any any -> any 80", true, 0}, // other languages are stored as given
+		{"sigma", "[not a stix pattern", true, 0},
+	}
+	for _, tt := range tests {
+		obs, err := validateIndicatorPattern(tt.patternType, tt.pattern)
+		if (err == nil) != tt.valid || len(obs) != tt.observables {
+			t.Errorf("validateIndicatorPattern(%q, %q) = %v, %v", tt.patternType, tt.pattern, obs, err)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+// File: stix_private.go
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Private Objects and Public Stubs
+// ──────────────────────────────────────────────────────────────────────────────
+
+// privateObjectTransientKey is the transient-map entry that carries a private STIX
+// object, so that its content never appears in the transaction proposal or the block
+const privateObjectTransientKey = "stix_object"
+
+// privateSaltTransientKey is the transient-map entry with the random salt of the
+// stub's hash; without it, the hash of a short object could be brute-forced
+const privateSaltTransientKey = "salt"
+
+// minPrivateSaltLength is the shortest salt CreatePrivateObject accepts, in bytes
+const minPrivateSaltLength = 16
+
+// privateStubObjectType is the composite-key namespace of the public stubs of private
+// objects: privateStubObjectType + [STIX ID]
+const privateStubObjectType = "privstub"
+
+// PrivateStub is the public record that a STIX object is held in a private data
+// collection. SHA256 lets collection members check the content they read against
+// what was anchored on the channel; it is salted, and the salt is only kept in the
+// collection, with the object.
+type PrivateStub struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Collection string `json:"collection"`
+	CreatorMSP string `json:"creator_msp"`
+	SHA256     string `json:"sha256"` // hex SHA-256 of the salt followed by the stored object JSON
+	TxID       string `json:"tx_id"`
+}
+
+// privateStubKey returns the world-state key of the public stub of a private object
+func (c *CTIStixContract) privateStubKey(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(privateStubObjectType, []string{id})
+	if err != nil {
+		return "", fmt.Errorf("failed to create stub key for %s: %v", id, err)
+	}
+	return key, nil
+}
+
+// getPrivateStub reads the public stub of a private object, or nil if there is none
+func (c *CTIStixContract) getPrivateStub(ctx contractapi.TransactionContextInterface, id string) (*PrivateStub, error) {
+	key, err := c.privateStubKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stub of %s: %v", id, err)
+	}
+	if bytes == nil {
+		return nil, nil
+	}
+	var stub PrivateStub
+	if err := json.Unmarshal(bytes, &stub); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal stub of %s: %v", id, err)
+	}
+	return &stub, nil
+}
+
+// getPrivateObject reads the record of a private object from a collection
+func (c *CTIStixContract) getPrivateObject(
+	ctx contractapi.TransactionContextInterface,
+	collection string,
+	id string,
+) (*ledgerRecord, error) {
+	key, err := c.stixObjectKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := ctx.GetStub().GetPrivateData(collection, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from collection %s: %v", id, collection, err)
+	}
+	if bytes == nil {
+		return nil, fmt.Errorf("asset %s does not exist in collection %s", id, collection)
+	}
+	return parseLedgerRecord(bytes)
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Private Object Methods
+// ──────────────────────────────────────────────────────────────────────────────
+
+// CreatePrivateObject stores a STIX object, passed in the transient map under
+// "stix_object", in the named private data collection. The object goes through the
+// same validation as CreateObject; references must resolve on the channel or in the
+// same collection. A public stub with the object's salted SHA-256 records that it
+// exists; the salt, at least 16 random bytes, is passed in the transient map under
+// "salt". Cyber-observables cannot be stored privately: their id is derived from
+// their value, so the public stub would disclose it.
+func (c *CTIStixContract) CreatePrivateObject(
+	ctx contractapi.TransactionContextInterface,
+	collection string,
+) error {
+	if err := c.requirePermission(ctx, actionCreate); err != nil {
+		return err
+	}
+
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("failed to read transient map: %v", err)
+	}
+	raw, ok := transient[privateObjectTransientKey]
+	if !ok || len(raw) == 0 {
+		return fmt.Errorf("the STIX object must be passed in the transient map under '%s'", privateObjectTransientKey)
+	}
+	salt := transient[privateSaltTransientKey]
+	if len(salt) < minPrivateSaltLength {
+		return fmt.Errorf("a random salt of at least %d bytes must be passed in the transient map under '%s'", minPrivateSaltLength, privateSaltTransientKey)
+	}
+
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return fmt.Errorf("failed to parse STIX object JSON: %v", err)
+	}
+	if header.Type == "bundle" {
+		return fmt.Errorf("bundles cannot be stored privately; submit their objects one by one")
+	}
+	sub, err := c.getSubmitterIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	identityID, err := c.ensureOrgIdentity(ctx, sub.MSPID)
+	if err != nil {
+		return err
+	}
+	if raw, err = stampCreatedByRef(raw, identityID); err != nil {
+		return err
+	}
+	if raw, err = withObservableID(raw); err != nil {
+		return err
+	}
+	_, props, id, err := decodeStixObject(header.Type, raw)
+	if err != nil {
+		return err
+	}
+	if isObservableObject(header.Type, props) {
+		return fmt.Errorf("cyber-observables cannot be stored privately: the id of %s is derived from its value, which the public stub would disclose", header.Type)
+	}
+	if err := c.checkExtensions(ctx, props, nil); err != nil {
+		return err
+	}
+	if err := c.checkMarkingRefs(ctx, props, nil); err != nil {
+		return err
+	}
+	if err := c.requireRevokePermission(ctx, props); err != nil {
+		return err
+	}
+
+	exists, err := c.stixObjectExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	stub, err := c.getPrivateStub(ctx, id)
+	if err != nil {
+		return err
+	}
+	if exists || stub != nil {
+		return fmt.Errorf("%s with ID %s already exists", header.Type, id)
+	}
+
+	// References may point at public objects or at objects of the same collection
+	v, err := c.orgViewer(ctx, sub.MSPID)
+	if err != nil {
+		return err
+	}
+	missing, err := c.checkObjectRefs(ctx, v, header.Type, props, nil, true)
+	if err != nil {
+		return err
+	}
+	for _, ref := range missing {
+		if _, err := c.getPrivateObject(ctx, collection, ref); err != nil {
+			return fmt.Errorf("referenced object %s does not exist", ref)
+		}
+	}
+
+	rec, err := newLedgerRecord(raw)
+	if err != nil {
+		return err
+	}
+	rec.CreatorMSP, rec.CreatorSubject = sub.MSPID, sub.Subject
+	rec.Salt = hex.EncodeToString(salt)
+	key, err := c.stixObjectKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	bytes, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s for storage: %v", rec.Type, err)
+	}
+	if err := ctx.GetStub().PutPrivateData(collection, key, bytes); err != nil {
+		return fmt.Errorf("failed to store %s in collection %s: %v", id, collection, err)
+	}
+
+	sum := sha256.Sum256(append(append([]byte(nil), salt...), rec.Object...))
+	stubJSON, err := json.Marshal(PrivateStub{
+		ID:         id,
+		Type:       rec.Type,
+		Collection: collection,
+		CreatorMSP: rec.CreatorMSP,
+		SHA256:     hex.EncodeToString(sum[:]),
+		TxID:       ctx.GetStub().GetTxID(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal stub of %s: %v", id, err)
+	}
+	stubKey, err := c.privateStubKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := c.putAsset(ctx, stubKey, stubJSON); err != nil {
+		return err
+	}
+
+	// The event carries no more than the public stub
+	return c.emitEvents(ctx, []ObjectEvent{{
+		Event:      createdEventName(rec.Type),
+		ID:         id,
+		Type:       rec.Type,
+		CreatorMSP: rec.CreatorMSP,
+		Restricted: true,
+		Collection: collection,
+		TxID:       ctx.GetStub().GetTxID(),
+	}})
+}
+
+// ReadPrivateObject retrieves a private STIX object from a collection. Only peers of
+// member organizations hold the data, so other organizations get an error.
+func (c *CTIStixContract) ReadPrivateObject(
+	ctx contractapi.TransactionContextInterface,
+	collection string,
+	id string,
+) (string, error) {
+	if err := c.requirePermission(ctx, actionRead); err != nil {
+		return "", err
+	}
+
+	rec, err := c.getPrivateObject(ctx, collection, id)
+	if err != nil {
+		return "", err
+	}
+	return string(rec.Object), nil
+}
+
+// GetPrivateStub returns the public stub of a private object, which every channel
+// member can read
+func (c *CTIStixContract) GetPrivateStub(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+) (*PrivateStub, error) {
+	stub, err := c.getPrivateStub(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if stub == nil {
+		return nil, fmt.Errorf("%s is not a private object", id)
+	}
+	return stub, nil
+}
+
+// QueryPrivateObjects searches the objects of a private data collection with the
+// same filter as QueryObjects. Fabric does not paginate private data queries, so
+// page_size and bookmark are ignored; it needs a CouchDB state database.
+func (c *CTIStixContract) QueryPrivateObjects(
+	ctx contractapi.TransactionContextInterface,
+	collection string,
+	filterJSON string,
+) (string, error) {
+	if err := c.requirePermission(ctx, actionRead); err != nil {
+		return "", err
+	}
+
+	var q ObjectQuery
+	if err := json.Unmarshal([]byte(filterJSON), &q); err != nil {
+		return "", fmt.Errorf("failed to parse query filter JSON: %v", err)
+	}
+	query, err := q.couchQuery()
+	if err != nil {
+		return "", err
+	}
+
+	iterator, err := ctx.GetStub().GetPrivateDataQueryResult(collection, query)
+	if err != nil {
+		if isLevelDBError(err) {
+			return "", fmt.Errorf("QueryPrivateObjects needs a CouchDB state database, this peer uses LevelDB")
+		}
+		return "", fmt.Errorf("failed to query collection %s: %v", collection, err)
+	}
+	defer iterator.Close()
+
+	objects := []json.RawMessage{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return "", fmt.Errorf("failed to iterate: %v", err)
+		}
+		rec, err := parseLedgerRecord(queryResponse.Value)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %v", queryResponse.Key, err)
+		}
+		objects = append(objects, rec.Object)
+	}
+	return resultJSON(objects)
+}
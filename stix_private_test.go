@@ -0,0 +1,131 @@
+// File: stix_private_test.go
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const privateCollection = "Org1MSPPrivateCollection"
+
+// createPrivate runs CreatePrivateObject with the given transient map
+func (l *mockLedger) createPrivate(id *mockIdentity, transient map[string][]byte) error {
+	l.stub.TransientMap = transient
+	defer func() { l.stub.TransientMap = nil }()
+	return l.invoke(id, func(ctx contractapi.TransactionContextInterface) error {
+		return (&CTIStixContract{}).CreatePrivateObject(ctx, privateCollection)
+	})
+}
+
+func TestCreatePrivateObject(t *testing.T) {
+	const id = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+	salt := []byte("0123456789abcdef")
+	indicator := stixObject(t, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45"))
+	observable := stixObject(t, map[string]interface{}{"type": "ipv4-addr", "spec_version": "2.1", "value": "203.0.113.45"})
+
+	l := newMockLedger(t)
+	producer := newMockIdentity("Org1MSP", "analyst", "producer", false)
+	steps := []struct {
+		name      string
+		transient map[string][]byte
+		errText   string
+	}{
+		{"no object", map[string][]byte{privateSaltTransientKey: salt}, "transient map under 'stix_object'"},
+		{"no salt", map[string][]byte{privateObjectTransientKey: indicator}, "at least 16 bytes"},
+		{"short salt", map[string][]byte{privateObjectTransientKey: indicator, privateSaltTransientKey: salt[:15]}, "at least 16 bytes"},
+		{"observable", map[string][]byte{privateObjectTransientKey: observable, privateSaltTransientKey: salt}, "cannot be stored privately"},
+		{"stored", map[string][]byte{privateObjectTransientKey: indicator, privateSaltTransientKey: salt}, ""},
+		{"stored twice", map[string][]byte{privateObjectTransientKey: indicator, privateSaltTransientKey: salt}, "already exists"},
+	}
+	for _, s := range steps {
+		err := l.createPrivate(producer, s.transient)
+		if s.errText == "" && err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if s.errText != "" && (err == nil || !strings.Contains(err.Error(), s.errText)) {
+			t.Errorf("%s: error %v, want %q", s.name, err, s.errText)
+		}
+	}
+	if l.exists(id) {
+		t.Errorf("%s was written to the world state", id)
+	}
+
+	// The stub anchors the salted hash of the stored object, which members can check
+	var stub *PrivateStub
+	var stored string
+	err := l.invoke(producer, func(ctx contractapi.TransactionContextInterface) error {
+		c := &CTIStixContract{}
+		var err error
+		if stub, err = c.GetPrivateStub(ctx, id); err != nil {
+			return err
+		}
+		stored, err = c.ReadPrivateObject(ctx, privateCollection, id)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(append(append([]byte(nil), salt...), stored...))
+	if stub.Collection != privateCollection || stub.CreatorMSP != "Org1MSP" || stub.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("stub %+v does not anchor %s", stub, stored)
+	}
+	for _, leak := range []string{"203.0.113.45", hex.EncodeToString(salt)} {
+		if raw, _ := json.Marshal(stub); strings.Contains(string(raw), leak) {
+			t.Errorf("stub %s discloses %s", raw, leak)
+		}
+	}
+}
+
+// A private object's id may not be reused on the channel, alone or in a bundle
+func TestPrivateIDsCannotBeReused(t *testing.T) {
+	const id = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+	public := stixObject(t, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "198.51.100.7"))
+	other := stixObject(t, testIndicator("0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21", "192.0.2.1"))
+	bundle := stixObject(t, map[string]interface{}{
+		"type":    "bundle",
+		"id":      "bundle--5d0092c5-5f74-4287-9642-33f4c354e56d",
+		"objects": []json.RawMessage{other, public},
+	})
+
+	l := newMockLedger(t)
+	producer := newMockIdentity("Org1MSP", "analyst", "producer", false)
+	err := l.createPrivate(producer, map[string][]byte{
+		privateObjectTransientKey: stixObject(t, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45")),
+		privateSaltTransientKey:   []byte("0123456789abcdef"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.events()
+
+	c := &CTIStixContract{}
+	attempts := map[string]func(ctx contractapi.TransactionContextInterface) error{
+		"CreateObject": func(ctx contractapi.TransactionContextInterface) error {
+			return c.CreateObject(ctx, string(public))
+		},
+		"CreateBundle": func(ctx contractapi.TransactionContextInterface) error {
+			_, err := c.CreateBundle(ctx, string(bundle))
+			return err
+		},
+	}
+	for name, attempt := range attempts {
+		err := l.invoke(producer, attempt)
+		if err == nil || !strings.Contains(err.Error(), id+" already exists") {
+			t.Errorf("%s reusing %s: error %v", name, id, err)
+		}
+	}
+	for _, stored := range []string{id, "indicator--0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21", "bundle--5d0092c5-5f74-4287-9642-33f4c354e56d"} {
+		if l.exists(stored) {
+			t.Errorf("%s was stored", stored)
+		}
+	}
+	if events := l.events(); len(events) != 0 {
+		t.Errorf("rejected transactions emitted %v", events)
+	}
+}
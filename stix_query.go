@@ -0,0 +1,172 @@
+// File: stix_query.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Query Filter
+// ──────────────────────────────────────────────────────────────────────────────
+
+// ObjectQuery is the structured filter accepted by QueryObjects. Every field is
+// optional; the set fields are combined with AND.
+type ObjectQuery struct {
+	Type            string   `json:"type,omitempty"`              // STIX type, e.g. "indicator"
+	Labels          []string `json:"labels,omitempty"`            // objects must carry all of these labels
+	MinConfidence   *int     `json:"min_confidence,omitempty"`    // inclusive
+	MaxConfidence   *int     `json:"max_confidence,omitempty"`    // inclusive
+	PatternContains string   `json:"pattern_contains,omitempty"`  // substring of the indicator pattern
+	ValidFromAfter  string   `json:"valid_from_after,omitempty"`  // inclusive, RFC 3339
+	ValidFromBefore string   `json:"valid_from_before,omitempty"` // exclusive, RFC 3339
+	CreatorMSP      string   `json:"creator_msp,omitempty"`       // MSP ID of the creating org
+	ExternalSource  string   `json:"external_source,omitempty"`   // external_references[].source_name
+	IncludeRevoked  bool     `json:"include_revoked,omitempty"`
+	PageSize        int32    `json:"page_size,omitempty"`
+	Bookmark        string   `json:"bookmark,omitempty"`
+}
+
+// selector translates the filter into a CouchDB Mango selector over ledger records
+func (q *ObjectQuery) selector() (map[string]interface{}, error) {
+	sel := map[string]interface{}{"docType": stixDocType}
+	if q.Type != "" {
+		sel["type"] = q.Type
+	}
+	if len(q.Labels) > 0 {
+		sel["object.labels"] = map[string]interface{}{"$all": q.Labels}
+	}
+
+	if q.MinConfidence != nil || q.MaxConfidence != nil {
+		rng := map[string]interface{}{}
+		if q.MinConfidence != nil {
+			rng["$gte"] = *q.MinConfidence
+		}
+		if q.MaxConfidence != nil {
+			rng["$lte"] = *q.MaxConfidence
+		}
+		sel["object.confidence"] = rng
+	}
+
+	if q.PatternContains != "" {
+		sel["object.pattern"] = map[string]interface{}{"$regex": regexp.QuoteMeta(q.PatternContains)}
+	}
+
+	// The range is matched against the record's valid_from, which is normalized to a
+	// fixed precision so that string comparison follows time order
+	if q.ValidFromAfter != "" || q.ValidFromBefore != "" {
+		rng := map[string]interface{}{}
+		if q.ValidFromAfter != "" {
+			t, err := parseStixTimestamp(q.ValidFromAfter)
+			if err != nil {
+				return nil, fmt.Errorf("valid_from_after must be an RFC 3339 timestamp, got '%s'", q.ValidFromAfter)
+			}
+			rng["$gte"] = sortableTimestamp(t)
+		}
+		if q.ValidFromBefore != "" {
+			t, err := parseStixTimestamp(q.ValidFromBefore)
+			if err != nil {
+				return nil, fmt.Errorf("valid_from_before must be an RFC 3339 timestamp, got '%s'", q.ValidFromBefore)
+			}
+			rng["$lt"] = sortableTimestamp(t)
+		}
+		sel["valid_from"] = rng
+	}
+
+	if q.CreatorMSP != "" {
+		sel["creator_msp"] = q.CreatorMSP
+	}
+	if q.ExternalSource != "" {
+		sel["object.external_references"] = map[string]interface{}{
+			"$elemMatch": map[string]interface{}{"source_name": q.ExternalSource},
+		}
+	}
+	if !q.IncludeRevoked {
+		sel["revoked"] = map[string]interface{}{"$ne": true}
+	}
+	return sel, nil
+}
+
+// couchQuery returns the CouchDB query for the filter. A valid_from range is sorted by
+// valid_from through indexValidFrom, so that the results come in time order.
+func (q *ObjectQuery) couchQuery() (string, error) {
+	sel, err := q.selector()
+	if err != nil {
+		return "", fmt.Errorf("invalid query filter: %v", err)
+	}
+	query := map[string]interface{}{"selector": sel}
+	if _, ok := sel["valid_from"]; ok {
+		query["sort"] = []map[string]string{{"docType": "asc"}, {"valid_from": "asc"}}
+		query["use_index"] = []string{"_design/indexValidFromDoc", "indexValidFrom"}
+	}
+	bytes, err := json.Marshal(query)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal CouchDB query: %v", err)
+	}
+	return string(bytes), nil
+}
+
+// isLevelDBError reports whether a rich-query error comes from a LevelDB state database
+func isLevelDBError(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "not supported for leveldb")
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Query Methods
+// ──────────────────────────────────────────────────────────────────────────────
+
+// QueryObjects searches stored STIX objects with a structured filter (see
+// ObjectQuery) using a CouchDB rich query. It needs CouchDB as the state database;
+// on LevelDB peers it fails with an error pointing to ListObjects.
+func (c *CTIStixContract) QueryObjects(
+	ctx contractapi.TransactionContextInterface,
+	filterJSON string,
+) (string, error) {
+	var q ObjectQuery
+	if err := json.Unmarshal([]byte(filterJSON), &q); err != nil {
+		return "", fmt.Errorf("failed to parse query filter JSON: %v", err)
+	}
+	query, err := q.couchQuery()
+	if err != nil {
+		return "", err
+	}
+	v, err := c.newObjectViewer(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	iterator, meta, err := ctx.GetStub().GetQueryResultWithPagination(query, clampPageSize(q.PageSize), q.Bookmark)
+	if err != nil {
+		if isLevelDBError(err) {
+			return "", fmt.Errorf("QueryObjects needs a CouchDB state database, this peer uses LevelDB; use ListObjects instead")
+		}
+		return "", fmt.Errorf("failed to run query: %v", err)
+	}
+	defer iterator.Close()
+
+	page := &ObjectPage{Objects: []json.RawMessage{}}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return "", fmt.Errorf("failed to iterate: %v", err)
+		}
+		rec, err := parseLedgerRecord(queryResponse.Value)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %v", queryResponse.Key, err)
+		}
+		obj, err := v.view(rec)
+		if err != nil {
+			return "", err
+		}
+		if obj != nil {
+			page.Objects = append(page.Objects, obj)
+		}
+	}
+	page.Bookmark = meta.Bookmark
+	return resultJSON(page)
+}
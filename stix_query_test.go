@@ -0,0 +1,110 @@
+// File: stix_query_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestObjectQueryCouchQuery(t *testing.T) {
+	tests := []struct {
+		filter string
+		query  string
+	}{
+		{`{}`, `{"selector":{"docType":"stix","revoked":{"$ne":true}}}`},
+		{`{"type":"indicator","include_revoked":true}`, `{"selector":{"docType":"stix","type":"indicator"}}`},
+		{`{"labels":["apt","c2"],"creator_msp":"Org1MSP","include_revoked":true}`,
+			`{"selector":{"creator_msp":"Org1MSP","docType":"stix","object.labels":{"$all":["apt","c2"]}}}`},
+		{`{"min_confidence":50,"max_confidence":90,"include_revoked":true}`,
+			`{"selector":{"docType":"stix","object.confidence":{"$gte":50,"$lte":90}}}`},
+		{`{"min_confidence":0,"include_revoked":true}`, `{"selector":{"docType":"stix","object.confidence":{"$gte":0}}}`},
+		{`{"pattern_contains":"'203.0.113.45'","include_revoked":true}`,
+			`{"selector":{"docType":"stix","object.pattern":{"$regex":"'203\\.0\\.113\\.45'"}}}`},
+		{`{"external_source":"mitre-attack","include_revoked":true}`,
+			`{"selector":{"docType":"stix","object.external_references":{"$elemMatch":{"source_name":"mitre-attack"}}}}`},
+		{`{"valid_from_after":"2025-05-01T00:00:00Z","valid_from_before":"2025-05-02T02:00:00+02:00","include_revoked":true}`,
+			`{"selector":{"docType":"stix","valid_from":{"$gte":"2025-05-01T00:00:00.000000000Z","$lt":"2025-05-02T00:00:00.000000000Z"}},` +
+				`"sort":[{"docType":"asc"},{"valid_from":"asc"}],"use_index":["_design/indexValidFromDoc","indexValidFrom"]}`},
+	}
+	for _, tt := range tests {
+		var q ObjectQuery
+		if err := json.Unmarshal([]byte(tt.filter), &q); err != nil {
+			t.Fatal(err)
+		}
+		query, err := q.couchQuery()
+		if err != nil || query != tt.query {
+			t.Errorf("couchQuery(%s) = %s, %v; want %s", tt.filter, query, err, tt.query)
+		}
+	}
+
+	for _, filter := range []string{`{"valid_from_after":"2025-05-01"}`, `{"valid_from_before":"yesterday"}`} {
+		var q ObjectQuery
+		json.Unmarshal([]byte(filter), &q)
+		if _, err := q.couchQuery(); err == nil || !strings.Contains(err.Error(), "must be an RFC 3339 timestamp") {
+			t.Errorf("couchQuery(%s): error %v", filter, err)
+		}
+	}
+}
+
+// Every shipped index covers properties of the ledger record, and the index the
+// valid_from query names is among them
+func TestCouchDBIndexes(t *testing.T) {
+	recordFields := map[string]bool{}
+	typ := reflect.TypeOf(ledgerRecord{})
+	for i := 0; i < typ.NumField(); i++ {
+		recordFields[strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]] = true
+	}
+
+	files, err := filepath.Glob(filepath.Join("META-INF", "statedb", "couchdb", "indexes", "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no index definitions found: %v", err)
+	}
+	indexes := map[string]bool{}
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var def struct {
+			Index struct {
+				Fields []string `json:"fields"`
+			} `json:"index"`
+			DDoc string `json:"ddoc"`
+			Name string `json:"name"`
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(raw, &def); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if def.Type != "json" || def.DDoc+".json" != filepath.Base(file) || len(def.Index.Fields) == 0 || def.Index.Fields[0] != "docType" {
+			t.Errorf("%s: %+v", file, def)
+		}
+		for _, field := range def.Index.Fields {
+			if !recordFields[strings.Split(field, ".")[0]] {
+				t.Errorf("%s indexes %s, which ledger records do not have", file, field)
+			}
+		}
+		indexes["_design/"+def.DDoc+"/"+def.Name] = true
+	}
+	if !indexes["_design/indexValidFromDoc/indexValidFrom"] {
+		t.Error("the index used by valid_from queries is not shipped")
+	}
+}
+
+func TestQueryObjectsOnLevelDB(t *testing.T) {
+	l := newMockLedger(t)
+	err := l.invoke(newMockIdentity("Org1MSP", "reader", "", false), func(ctx contractapi.TransactionContextInterface) error {
+		_, err := (&CTIStixContract{}).QueryObjects(ctx, `{"type":"indicator"}`)
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "needs a CouchDB state database") || !strings.Contains(err.Error(), "ListObjects") {
+		t.Errorf("error %v", err)
+	}
+}
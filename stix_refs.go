@@ -0,0 +1,425 @@
+// File: stix_refs.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Reference Format and Relationship Matrix
+// ──────────────────────────────────────────────────────────────────────────────
+
+// stixIDPattern matches a STIX identifier: <type>--<RFC 4122 UUID>
+var stixIDPattern = regexp.MustCompile(`^[a-z][a-z0-9-]*[a-z0-9]--[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// anyRelationshipTypes may connect any two objects; the first two only objects of the same type
+var anyRelationshipTypes = map[string]bool{
+	"derived-from": true,
+	"duplicate-of": true,
+	"related-to":   true,
+}
+
+// relationshipMatrix holds the STIX 2.1 recommended relationships:
+// source type → relationship_type → allowed target types
+var relationshipMatrix = map[string]map[string][]string{
+	"attack-pattern": {
+		"delivers": {"malware"},
+		"targets":  {"identity", "location", "vulnerability"},
+		"uses":     {"malware", "tool"},
+	},
+	"campaign": {
+		"attributed-to":   {"intrusion-set", "threat-actor"},
+		"compromises":     {"infrastructure"},
+		"originates-from": {"location"},
+		"targets":         {"identity", "location", "vulnerability"},
+		"uses":            {"attack-pattern", "infrastructure", "malware", "tool"},
+	},
+	"course-of-action": {
+		"investigates": {"indicator"},
+		"mitigates":    {"attack-pattern", "indicator", "malware", "tool", "vulnerability"},
+		"remediates":   {"malware", "vulnerability"},
+	},
+	"identity": {
+		"located-at": {"location"},
+	},
+	"indicator": {
+		"indicates": {"attack-pattern", "campaign", "infrastructure", "intrusion-set", "malware", "threat-actor", "tool"},
+		"based-on":  {"observed-data"},
+	},
+	"infrastructure": {
+		"communicates-with": {"infrastructure", "ipv4-addr", "ipv6-addr", "domain-name", "url"},
+		"consists-of":       {"infrastructure", "observed-data", "ipv4-addr", "ipv6-addr", "domain-name", "url", "file", "network-traffic", "x509-certificate", "software", "process"},
+		"controls":          {"infrastructure", "malware"},
+		"delivers":          {"malware"},
+		"has":               {"vulnerability"},
+		"hosts":             {"tool", "malware"},
+		"located-at":        {"location"},
+		"uses":              {"infrastructure"},
+	},
+	"intrusion-set": {
+		"attributed-to":   {"threat-actor"},
+		"compromises":     {"infrastructure"},
+		"hosts":           {"infrastructure"},
+		"owns":            {"infrastructure"},
+		"originates-from": {"location"},
+		"targets":         {"identity", "location", "vulnerability"},
+		"uses":            {"attack-pattern", "infrastructure", "malware", "tool"},
+	},
+	"malware": {
+		"authored-by":       {"threat-actor", "intrusion-set"},
+		"beacons-to":        {"infrastructure"},
+		"exfiltrates-to":    {"infrastructure"},
+		"communicates-with": {"ipv4-addr", "ipv6-addr", "domain-name", "url"},
+		"controls":          {"malware"},
+		"downloads":         {"malware", "tool", "file"},
+		"drops":             {"malware", "tool", "file"},
+		"exploits":          {"vulnerability"},
+		"originates-from":   {"location"},
+		"targets":           {"identity", "infrastructure", "location", "vulnerability"},
+		"uses":              {"attack-pattern", "infrastructure", "malware", "tool"},
+		"variant-of":        {"malware"},
+	},
+	"malware-analysis": {
+		"characterizes":       {"malware"},
+		"analysis-of":         {"malware"},
+		"static-analysis-of":  {"malware"},
+		"dynamic-analysis-of": {"malware"},
+	},
+	"threat-actor": {
+		"attributed-to": {"identity"},
+		"compromises":   {"infrastructure"},
+		"hosts":         {"infrastructure"},
+		"owns":          {"infrastructure"},
+		"impersonates":  {"identity"},
+		"located-at":    {"location"},
+		"targets":       {"identity", "location", "vulnerability"},
+		"uses":          {"attack-pattern", "infrastructure", "malware", "tool"},
+	},
+	"tool": {
+		"delivers": {"malware"},
+		"drops":    {"malware"},
+		"has":      {"vulnerability"},
+		"targets":  {"identity", "infrastructure", "location", "vulnerability"},
+		"uses":     {"infrastructure"},
+	},
+}
+
+// knownRelationshipTypes is every relationship_type that appears in the matrix
+var knownRelationshipTypes = func() map[string]bool {
+	known := map[string]bool{}
+	for _, rels := range relationshipMatrix {
+		for relType := range rels {
+			known[relType] = true
+		}
+	}
+	return known
+}()
+
+// checkRelationshipTypes checks a relationship against the recommended matrix. Custom
+// relationship types, which STIX allows, are accepted between any objects.
+func checkRelationshipTypes(relType, sourceRef, targetRef string) error {
+	sourceType, err := stixTypeOf(sourceRef)
+	if err != nil {
+		return err
+	}
+	targetType, err := stixTypeOf(targetRef)
+	if err != nil {
+		return err
+	}
+
+	if anyRelationshipTypes[relType] {
+		if relType != "related-to" && sourceType != targetType {
+			return fmt.Errorf("'%s' must connect objects of the same type, got %s and %s", relType, sourceType, targetType)
+		}
+		return nil
+	}
+	if !knownRelationshipTypes[relType] {
+		return nil
+	}
+
+	targets, ok := relationshipMatrix[sourceType][relType]
+	if !ok {
+		return fmt.Errorf("'%s' is not a recommended relationship from %s", relType, sourceType)
+	}
+	for _, t := range targets {
+		if t == targetType {
+			return nil
+		}
+	}
+	return fmt.Errorf("'%s' from %s may not target %s", relType, sourceType, targetType)
+}
+
+// relationshipRefs returns the object IDs that a relationship or sighting points at
+func relationshipRefs(props map[string]json.RawMessage) ([]string, error) {
+	var refs []string
+	for _, name := range []string{"source_ref", "target_ref", "sighting_of_ref"} {
+		if !hasProperty(props, name) {
+			continue
+		}
+		var ref string
+		if err := json.Unmarshal(props[name], &ref); err != nil {
+			return nil, fmt.Errorf("%s must be a string", name)
+		}
+		refs = append(refs, ref)
+	}
+	for _, name := range []string{"observed_data_refs", "where_sighted_refs"} {
+		if !hasProperty(props, name) {
+			continue
+		}
+		var list []string
+		if err := json.Unmarshal(props[name], &list); err != nil {
+			return nil, fmt.Errorf("%s must be a list of strings", name)
+		}
+		refs = append(refs, list...)
+	}
+	return refs, nil
+}
+
+// stringProperty returns a string property, or "" if it is absent or not a string
+func stringProperty(props map[string]json.RawMessage, name string) string {
+	var value string
+	if err := json.Unmarshal(props[name], &value); err != nil {
+		return ""
+	}
+	return value
+}
+
+// stringListProperty returns a list-of-strings property, or nil if absent or malformed
+func stringListProperty(props map[string]json.RawMessage, name string) []string {
+	var values []string
+	if err := json.Unmarshal(props[name], &values); err != nil {
+		return nil
+	}
+	return values
+}
+
+// checkRefTypes enforces which object types a relationship or sighting may point at
+func checkRefTypes(typ string, props map[string]json.RawMessage) error {
+	switch typ {
+	case "relationship":
+		return checkRelationshipTypes(stringProperty(props, "relationship_type"),
+			stringProperty(props, "source_ref"), stringProperty(props, "target_ref"))
+
+	case "sighting":
+		t, err := stixTypeOf(stringProperty(props, "sighting_of_ref"))
+		if err != nil {
+			return err
+		}
+		if spec, ok := stixTypeSpecs[t]; !ok || spec.sco || t == "relationship" || t == "sighting" {
+			return fmt.Errorf("sighting_of_ref must point at an SDO, got %s", t)
+		}
+		for _, ref := range stringListProperty(props, "observed_data_refs") {
+			if t, _ := stixTypeOf(ref); t != "observed-data" {
+				return fmt.Errorf("observed_data_refs must point at observed-data, got %s", ref)
+			}
+		}
+		for _, ref := range stringListProperty(props, "where_sighted_refs") {
+			if t, _ := stixTypeOf(ref); t != "identity" && t != "location" {
+				return fmt.Errorf("where_sighted_refs must point at identity or location, got %s", ref)
+			}
+		}
+	}
+	return nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Reference Resolution and Pending References
+// ──────────────────────────────────────────────────────────────────────────────
+
+// pendingRefObjectType is the composite-key namespace of forward references:
+// pendingRefObjectType + [missing ID, referencing object ID]
+const pendingRefObjectType = "pendingref"
+
+// PendingRef is a reference to an object that is not on the ledger yet
+type PendingRef struct {
+	MissingID     string `json:"missing_id"`     // the referenced object that does not exist yet
+	ReferencingID string `json:"referencing_id"` // the relationship or sighting holding the reference
+}
+
+// checkObjectRefs validates the references of a relationship or sighting: each must be
+// a well-formed STIX ID of an allowed type and resolve to an object pending in this
+// transaction or on the ledger that v may see. An object that v's markings withhold
+// counts as missing, so that a reference does not reveal whether it exists. With
+// allowForward, unresolved references are returned instead of failing so they can be
+// tracked as pending.
+func (c *CTIStixContract) checkObjectRefs(
+	ctx contractapi.TransactionContextInterface,
+	v *objectViewer,
+	typ string,
+	props map[string]json.RawMessage,
+	pending map[string]json.RawMessage,
+	allowForward bool,
+) ([]string, error) {
+	refs, err := relationshipRefs(props)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		if !stixIDPattern.MatchString(ref) {
+			return nil, fmt.Errorf("reference '%s' is not of the form <type>--<uuid>", ref)
+		}
+	}
+	if err := checkRefTypes(typ, props); err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, ref := range refs {
+		found, err := c.refResolves(ctx, v, ref, pending)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			continue
+		}
+		if !allowForward {
+			return nil, fmt.Errorf("referenced object %s does not exist", ref)
+		}
+		missing = append(missing, ref)
+	}
+	return missing, nil
+}
+
+// refResolves reports whether a reference resolves to an object pending in this
+// transaction or to a stored object that v may see
+func (c *CTIStixContract) refResolves(
+	ctx contractapi.TransactionContextInterface,
+	v *objectViewer,
+	ref string,
+	pending map[string]json.RawMessage,
+) (bool, error) {
+	if _, ok := pending[ref]; ok {
+		return true, nil
+	}
+	exists, err := c.stixObjectExists(ctx, ref)
+	if err != nil || !exists {
+		return false, err
+	}
+	rec, err := c.getStixObject(ctx, ref)
+	if err != nil {
+		return false, err
+	}
+	obj, err := v.view(rec)
+	if err != nil {
+		return false, err
+	}
+	return obj != nil, nil
+}
+
+// recordPendingRefs indexes the unresolved references of an object
+func (c *CTIStixContract) recordPendingRefs(ctx contractapi.TransactionContextInterface, referencingID string, missing []string) error {
+	for _, ref := range missing {
+		key, err := ctx.GetStub().CreateCompositeKey(pendingRefObjectType, []string{ref, referencingID})
+		if err != nil {
+			return fmt.Errorf("failed to create pending reference key: %v", err)
+		}
+		if err := c.putAsset(ctx, key, []byte{0x00}); err != nil {
+			return fmt.Errorf("failed to record pending reference to %s: %v", ref, err)
+		}
+	}
+	return nil
+}
+
+// updatePendingRefs replaces the pending references of a versioned object. As with
+// the index keys in updateObjectIndexes, the entries of the previous version that the
+// new one no longer holds are deleted, and the new unresolved references recorded.
+func (c *CTIStixContract) updatePendingRefs(
+	ctx contractapi.TransactionContextInterface,
+	referencingID string,
+	previous map[string]json.RawMessage,
+	missing []string,
+) error {
+	previousRefs, err := relationshipRefs(previous)
+	if err != nil {
+		return fmt.Errorf("failed to read references of the current version of %s: %v", referencingID, err)
+	}
+	keep := make(map[string]bool, len(missing))
+	for _, ref := range missing {
+		keep[ref] = true
+	}
+	for _, ref := range previousRefs {
+		if keep[ref] {
+			continue
+		}
+		key, err := ctx.GetStub().CreateCompositeKey(pendingRefObjectType, []string{ref, referencingID})
+		if err != nil {
+			return fmt.Errorf("failed to create pending reference key: %v", err)
+		}
+		if err := ctx.GetStub().DelState(key); err != nil {
+			return fmt.Errorf("failed to drop pending reference to %s: %v", ref, err)
+		}
+	}
+	return c.recordPendingRefs(ctx, referencingID, missing)
+}
+
+// resolvePendingRefs drops the pending references to a newly created object
+func (c *CTIStixContract) resolvePendingRefs(ctx contractapi.TransactionContextInterface, id string) error {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(pendingRefObjectType, []string{id})
+	if err != nil {
+		return fmt.Errorf("failed to read pending references to %s: %v", id, err)
+	}
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return fmt.Errorf("failed to iterate: %v", err)
+		}
+		if err := ctx.GetStub().DelState(queryResponse.Key); err != nil {
+			return fmt.Errorf("failed to resolve pending reference to %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// GetPendingRefs lists references to objects that are not on the ledger yet, either
+// all of them (empty missingID) or only those to one missing object. References held
+// by objects that the caller's markings withhold are left out.
+func (c *CTIStixContract) GetPendingRefs(
+	ctx contractapi.TransactionContextInterface,
+	missingID string,
+) ([]PendingRef, error) {
+	v, err := c.newObjectViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var attributes []string
+	if missingID != "" {
+		attributes = []string{missingID}
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(pendingRefObjectType, attributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pending references: %v", err)
+	}
+	defer iterator.Close()
+
+	refs := []PendingRef{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate: %v", err)
+		}
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 2 {
+			return nil, fmt.Errorf("malformed pending reference key %q", queryResponse.Key)
+		}
+		rec, err := c.getStixObject(ctx, parts[1])
+		if err != nil {
+			return nil, err
+		}
+		obj, err := v.view(rec)
+		if err != nil {
+			return nil, err
+		}
+		if obj == nil {
+			continue
+		}
+		refs = append(refs, PendingRef{MissingID: parts[0], ReferencingID: parts[1]})
+	}
+	return refs, nil
+}
@@ -0,0 +1,246 @@
+// File: stix_reputation.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Reputation Entries
+// ──────────────────────────────────────────────────────────────────────────────
+
+// reputationObjectType is the composite-key namespace of reputation entries:
+// reputationObjectType + [rated MSP ID, kind, feedback org identity, rated object ID,
+// source object ID]. Entries are secondary-index keys of the source object, so they
+// follow its versions and every feedback object writes its own key, free of MVCC
+// conflicts. An organization's feedback of one kind on one object counts once, however
+// many source objects carry it.
+const reputationObjectType = "reputation"
+
+// Reputation entry kinds
+const (
+	reputationSighted  = "sighted"  // another org sighted the indicator
+	reputationEndorsed = "endorsed" // another org agrees with the indicator
+	reputationDisputed = "disputed" // another org disagrees with the indicator
+	reputationRevoked  = "revoked"  // the creator revoked its own object
+)
+
+// opinionReputation maps the opinion-enum to the entry it leaves; neutral leaves none
+var opinionReputation = map[string]string{
+	"strongly-disagree": reputationDisputed,
+	"disagree":          reputationDisputed,
+	"agree":             reputationEndorsed,
+	"strongly-agree":    reputationEndorsed,
+}
+
+// objectCreatorMSP returns the MSP ID of the creator of a stored object, or "" if the
+// object is not on the ledger
+func (c *CTIStixContract) objectCreatorMSP(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	exists, err := c.stixObjectExists(ctx, id)
+	if err != nil || !exists {
+		return "", err
+	}
+	rec, err := c.getStixObject(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return rec.CreatorMSP, nil
+}
+
+// reputationKeys returns the reputation entries one version of an object leaves: a
+// revoked object counts against its creator, and an unrevoked sighting or opinion
+// about another organization's indicator counts for or against that organization.
+// Indicators that are not on the ledger yet are not rated.
+func (c *CTIStixContract) reputationKeys(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+	props map[string]json.RawMessage,
+) ([]string, error) {
+	var revoked bool
+	if err := json.Unmarshal(props["revoked"], &revoked); err == nil && revoked {
+		creatorMSP, err := c.objectCreatorMSP(ctx, id)
+		if err != nil || creatorMSP == "" {
+			return nil, err
+		}
+		key, err := ctx.GetStub().CreateCompositeKey(reputationObjectType, []string{creatorMSP, reputationRevoked, orgIdentityID(creatorMSP), id, id})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create reputation key for %s: %v", id, err)
+		}
+		return []string{key}, nil
+	}
+
+	kind := ""
+	var targets []string
+	switch stringProperty(props, "type") {
+	case "sighting":
+		kind, targets = reputationSighted, []string{stringProperty(props, "sighting_of_ref")}
+	case "opinion":
+		kind, targets = opinionReputation[stringProperty(props, "opinion")], stringListProperty(props, "object_refs")
+	}
+	if kind == "" {
+		return nil, nil
+	}
+
+	var keys []string
+	for _, ref := range targets {
+		if typ, err := stixTypeOf(ref); err != nil || typ != "indicator" {
+			continue
+		}
+		creatorMSP, err := c.objectCreatorMSP(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		author := stringProperty(props, "created_by_ref") // stamped, so it names the submitting org
+		if creatorMSP == "" || orgIdentityID(creatorMSP) == author {
+			continue // unknown yet, or the creator's own feedback
+		}
+		key, err := ctx.GetStub().CreateCompositeKey(reputationObjectType, []string{creatorMSP, kind, author, ref, id})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create reputation key for %s: %v", id, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Reputation Methods
+// ──────────────────────────────────────────────────────────────────────────────
+
+// OrgReputation tallies the feedback on one organization's intelligence
+type OrgReputation struct {
+	MSPID    string `json:"msp_id"`
+	Sighted  int    `json:"sighted"`  // other orgs that sighted one of its indicators, per indicator
+	Endorsed int    `json:"endorsed"` // agreeing opinions of other orgs
+	Disputed int    `json:"disputed"` // disagreeing opinions of other orgs
+	Revoked  int    `json:"revoked"`  // objects it revoked itself
+	Score    int    `json:"score"`    // 0-100, 50 without any feedback
+}
+
+// add counts one reputation entry
+func (r *OrgReputation) add(kind string) {
+	switch kind {
+	case reputationSighted:
+		r.Sighted++
+	case reputationEndorsed:
+		r.Endorsed++
+	case reputationDisputed:
+		r.Disputed++
+	case reputationRevoked:
+		r.Revoked++
+	}
+}
+
+// score rates an organization with the Laplace-smoothed share of positive feedback:
+//
+//	score = (positive + 1) / (positive + negative + 2) · 100, rounded
+//
+// where positive = sighted + endorsed and negative = disputed + revoked
+func (r *OrgReputation) score() int {
+	positive := r.Sighted + r.Endorsed
+	total := positive + r.Disputed + r.Revoked
+	return (200*(positive+1) + total + 2) / (2 * (total + 2))
+}
+
+// readReputations tallies the reputation entries under a partial key, by MSP ID,
+// counting entries that differ only in their source object once
+func (c *CTIStixContract) readReputations(
+	ctx contractapi.TransactionContextInterface,
+	attributes []string,
+) (map[string]*OrgReputation, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(reputationObjectType, attributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reputation entries: %v", err)
+	}
+	defer iterator.Close()
+
+	reputations := map[string]*OrgReputation{}
+	counted := map[string]bool{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate: %v", err)
+		}
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 5 {
+			return nil, fmt.Errorf("malformed reputation key %q", queryResponse.Key)
+		}
+		feedback := strings.Join(parts[:4], "|")
+		if counted[feedback] {
+			continue // e.g. a second sighting of the same indicator by the same org
+		}
+		counted[feedback] = true
+		rep, ok := reputations[parts[0]]
+		if !ok {
+			rep = &OrgReputation{MSPID: parts[0]}
+			reputations[parts[0]] = rep
+		}
+		rep.add(parts[1])
+	}
+	for _, rep := range reputations {
+		rep.Score = rep.score()
+	}
+	return reputations, nil
+}
+
+// GetOrgReputation returns the reputation of an organization (the caller's own if
+// mspID is empty)
+func (c *CTIStixContract) GetOrgReputation(
+	ctx contractapi.TransactionContextInterface,
+	mspID string,
+) (*OrgReputation, error) {
+	if err := c.requirePermission(ctx, actionRead); err != nil {
+		return nil, err
+	}
+	if mspID == "" {
+		var err error
+		if mspID, _, err = c.getSubmitter(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	reputations, err := c.readReputations(ctx, []string{mspID})
+	if err != nil {
+		return nil, err
+	}
+	if rep, ok := reputations[mspID]; ok {
+		return rep, nil
+	}
+	rep := &OrgReputation{MSPID: mspID}
+	rep.Score = rep.score()
+	return rep, nil
+}
+
+// ListOrgReputations ranks every organization that has received feedback, best
+// score first; ties go to the organization with more positive feedback
+func (c *CTIStixContract) ListOrgReputations(ctx contractapi.TransactionContextInterface) ([]*OrgReputation, error) {
+	if err := c.requirePermission(ctx, actionRead); err != nil {
+		return nil, err
+	}
+
+	reputations, err := c.readReputations(ctx, []string{})
+	if err != nil {
+		return nil, err
+	}
+	ranking := make([]*OrgReputation, 0, len(reputations))
+	for _, rep := range reputations {
+		ranking = append(ranking, rep)
+	}
+	sort.Slice(ranking, func(i, j int) bool {
+		a, b := ranking[i], ranking[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if pa, pb := a.Sighted+a.Endorsed, b.Sighted+b.Endorsed; pa != pb {
+			return pa > pb
+		}
+		return a.MSPID < b.MSPID
+	})
+	return ranking, nil
+}
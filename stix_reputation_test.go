@@ -0,0 +1,138 @@
+// File: stix_reputation_test.go
+
+package main
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// reputation runs GetOrgReputation as id
+func (l *mockLedger) reputation(id *mockIdentity, mspID string) OrgReputation {
+	l.t.Helper()
+	var rep *OrgReputation
+	err := l.invoke(id, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		rep, err = (&CTIStixContract{}).GetOrgReputation(ctx, mspID)
+		return err
+	})
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	return *rep
+}
+
+func TestReputationScore(t *testing.T) {
+	tests := []struct {
+		rep  OrgReputation
+		want int
+	}{
+		{OrgReputation{}, 50},
+		{OrgReputation{Sighted: 1}, 67},
+		{OrgReputation{Revoked: 1}, 33},
+		{OrgReputation{Sighted: 2, Endorsed: 2, Disputed: 1, Revoked: 1}, 63}, // 5 / 8
+		{OrgReputation{Endorsed: 98}, 99},
+	}
+	for _, tt := range tests {
+		if got := tt.rep.score(); got != tt.want {
+			t.Errorf("score of %+v = %d, want %d", tt.rep, got, tt.want)
+		}
+	}
+}
+
+func TestOrgReputation(t *testing.T) {
+	const (
+		org1Indicator = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+		org2Indicator = "indicator--0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21"
+	)
+	l := newMockLedger(t)
+	org1 := newMockIdentity("Org1MSP", "lead", "", false)
+	org2 := newMockIdentity("Org2MSP", "lead", "", false)
+	org3 := newMockIdentity("Org3MSP", "lead", "", false)
+	l.create(org1, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45"))
+	l.create(org2, testIndicator("0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21", "198.51.100.7"))
+
+	// Repeated sightings by one organization count once; the creator's own do not count
+	for _, sensor := range []*mockIdentity{org2, org2, org3, org1} {
+		if err := l.reportSighting(sensor, org1Indicator, 1, "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.create(org3, testOpinion("c626b7b2-36b3-4626-95c4-b4c5d6e7f809", "agree", org1Indicator))
+	l.create(org1, testOpinion("d737c8c3-47c4-4737-a6d5-c5d6e7f8091a", "strongly-disagree", org2Indicator))
+	l.create(org3, testOpinion("e848d9d4-58d5-4848-b7e6-d6e7f8091a2b", "neutral", org2Indicator))
+	err := l.invoke(org2, func(ctx contractapi.TransactionContextInterface) error {
+		return (&CTIStixContract{}).RevokeObject(ctx, org2Indicator, "false positive")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := l.reputation(org2, "Org1MSP"), (OrgReputation{MSPID: "Org1MSP", Sighted: 2, Endorsed: 1, Score: 80}); got != want {
+		t.Errorf("Org1MSP reputation %+v, want %+v", got, want)
+	}
+	if got, want := l.reputation(org2, ""), (OrgReputation{MSPID: "Org2MSP", Disputed: 1, Revoked: 1, Score: 25}); got != want {
+		t.Errorf("own reputation %+v, want %+v", got, want)
+	}
+
+	// A revised opinion replaces the entry of the earlier version
+	revised := testOpinion("c626b7b2-36b3-4626-95c4-b4c5d6e7f809", "disagree", org1Indicator)
+	revised["modified"] = "2025-05-02T08:00:00.000Z"
+	if err := l.update(org3, revised); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := l.reputation(org2, "Org1MSP"), (OrgReputation{MSPID: "Org1MSP", Sighted: 2, Disputed: 1, Score: 60}); got != want {
+		t.Errorf("Org1MSP reputation after the revision %+v, want %+v", got, want)
+	}
+	if got, want := l.reputation(org2, "Org4MSP"), (OrgReputation{MSPID: "Org4MSP", Score: 50}); got != want {
+		t.Errorf("reputation without feedback %+v, want %+v", got, want)
+	}
+
+	var ranking []*OrgReputation
+	err = l.invoke(org3, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		ranking, err = (&CTIStixContract{}).ListOrgReputations(ctx)
+		return err
+	})
+	if err != nil || len(ranking) != 2 || ranking[0].MSPID != "Org1MSP" || ranking[1].MSPID != "Org2MSP" {
+		t.Errorf("ranking %v, %v", ranking, err)
+	}
+}
+
+// Equal scores rank the organization with more positive feedback first
+func TestReputationRankingTies(t *testing.T) {
+	l := newMockLedger(t)
+	org1 := newMockIdentity("Org1MSP", "lead", "", false)
+	org2 := newMockIdentity("Org2MSP", "lead", "", false)
+	l.create(org1, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45"))
+	l.create(org1, testIndicator("0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21", "198.51.100.7"))
+	l.create(org2, testIndicator("1b7b0c07-8b1a-4b7b-aab9-091a2b3c4d5e", "192.0.2.1"))
+
+	// Org1MSP: 2 positive, 2 negative; Org2MSP: 1 positive, 1 negative; both score 50
+	for _, ref := range []string{"indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "indicator--0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21"} {
+		if err := l.reportSighting(org2, ref, 1, "", ""); err != nil {
+			t.Fatal(err)
+		}
+		err := l.invoke(org1, func(ctx contractapi.TransactionContextInterface) error {
+			return (&CTIStixContract{}).RevokeObject(ctx, ref, "expired")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.reportSighting(org1, "indicator--1b7b0c07-8b1a-4b7b-aab9-091a2b3c4d5e", 1, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	l.create(org1, testOpinion("c626b7b2-36b3-4626-95c4-b4c5d6e7f809", "disagree", "indicator--1b7b0c07-8b1a-4b7b-aab9-091a2b3c4d5e"))
+
+	var ranking []*OrgReputation
+	err := l.invoke(org2, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		ranking, err = (&CTIStixContract{}).ListOrgReputations(ctx)
+		return err
+	})
+	if err != nil || len(ranking) != 2 || ranking[0].MSPID != "Org1MSP" || ranking[0].Score != 50 || ranking[1].Score != 50 {
+		t.Errorf("ranking %+v, %v", ranking, err)
+	}
+}
@@ -0,0 +1,113 @@
+// File: stix_revocation.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Revocation Methods
+// ──────────────────────────────────────────────────────────────────────────────
+
+// RevokeObject withdraws a STIX object by storing a new version with “revoked” set
+// and “modified” set to the transaction time. Only the creating organization may
+// revoke, a reason is required, and an ObjectRevoked event is emitted.
+func (c *CTIStixContract) RevokeObject(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+	reason string,
+) error {
+	if err := c.requirePermission(ctx, actionRevoke); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("a reason is required to revoke %s", id)
+	}
+
+	current, err := c.getStixObject(ctx, id)
+	if err != nil {
+		return err
+	}
+	if current.Type == "bundle" {
+		return fmt.Errorf("bundles cannot be revoked; revoke the objects they contain")
+	}
+	if current.Type == "marking-definition" {
+		return fmt.Errorf("marking definitions cannot be revoked")
+	}
+	if spec := stixTypeSpecs[current.Type]; spec.sco {
+		return fmt.Errorf("cyber-observables cannot be revoked; revoke the indicators or observed data that use them")
+	}
+	mspID, _, err := c.getSubmitter(ctx)
+	if err != nil {
+		return err
+	}
+	if current.CreatorMSP != mspID {
+		return fmt.Errorf("only %s may revoke %s, submitter is %s", current.CreatorMSP, id, mspID)
+	}
+	if current.Revoked {
+		return fmt.Errorf("%s has already been revoked", id)
+	}
+
+	// The new version must be strictly later than the current one at the millisecond
+	// precision “modified” is stored with, even if the current “modified” lies ahead
+	// of the transaction clock
+	now, err := c.txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	now = now.Truncate(time.Millisecond)
+	if curModified, err := parseStixTimestamp(current.Modified); err == nil && !now.After(curModified) {
+		now = curModified.Add(time.Millisecond)
+	}
+
+	// Patch the stored JSON rather than re-marshal it, to keep its property order
+	modified, err := json.Marshal(formatStixTimestamp(now))
+	if err != nil {
+		return fmt.Errorf("failed to marshal modified: %v", err)
+	}
+	revokedJSON, err := setProperty(current.Object, "revoked", json.RawMessage("true"))
+	if err != nil {
+		return err
+	}
+	if revokedJSON, err = setProperty(revokedJSON, "modified", modified); err != nil {
+		return err
+	}
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(revokedJSON, &props); err != nil {
+		return fmt.Errorf("failed to unmarshal %s JSON: %v", id, err)
+	}
+
+	rec, err := newLedgerRecord(revokedJSON)
+	if err != nil {
+		return err
+	}
+	rec.CreatorMSP, rec.CreatorSubject = current.CreatorMSP, current.CreatorSubject
+	rec.Reason = reason
+	if err := c.putStixObject(ctx, rec); err != nil {
+		return err
+	}
+	var previous map[string]json.RawMessage
+	if err := json.Unmarshal(current.Object, &previous); err != nil {
+		return fmt.Errorf("failed to parse current version of %s: %v", id, err)
+	}
+	if err := c.updateObjectIndexes(ctx, id, previous, props); err != nil {
+		return err
+	}
+
+	public, err := c.publicViewer(ctx)
+	if err != nil {
+		return err
+	}
+	event := newObjectEvent(ctx, objectRevokedEvent, rec, props, public)
+	if !event.Restricted {
+		event.Reason = reason
+	}
+	return c.emitEvents(ctx, []ObjectEvent{event})
+}
@@ -0,0 +1,178 @@
+// File: stix_revocation_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestRevokeObject(t *testing.T) {
+	l := newMockLedger(t)
+	producer := newMockIdentity("Org1MSP", "analyst", "producer", false)
+	lead := newMockIdentity("Org1MSP", "lead", "", false) // default roles: producer and reviewer
+	org2 := newMockIdentity("Org2MSP", "lead", "", false)
+	const id = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+	l.create(producer, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45"))
+	l.events()
+
+	revoke := func(reason string) func(ctx contractapi.TransactionContextInterface) error {
+		return func(ctx contractapi.TransactionContextInterface) error {
+			return (&CTIStixContract{}).RevokeObject(ctx, id, reason)
+		}
+	}
+	steps := []struct {
+		name    string
+		client  *mockIdentity
+		fn      func(ctx contractapi.TransactionContextInterface) error
+		errText string
+	}{
+		{"producer role", producer, revoke("false positive"), "may not revoke"},
+		{"another organization", org2, revoke("false positive"), "only Org1MSP may revoke"},
+		{"no reason", lead, revoke(" "), "reason is required"},
+		{"revoke", lead, revoke("false positive"), ""},
+		{"revoke twice", lead, revoke("false positive"), "already been revoked"},
+		{"version a revoked object", lead, func(ctx contractapi.TransactionContextInterface) error {
+			obj := testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45")
+			obj["modified"] = "2030-01-01T00:00:00.000Z"
+			return (&CTIStixContract{}).UpdateObject(ctx, string(stixObject(t, obj)))
+		}, "cannot be versioned"},
+	}
+	for _, step := range steps {
+		err := l.invoke(step.client, step.fn)
+		if step.errText == "" && err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if step.errText != "" && (err == nil || !strings.Contains(err.Error(), step.errText)) {
+			t.Errorf("%s: error %v, want %q", step.name, err, step.errText)
+		}
+	}
+	if events := l.events(); len(events) != 1 || events[0] != objectRevokedEvent {
+		t.Errorf("events %v, want a single %s", events, objectRevokedEvent)
+	}
+
+	// The revoked version keeps the submitted property order
+	var obj string
+	err := l.invoke(producer, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		obj, err = (&CTIStixContract{}).ReadObject(ctx, id, true)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(obj, `{"created_by_ref":"`+orgIdentityID("Org1MSP")+`","created":"2025-05-01T12:15:00.000Z","id":"`+id+`","modified":"`) ||
+		!strings.HasSuffix(obj, `"valid_from":"2025-05-01T00:00:00Z","revoked":true}`) || strings.Contains(obj, `"modified":"2025-05-01T12:15:00.000Z"`) {
+		t.Errorf("revoked version %s", obj)
+	}
+}
+
+// A revocation in the same millisecond as the current version, or before it, is
+// still stored as a later version
+func TestRevokeObjectModified(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		clock    time.Time
+		modified string
+	}{
+		{"same millisecond", time.Date(2025, 5, 1, 12, 15, 0, 400000, time.UTC), "2025-05-01T12:15:00.001Z"},
+		{"clock behind", time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC), "2025-05-01T12:15:00.001Z"},
+		{"clock ahead", time.Date(2025, 5, 2, 8, 0, 0, 999999, time.UTC), "2025-05-02T08:00:00.000Z"},
+	} {
+		l := newMockLedger(t)
+		lead := newMockIdentity("Org1MSP", "lead", "", false)
+		l.create(lead, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45"))
+		l.clock = tt.clock
+		err := l.invoke(lead, func(ctx contractapi.TransactionContextInterface) error {
+			return (&CTIStixContract{}).RevokeObject(ctx, "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "false positive")
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if rec := l.record("indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"); rec.Modified != tt.modified || !rec.Revoked {
+			t.Errorf("%s: modified %s, revoked %v; want %s", tt.name, rec.Modified, rec.Revoked, tt.modified)
+		}
+	}
+}
+
+func TestReadersHideRevokedObjects(t *testing.T) {
+	l := newMockLedger(t)
+	producer := newMockIdentity("Org1MSP", "analyst", "producer", false)
+	lead := newMockIdentity("Org1MSP", "lead", "", false)
+	const revoked = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+	const active = "indicator--0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21"
+	l.create(producer, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45"))
+	l.create(producer, testIndicator("0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21", "198.51.100.7"))
+	err := l.invoke(lead, func(ctx contractapi.TransactionContextInterface) error {
+		return (&CTIStixContract{}).RevokeObject(ctx, revoked, "false positive")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &CTIStixContract{}
+	readers := []struct {
+		name string
+		read func(ctx contractapi.TransactionContextInterface, id string, includeRevoked bool) error
+	}{
+		{"ReadObject", func(ctx contractapi.TransactionContextInterface, id string, includeRevoked bool) error {
+			_, err := c.ReadObject(ctx, id, includeRevoked)
+			return err
+		}},
+		{"ReadIndicator", func(ctx contractapi.TransactionContextInterface, id string, includeRevoked bool) error {
+			ind, err := c.ReadIndicator(ctx, id, includeRevoked)
+			if err == nil && ind.Revoked != (id == revoked) {
+				t.Errorf("ReadIndicator(%s).Revoked = %v", id, ind.Revoked)
+			}
+			return err
+		}},
+	}
+	for _, r := range readers {
+		for _, tt := range []struct {
+			id             string
+			includeRevoked bool
+			errText        string
+		}{
+			{active, false, ""},
+			{revoked, false, "has been revoked: false positive"},
+			{revoked, true, ""},
+		} {
+			err := l.invoke(producer, func(ctx contractapi.TransactionContextInterface) error {
+				return r.read(ctx, tt.id, tt.includeRevoked)
+			})
+			if tt.errText == "" && err != nil {
+				t.Errorf("%s(%s, %v): %v", r.name, tt.id, tt.includeRevoked, err)
+			}
+			if tt.errText != "" && (err == nil || !strings.Contains(err.Error(), tt.errText)) {
+				t.Errorf("%s(%s, %v): error %v, want %q", r.name, tt.id, tt.includeRevoked, err, tt.errText)
+			}
+		}
+	}
+
+	// The typed readers refuse objects of another type
+	err = l.invoke(producer, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := c.ReadRelationship(ctx, active, false)
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "not a relationship") {
+		t.Errorf("ReadRelationship of an indicator: %v", err)
+	}
+
+	for _, includeRevoked := range []bool{false, true} {
+		var page ObjectPage
+		err := l.invoke(producer, func(ctx contractapi.TransactionContextInterface) error {
+			pageJSON, err := c.ListObjects(ctx, "indicator", 10, "", includeRevoked)
+			if err != nil {
+				return err
+			}
+			return json.Unmarshal([]byte(pageJSON), &page)
+		})
+		if want := map[bool]int{false: 1, true: 2}[includeRevoked]; err != nil || len(page.Objects) != want {
+			t.Errorf("ListObjects(includeRevoked %v) = %d objects, %v, want %d", includeRevoked, len(page.Objects), err, want)
+		}
+	}
+}
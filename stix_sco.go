@@ -0,0 +1,592 @@
+// File: stix_sco.go
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) STIX 2.1 Cyber-observable Objects
+// ──────────────────────────────────────────────────────────────────────────────
+
+// SCOCommonProperties holds the properties shared by every STIX 2.1 SCO. SCOs carry
+// no created, modified or created_by_ref: they describe facts, not claims.
+type SCOCommonProperties struct {
+	Type              string                     `json:"type"`
+	ID                string                     `json:"id"`
+	SpecVersion       string                     `json:"spec_version,omitempty"`
+	ObjectMarkingRefs []string                   `json:"object_marking_refs,omitempty"`
+	Defanged          bool                       `json:"defanged,omitempty"`
+	Extensions        map[string]json.RawMessage `json:"extensions,omitempty"`
+}
+
+// Artifact represents a STIX 2.1 “artifact” object
+type Artifact struct {
+	SCOCommonProperties
+	MimeType   string            `json:"mime_type,omitempty"`
+	PayloadBin string            `json:"payload_bin,omitempty"` // base64
+	URL        string            `json:"url,omitempty"`
+	Hashes     map[string]string `json:"hashes,omitempty"`
+}
+
+// AutonomousSystem represents a STIX 2.1 “autonomous-system” object
+type AutonomousSystem struct {
+	SCOCommonProperties
+	Number int64  `json:"number"`
+	Name   string `json:"name,omitempty"`
+	RIR    string `json:"rir,omitempty"`
+}
+
+// Directory represents a STIX 2.1 “directory” object
+type Directory struct {
+	SCOCommonProperties
+	Path         string   `json:"path"`
+	Ctime        string   `json:"ctime,omitempty"`
+	Mtime        string   `json:"mtime,omitempty"`
+	Atime        string   `json:"atime,omitempty"`
+	ContainsRefs []string `json:"contains_refs,omitempty"`
+}
+
+// DomainName represents a STIX 2.1 “domain-name” object
+type DomainName struct {
+	SCOCommonProperties
+	Value          string   `json:"value"`
+	ResolvesToRefs []string `json:"resolves_to_refs,omitempty"`
+}
+
+// EmailAddr represents a STIX 2.1 “email-addr” object
+type EmailAddr struct {
+	SCOCommonProperties
+	Value        string `json:"value"`
+	DisplayName  string `json:"display_name,omitempty"`
+	BelongsToRef string `json:"belongs_to_ref,omitempty"`
+}
+
+// EmailMessage represents a STIX 2.1 “email-message” object
+type EmailMessage struct {
+	SCOCommonProperties
+	IsMultipart bool     `json:"is_multipart"`
+	Date        string   `json:"date,omitempty"`
+	ContentType string   `json:"content_type,omitempty"`
+	FromRef     string   `json:"from_ref,omitempty"`
+	ToRefs      []string `json:"to_refs,omitempty"`
+	CcRefs      []string `json:"cc_refs,omitempty"`
+	Subject     string   `json:"subject,omitempty"`
+	Body        string   `json:"body,omitempty"`
+}
+
+// File represents a STIX 2.1 “file” object
+type File struct {
+	SCOCommonProperties
+	Hashes             map[string]string `json:"hashes,omitempty"`
+	Size               int64             `json:"size,omitempty"`
+	Name               string            `json:"name,omitempty"`
+	MimeType           string            `json:"mime_type,omitempty"`
+	Ctime              string            `json:"ctime,omitempty"`
+	Mtime              string            `json:"mtime,omitempty"`
+	Atime              string            `json:"atime,omitempty"`
+	ParentDirectoryRef string            `json:"parent_directory_ref,omitempty"`
+	ContainsRefs       []string          `json:"contains_refs,omitempty"`
+	ContentRef         string            `json:"content_ref,omitempty"`
+}
+
+// IPv4Addr represents a STIX 2.1 “ipv4-addr” object
+type IPv4Addr struct {
+	SCOCommonProperties
+	Value          string   `json:"value"` // address or CIDR block
+	ResolvesToRefs []string `json:"resolves_to_refs,omitempty"`
+	BelongsToRefs  []string `json:"belongs_to_refs,omitempty"`
+}
+
+// IPv6Addr represents a STIX 2.1 “ipv6-addr” object
+type IPv6Addr struct {
+	SCOCommonProperties
+	Value          string   `json:"value"` // address or CIDR block
+	ResolvesToRefs []string `json:"resolves_to_refs,omitempty"`
+	BelongsToRefs  []string `json:"belongs_to_refs,omitempty"`
+}
+
+// MACAddr represents a STIX 2.1 “mac-addr” object
+type MACAddr struct {
+	SCOCommonProperties
+	Value string `json:"value"`
+}
+
+// Mutex represents a STIX 2.1 “mutex” object
+type Mutex struct {
+	SCOCommonProperties
+	Name string `json:"name"`
+}
+
+// NetworkTraffic represents a STIX 2.1 “network-traffic” object
+type NetworkTraffic struct {
+	SCOCommonProperties
+	Start     string   `json:"start,omitempty"`
+	End       string   `json:"end,omitempty"`
+	IsActive  bool     `json:"is_active,omitempty"`
+	SrcRef    string   `json:"src_ref,omitempty"`
+	DstRef    string   `json:"dst_ref,omitempty"`
+	SrcPort   int      `json:"src_port,omitempty"`
+	DstPort   int      `json:"dst_port,omitempty"`
+	Protocols []string `json:"protocols"`
+	SrcBytes  int64    `json:"src_byte_count,omitempty"`
+	DstBytes  int64    `json:"dst_byte_count,omitempty"`
+}
+
+// Process represents a STIX 2.1 “process” object
+type Process struct {
+	SCOCommonProperties
+	IsHidden    bool     `json:"is_hidden,omitempty"`
+	PID         int64    `json:"pid,omitempty"`
+	CreatedTime string   `json:"created_time,omitempty"`
+	Cwd         string   `json:"cwd,omitempty"`
+	CommandLine string   `json:"command_line,omitempty"`
+	ImageRef    string   `json:"image_ref,omitempty"`
+	ParentRef   string   `json:"parent_ref,omitempty"`
+	ChildRefs   []string `json:"child_refs,omitempty"`
+}
+
+// Software represents a STIX 2.1 “software” object
+type Software struct {
+	SCOCommonProperties
+	Name      string   `json:"name"`
+	CPE       string   `json:"cpe,omitempty"`
+	SWID      string   `json:"swid,omitempty"`
+	Languages []string `json:"languages,omitempty"`
+	Vendor    string   `json:"vendor,omitempty"`
+	Version   string   `json:"version,omitempty"`
+}
+
+// URL represents a STIX 2.1 “url” object
+type URL struct {
+	SCOCommonProperties
+	Value string `json:"value"`
+}
+
+// UserAccount represents a STIX 2.1 “user-account” object
+type UserAccount struct {
+	SCOCommonProperties
+	UserID       string `json:"user_id,omitempty"`
+	AccountLogin string `json:"account_login,omitempty"`
+	AccountType  string `json:"account_type,omitempty"`
+	DisplayName  string `json:"display_name,omitempty"`
+	IsPrivileged bool   `json:"is_privileged,omitempty"`
+}
+
+// WindowsRegistryKey represents a STIX 2.1 “windows-registry-key” object
+type WindowsRegistryKey struct {
+	SCOCommonProperties
+	Key    string `json:"key,omitempty"`
+	Values []struct {
+		Name     string `json:"name,omitempty"`
+		Data     string `json:"data,omitempty"`
+		DataType string `json:"data_type,omitempty"`
+	} `json:"values,omitempty"`
+	ModifiedTime string `json:"modified_time,omitempty"`
+}
+
+// X509Certificate represents a STIX 2.1 “x509-certificate” object
+type X509Certificate struct {
+	SCOCommonProperties
+	IsSelfSigned      bool              `json:"is_self_signed,omitempty"`
+	Hashes            map[string]string `json:"hashes,omitempty"`
+	Version           string            `json:"version,omitempty"`
+	SerialNumber      string            `json:"serial_number,omitempty"`
+	SignatureAlgo     string            `json:"signature_algorithm,omitempty"`
+	Issuer            string            `json:"issuer,omitempty"`
+	ValidityNotBefore string            `json:"validity_not_before,omitempty"`
+	ValidityNotAfter  string            `json:"validity_not_after,omitempty"`
+	Subject           string            `json:"subject,omitempty"`
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Type-specific Rules
+// ──────────────────────────────────────────────────────────────────────────────
+
+// checkIPAddr enforces that an ipv4-addr or ipv6-addr value is an address or CIDR
+// block of the right IP version
+func checkIPAddr(typ string) func(props map[string]json.RawMessage) error {
+	return func(props map[string]json.RawMessage) error {
+		_, err := parseIPBlock(typ, stringProperty(props, "value"))
+		return err
+	}
+}
+
+// checkFile enforces that a file carries hashes or a name
+func checkFile(props map[string]json.RawMessage) error {
+	if !hasProperty(props, "hashes") && !hasProperty(props, "name") {
+		return fmt.Errorf("file requires hashes or name")
+	}
+	return nil
+}
+
+// checkArtifact enforces that an artifact carries either its payload or a URL with hashes
+func checkArtifact(props map[string]json.RawMessage) error {
+	if hasProperty(props, "payload_bin") == hasProperty(props, "url") {
+		return fmt.Errorf("artifact requires exactly one of payload_bin or url")
+	}
+	if hasProperty(props, "url") && !hasProperty(props, "hashes") {
+		return fmt.Errorf("artifact with url requires hashes")
+	}
+	return nil
+}
+
+// checkNetworkTraffic enforces that network traffic has at least one endpoint
+func checkNetworkTraffic(props map[string]json.RawMessage) error {
+	if !hasProperty(props, "src_ref") && !hasProperty(props, "dst_ref") {
+		return fmt.Errorf("network-traffic requires src_ref or dst_ref")
+	}
+	return nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 3) Deterministic Identifiers
+// ──────────────────────────────────────────────────────────────────────────────
+
+// scoNamespace is the UUIDv5 namespace the STIX 2.1 specification defines for SCO ids
+var scoNamespace = [16]byte{
+	0x00, 0xab, 0xed, 0xb4, 0xaa, 0x42, 0x46, 0x6c,
+	0x9c, 0x01, 0xfe, 0xd2, 0x33, 0x15, 0xa9, 0xb7,
+}
+
+// scoIDContributing lists the ID-contributing properties of each SCO type, as given
+// by the STIX 2.1 specification. process has none: its ids are UUIDv4.
+var scoIDContributing = map[string][]string{
+	"artifact":             {"hashes", "payload_bin"},
+	"autonomous-system":    {"number"},
+	"directory":            {"path"},
+	"domain-name":          {"value"},
+	"email-addr":           {"value"},
+	"email-message":        {"from_ref", "subject", "body"},
+	"file":                 {"hashes", "name", "extensions", "parent_directory_ref"},
+	"ipv4-addr":            {"value"},
+	"ipv6-addr":            {"value"},
+	"mac-addr":             {"value"},
+	"mutex":                {"name"},
+	"network-traffic":      {"start", "end", "src_ref", "dst_ref", "src_port", "dst_port", "protocols", "extensions"},
+	"software":             {"name", "cpe", "swid", "vendor", "version"},
+	"url":                  {"value"},
+	"user-account":         {"account_type", "user_id", "account_login"},
+	"windows-registry-key": {"key", "values"},
+	"x509-certificate":     {"hashes", "serial_number"},
+}
+
+// hashPreference is the order in which a single hash is picked when “hashes” is an
+// ID-contributing property
+var hashPreference = []string{"MD5", "SHA-1", "SHA-256", "SHA-512"}
+
+// isObservableObject reports whether an object is an SCO, either a registered type or
+// one defined by a “new-sco” extension
+func isObservableObject(typ string, props map[string]json.RawMessage) bool {
+	if spec, ok := stixTypeSpecs[typ]; ok {
+		return spec.sco
+	}
+	return newTypeExtension(props) == "new-sco"
+}
+
+// canonicalJSON serializes a value the way RFC 8785 (JSON Canonicalization Scheme)
+// does for the values STIX uses: sorted keys, no insignificant whitespace, no HTML
+// escaping and shortest number form
+func canonicalJSON(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// observableID computes the deterministic id of an SCO: a UUIDv5 in the STIX namespace
+// over the canonical JSON of its ID-contributing properties. ok is false when the type
+// has no ID-contributing properties or the object carries none of them.
+func observableID(typ string, props map[string]json.RawMessage) (id string, ok bool, err error) {
+	contributing := map[string]interface{}{}
+	for _, name := range scoIDContributing[typ] {
+		if !hasProperty(props, name) {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal(props[name], &value); err != nil {
+			return "", false, fmt.Errorf("failed to parse %s: %v", name, err)
+		}
+		if hashes, isMap := value.(map[string]interface{}); isMap && name == "hashes" {
+			for _, algo := range hashPreference {
+				if h, found := hashes[algo]; found {
+					value = map[string]interface{}{algo: h}
+					break
+				}
+			}
+		}
+		contributing[name] = value
+	}
+	if len(contributing) == 0 {
+		return "", false, nil
+	}
+
+	name, err := canonicalJSON(contributing)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to canonicalize %s: %v", typ, err)
+	}
+	return typ + "--" + uuidV5(scoNamespace, name), true, nil
+}
+
+// uuidV5 returns the RFC 4122 version 5 (SHA-1) UUID of name in a namespace
+func uuidV5(namespace [16]byte, name []byte) string {
+	h := sha1.New()
+	h.Write(namespace[:])
+	h.Write(name)
+	u := h.Sum(nil)[:16]
+	u[6] = (u[6] & 0x0f) | 0x50 // version 5
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// checkObservableID enforces that an SCO whose type has ID-contributing properties
+// uses the UUIDv5 id derived from them
+func checkObservableID(typ string, props map[string]json.RawMessage, verr *ValidationError) {
+	expected, ok, err := observableID(typ, props)
+	if err != nil {
+		verr.add("id", "%v", err)
+		return
+	}
+	if ok && stringProperty(props, "id") != expected {
+		verr.add("id", "must be %s, the UUIDv5 of the ID-contributing properties", expected)
+	}
+}
+
+// withObservableID returns raw unchanged unless it is an SCO without an id, in which
+// case the deterministic id is computed and added
+func withObservableID(raw []byte) ([]byte, error) {
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &props); err != nil {
+		return raw, nil // reported by decodeStixObject
+	}
+	typ := stringProperty(props, "type")
+	if hasProperty(props, "id") || !isObservableObject(typ, props) {
+		return raw, nil
+	}
+	id, ok, err := observableID(typ, props)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s has no ID-contributing properties, an id is required", typ)
+	}
+	return prependProperty(raw, "id", id)
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 4) Merging Resubmitted Observables
+// ──────────────────────────────────────────────────────────────────────────────
+
+// scoReport is another organization's submission of a stored SCO. It is kept
+// as submitted, apart from the stored object, so that its own markings keep deciding
+// who may see the properties it adds.
+type scoReport struct {
+	MSPID  string          `json:"msp_id"`
+	Object json.RawMessage `json:"object"`
+}
+
+// addMissingProperties adds to a JSON object the properties of another that it lacks,
+// in the other's order, and combines their object_marking_refs. Granular markings of
+// the other object are carried over if they only select added properties. The JSON
+// of into is otherwise left as it was.
+func addMissingProperties(into, from json.RawMessage) (json.RawMessage, error) {
+	var intoProps, fromProps map[string]json.RawMessage
+	if err := json.Unmarshal(into, &intoProps); err != nil {
+		return nil, fmt.Errorf("failed to parse STIX object JSON: %v", err)
+	}
+	if err := json.Unmarshal(from, &fromProps); err != nil {
+		return nil, fmt.Errorf("failed to parse STIX object JSON: %v", err)
+	}
+	names, err := propertyNames(from)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := into
+	added := map[string]bool{}
+	for _, name := range names {
+		if name == "object_marking_refs" || name == "granular_markings" || hasProperty(intoProps, name) {
+			continue
+		}
+		if merged, err = setProperty(merged, name, fromProps[name]); err != nil {
+			return nil, err
+		}
+		added[name] = true
+	}
+
+	current := stringListProperty(intoProps, "object_marking_refs")
+	if markings := unionStrings(current, stringListProperty(fromProps, "object_marking_refs")); len(markings) > len(current) {
+		markingsJSON, err := json.Marshal(markings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal object_marking_refs: %v", err)
+		}
+		if merged, err = setProperty(merged, "object_marking_refs", markingsJSON); err != nil {
+			return nil, err
+		}
+	}
+
+	var granular, carried []json.RawMessage
+	if err := json.Unmarshal(fromProps["granular_markings"], &carried); err != nil || len(carried) == 0 {
+		return merged, nil
+	}
+	if err := json.Unmarshal(intoProps["granular_markings"], &granular); err != nil && hasProperty(intoProps, "granular_markings") {
+		return nil, fmt.Errorf("failed to parse granular_markings: %v", err)
+	}
+	count := len(granular)
+	for _, raw := range carried {
+		var m GranularMarking
+		if err := json.Unmarshal(raw, &m); err != nil || len(m.Selectors) == 0 {
+			continue
+		}
+		selectsAdded := true
+		for _, selector := range m.Selectors {
+			selectsAdded = selectsAdded && added[selectorProperty(selector)]
+		}
+		if selectsAdded {
+			granular = append(granular, raw)
+		}
+	}
+	if len(granular) == count {
+		return merged, nil
+	}
+	granularJSON, err := json.Marshal(granular)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal granular_markings: %v", err)
+	}
+	return setProperty(merged, "granular_markings", granularJSON)
+}
+
+// observableProperties returns the properties of a stored SCO together with those
+// its reports add, which is what the SCO is indexed under
+func observableProperties(rec *ledgerRecord) (map[string]json.RawMessage, error) {
+	merged := rec.Object
+	for _, report := range rec.Reports {
+		var err error
+		if merged, err = addMissingProperties(merged, report.Object); err != nil {
+			return nil, fmt.Errorf("failed to merge the report of %s on %s: %v", report.MSPID, rec.ID, err)
+		}
+	}
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(merged, &props); err != nil {
+		return nil, fmt.Errorf("failed to parse stored %s: %v", rec.ID, err)
+	}
+	return props, nil
+}
+
+// mergeObservable records a resubmitted SCO against the stored one. A resubmission by
+// the creating organization is folded into the stored object; one by any other
+// organization is folded into that organization's report. Stored values win,
+// properties that are lacking are added and marking refs are combined, so each
+// organization's content stays under its own markings. The submitting organization is
+// recorded in ReportedBy. The returned event describes the resubmission.
+func (c *CTIStixContract) mergeObservable(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+	raw json.RawMessage,
+	props map[string]json.RawMessage,
+	mspID string,
+	public *objectViewer,
+) (ObjectEvent, error) {
+	current, err := c.getStixObject(ctx, id)
+	if err != nil {
+		return ObjectEvent{}, err
+	}
+	previous, err := observableProperties(current)
+	if err != nil {
+		return ObjectEvent{}, err
+	}
+
+	base, reports := current.Object, append([]scoReport{}, current.Reports...)
+	if mspID == current.CreatorMSP {
+		if base, err = addMissingProperties(base, raw); err != nil {
+			return ObjectEvent{}, err
+		}
+	} else {
+		found := false
+		for i := range reports {
+			if reports[i].MSPID != mspID {
+				continue
+			}
+			if reports[i].Object, err = addMissingProperties(reports[i].Object, raw); err != nil {
+				return ObjectEvent{}, err
+			}
+			found = true
+		}
+		if !found {
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, raw); err != nil {
+				return ObjectEvent{}, fmt.Errorf("failed to compact STIX JSON: %v", err)
+			}
+			reports = append(reports, scoReport{MSPID: mspID, Object: compact.Bytes()})
+		}
+	}
+
+	rec, err := newLedgerRecord(base)
+	if err != nil {
+		return ObjectEvent{}, err
+	}
+	rec.CreatorMSP, rec.CreatorSubject = current.CreatorMSP, current.CreatorSubject
+	rec.ReportedBy = unionStrings(current.ReportedBy, []string{mspID})
+	rec.Reports = reports
+	if err := c.putStixObject(ctx, rec); err != nil {
+		return ObjectEvent{}, err
+	}
+	next, err := observableProperties(rec)
+	if err != nil {
+		return ObjectEvent{}, err
+	}
+	if err := c.updateObjectIndexes(ctx, id, previous, next); err != nil {
+		return ObjectEvent{}, err
+	}
+
+	// The event reflects the resubmission and its markings, not the stored object
+	event := newObjectEvent(ctx, observableMergedEvent, rec, props, public)
+	event.ReportedBy = mspID
+	return event, nil
+}
+
+// observableVisible reports whether an organization may see a stored SCO, without
+// requiring the read permission of the submitter
+func (c *CTIStixContract) observableVisible(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+	mspID string,
+) (bool, error) {
+	rec, err := c.getStixObject(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	v, err := c.orgViewer(ctx, mspID)
+	if err != nil {
+		return false, err
+	}
+	obj, err := v.view(rec)
+	return obj != nil, err
+}
+
+// unionStrings appends the values of b missing from a, keeping the order
+func unionStrings(a, b []string) []string {
+	out := append([]string{}, a...)
+	for _, v := range b {
+		found := false
+		for _, w := range out {
+			if v == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,156 @@
+// File: stix_sco_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestUUIDv5(t *testing.T) {
+	// RFC 4122 DNS namespace; the expected value is the example of Python's uuid module
+	dns := [16]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	if got, want := uuidV5(dns, []byte("python.org")), "886313e1-3b8a-5372-9b90-0c9aee199e5d"; got != want {
+		t.Errorf("uuidV5(DNS, python.org) = %s, want %s", got, want)
+	}
+}
+
+// The expected ids are the UUIDv5 of the canonical JSON of the ID-contributing
+// properties in the STIX namespace, computed with Python's uuid.uuid5
+func TestObservableID(t *testing.T) {
+	tests := []struct {
+		obj  string
+		want string // "" if the object has no deterministic id
+	}{
+		{`{"type":"ipv4-addr","value":"198.51.100.3"}`, "ipv4-addr--28bb3599-77cd-5a82-a950-b5bc3caf07c4"},
+		{`{"type":"domain-name","value":"example.com"}`, "domain-name--bedb4899-d24b-5401-bc86-8f6b4cc18ec7"},
+		{`{"type":"email-addr","value":"john@example.com","display_name":"John Doe"}`, "email-addr--7165e2a9-671f-585d-b1e1-ca59c671d934"},
+		{`{"type":"url","value":"https://example.com/research/index.html"}`, "url--47c3cf9a-5027-5bf0-997a-017c7edc7c55"},
+		{`{"type":"autonomous-system","number":15139,"name":"Slime Industries"}`, "autonomous-system--3aa27478-50b5-5ab8-9da9-cdc12b657fff"},
+		{`{"type":"user-account","user_id":"1001","account_login":"jdoe","account_type":"unix"}`, "user-account--f94d689e-707d-58c3-b803-c720bb6ed096"},
+		{`{"type":"directory","path":"C:\\Windows\\System32"}`, "directory--0a58d0c1-59e6-5afd-8252-dcd3f13e5622"},
+
+		// Only the preferred hash contributes, MD5 first
+		{`{"type":"file","name":"foo.exe","hashes":{"SHA-256":"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855","MD5":"d41d8cd98f00b204e9800998ecf8427e"}}`, "file--51d0535d-dfbb-5909-b066-8ee329783353"},
+		{`{"type":"file","name":"foo.exe","hashes":{"MD5":"d41d8cd98f00b204e9800998ecf8427e"},"size":0}`, "file--51d0535d-dfbb-5909-b066-8ee329783353"},
+
+		// Canonical JSON keeps non-ASCII and HTML characters as they are
+		{`{"type":"domain-name","value":"bücher.example"}`, "domain-name--dbf3aff2-b3cd-57d2-9616-0ddfde9d01ad"},
+		{`{"type":"url","value":"https://example.com/?a=1&b=<2>"}`, "url--12490c2a-4539-542a-8145-76107c1d1877"},
+
+		{`{"type":"process","pid":1}`, ""},                   // process ids are UUIDv4
+		{`{"type":"file","size":25536,"mime_type":"x"}`, ""}, // no ID-contributing property
+	}
+	for _, tt := range tests {
+		var props map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(tt.obj), &props); err != nil {
+			t.Fatal(err)
+		}
+		id, ok, err := observableID(stringProperty(props, "type"), props)
+		if err != nil {
+			t.Errorf("observableID(%s): %v", tt.obj, err)
+			continue
+		}
+		if ok != (tt.want != "") || id != tt.want {
+			t.Errorf("observableID(%s) = %q, %v, want %q", tt.obj, id, ok, tt.want)
+		}
+	}
+}
+
+func TestWithObservableID(t *testing.T) {
+	raw, err := withObservableID([]byte(`{"type":"ipv4-addr","value":"198.51.100.3"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &props); err != nil {
+		t.Fatal(err)
+	}
+	if id := stringProperty(props, "id"); id != "ipv4-addr--28bb3599-77cd-5a82-a950-b5bc3caf07c4" {
+		t.Errorf("added id %q", id)
+	}
+
+	// An id that is present is kept, and then checked by decodeStixObject
+	given := `{"type":"ipv4-addr","id":"ipv4-addr--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f","value":"198.51.100.3"}`
+	if raw, err := withObservableID([]byte(given)); err != nil || string(raw) != given {
+		t.Errorf("withObservableID changed %s to %s, %v", given, raw, err)
+	}
+	if _, _, _, err := decodeStixObject("ipv4-addr", []byte(given)); err == nil {
+		t.Error("decodeStixObject accepted a random id for an SCO with ID-contributing properties")
+	}
+
+	if _, err := withObservableID([]byte(`{"type":"file","size":1}`)); err == nil {
+		t.Error("withObservableID accepted an SCO without id or ID-contributing properties")
+	}
+}
+
+// Each organization's submissions of an SCO stay under its own markings
+func TestMergeObservableKeepsReportsApart(t *testing.T) {
+	const id = "ipv4-addr--28bb3599-77cd-5a82-a950-b5bc3caf07c4"
+	l := newMockLedger(t)
+	org := func(mspID string) *mockIdentity { return newMockIdentity(mspID, "analyst", "producer", false) }
+	submit := func(mspID, obj string) {
+		t.Helper()
+		err := l.invoke(org(mspID), func(ctx contractapi.TransactionContextInterface) error {
+			return (&CTIStixContract{}).CreateObject(ctx, obj)
+		})
+		if err != nil {
+			t.Fatalf("%s submitting %s: %v", mspID, obj, err)
+		}
+	}
+	read := func(mspID string) string {
+		t.Helper()
+		var obj string
+		err := l.invoke(org(mspID), func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			obj, err = (&CTIStixContract{}).ReadObject(ctx, id, false)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("%s reading %s: %v", mspID, id, err)
+		}
+		return obj
+	}
+
+	submit("Org1MSP", `{"type":"ipv4-addr","spec_version":"2.1","value":"198.51.100.3","x_zeta":1,"x_alpha":2,"object_marking_refs":["`+tlpGreen+`"]}`)
+	stored := read("Org1MSP")
+	l.events()
+
+	submit("Org2MSP", `{"type":"ipv4-addr","value":"198.51.100.3","x_org2_note":"red","object_marking_refs":["`+tlpRed+`"]}`)
+	var event ObjectEvent
+	select {
+	case e := <-l.stub.ChaincodeEventsChannel:
+		if e.EventName != observableMergedEvent || json.Unmarshal(e.Payload, &event) != nil {
+			t.Fatalf("merge emitted %s %s", e.EventName, e.Payload)
+		}
+	default:
+		t.Fatal("merge emitted no event")
+	}
+	if event.ID != id || event.CreatorMSP != "Org1MSP" || event.ReportedBy != "Org2MSP" || !event.Restricted || len(event.Observables) != 0 {
+		t.Errorf("merge event %+v", event)
+	}
+
+	submit("Org3MSP", `{"type":"ipv4-addr","value":"198.51.100.3","x_org3_score":7,"x_org3_secret":"s","granular_markings":[{"marking_ref":"`+tlpRed+`","selectors":["x_org3_secret"]}]}`)
+	submit("Org2MSP", `{"type":"ipv4-addr","value":"198.51.100.3","x_org2_more":"red too","object_marking_refs":["`+tlpRed+`"]}`)
+
+	// The stored JSON is kept as it was; visible reports only append properties
+	base := strings.TrimSuffix(stored, "}")
+	tests := []struct {
+		mspID string
+		want  string
+	}{
+		{"Org1MSP", base + `,"x_org3_score":7}`},
+		{"Org4MSP", base + `,"x_org3_score":7}`},
+		{"Org2MSP", strings.Replace(base, `"object_marking_refs":["`+tlpGreen+`"]`, `"object_marking_refs":["`+tlpGreen+`","`+tlpRed+`"]`, 1) +
+			`,"x_org2_note":"red","x_org2_more":"red too","x_org3_score":7}`},
+		{"Org3MSP", base + `,"x_org3_score":7,"x_org3_secret":"s","granular_markings":[{"marking_ref":"` + tlpRed + `","selectors":["x_org3_secret"]}]}`},
+	}
+	for _, tt := range tests {
+		if got := read(tt.mspID); got != tt.want {
+			t.Errorf("%s reads\n%s\nwant\n%s", tt.mspID, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// File: stix_sightings.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Sighting Aggregation
+// ──────────────────────────────────────────────────────────────────────────────
+
+// maxSightingCount is the upper bound STIX puts on a sighting's count
+const maxSightingCount = 999999999
+
+// SightingSummary aggregates the sightings of one object across the organizations
+// of the channel
+type SightingSummary struct {
+	SightingOfRef string   `json:"sighting_of_ref"`
+	Sightings     int      `json:"sightings"`   // sighting objects counted
+	TotalCount    int64    `json:"total_count"` // sum of their counts, 1 where a count is missing
+	Orgs          []string `json:"orgs"`        // distinct MSP IDs of the reporting organizations
+	FirstSeen     string   `json:"first_seen,omitempty"`
+	LastSeen      string   `json:"last_seen,omitempty"`
+}
+
+// orgSightingID returns the id of the sighting an organization keeps for an object
+func orgSightingID(mspID, sightingOfRef string) string {
+	return derivedID("sighting", mspID, sightingOfRef)
+}
+
+// parseSeen returns the parsed first or last seen of a report, defaulting to now
+func parseSeen(name, value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return now, nil
+	}
+	if !timestampPattern.MatchString(value) {
+		return time.Time{}, fmt.Errorf("%s '%s' must be an RFC 3339 timestamp in UTC ending in Z", name, value)
+	}
+	return parseStixTimestamp(value)
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Sighting Methods
+// ──────────────────────────────────────────────────────────────────────────────
+
+// ReportSighting records that the submitting organization observed an object count
+// times between firstSeen and lastSeen (both default to the transaction time). The
+// first report creates the organization's sighting of the object, whose id is
+// derived from the MSP ID and sightingOfRef; later reports store a new version with
+// the counts added up and first_seen/last_seen widened. Two concurrent reports of the
+// same organization read and write the same key, so Fabric's MVCC check invalidates
+// one of them instead of losing its count; the client resubmits it.
+func (c *CTIStixContract) ReportSighting(
+	ctx contractapi.TransactionContextInterface,
+	sightingOfRef string,
+	count int,
+	firstSeen string,
+	lastSeen string,
+) (string, error) {
+	if err := c.requirePermission(ctx, actionCreate); err != nil {
+		return "", err
+	}
+
+	if count < 1 || count > maxSightingCount {
+		return "", fmt.Errorf("count must be between 1 and %d, got %d", maxSightingCount, count)
+	}
+	now, err := c.txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	first, err := parseSeen("first_seen", firstSeen, now)
+	if err != nil {
+		return "", err
+	}
+	last, err := parseSeen("last_seen", lastSeen, now)
+	if err != nil {
+		return "", err
+	}
+	if last.Before(first) {
+		return "", fmt.Errorf("last_seen '%s' must not be earlier than first_seen '%s'", formatStixTimestamp(last), formatStixTimestamp(first))
+	}
+
+	mspID, _, err := c.getSubmitter(ctx)
+	if err != nil {
+		return "", err
+	}
+	id := orgSightingID(mspID, sightingOfRef)
+	exists, err := c.stixObjectExists(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	if !exists {
+		ts := formatStixTimestamp(now)
+		sightingJSON, err := json.Marshal(map[string]interface{}{
+			"type":            "sighting",
+			"spec_version":    "2.1",
+			"id":              id,
+			"created":         ts,
+			"modified":        ts,
+			"created_by_ref":  orgIdentityID(mspID),
+			"sighting_of_ref": sightingOfRef,
+			"count":           count,
+			"first_seen":      formatStixTimestamp(first),
+			"last_seen":       formatStixTimestamp(last),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal sighting of %s: %v", sightingOfRef, err)
+		}
+		if err := c.createTypedObject(ctx, "sighting", sightingJSON); err != nil {
+			return "", err
+		}
+		return string(sightingJSON), nil
+	}
+
+	current, err := c.getStixObject(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if current.Revoked {
+		return "", fmt.Errorf("the sighting %s of %s has been revoked", id, sightingOfRef)
+	}
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(current.Object, &props); err != nil {
+		return "", fmt.Errorf("failed to unmarshal %s JSON: %v", id, err)
+	}
+
+	total := int64(count)
+	var previous int64
+	if err := json.Unmarshal(props["count"], &previous); err == nil {
+		total += previous
+	}
+	if total > maxSightingCount {
+		total = maxSightingCount
+	}
+	if t, err := parseStixTimestamp(stringProperty(props, "first_seen")); err == nil && t.Before(first) {
+		first = t
+	}
+	if t, err := parseStixTimestamp(stringProperty(props, "last_seen")); err == nil && t.After(last) {
+		last = t
+	}
+	// The new version must be strictly later than the current one at the millisecond
+	// precision “modified” is stored with
+	now = now.Truncate(time.Millisecond)
+	if curModified, err := parseStixTimestamp(current.Modified); err == nil && !now.After(curModified) {
+		now = curModified.Add(time.Millisecond)
+	}
+
+	for name, value := range map[string]interface{}{
+		"count":      total,
+		"first_seen": formatStixTimestamp(first),
+		"last_seen":  formatStixTimestamp(last),
+		"modified":   formatStixTimestamp(now),
+	} {
+		if props[name], err = json.Marshal(value); err != nil {
+			return "", fmt.Errorf("failed to marshal %s: %v", name, err)
+		}
+	}
+	sightingJSON, err := json.Marshal(props)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal sighting %s: %v", id, err)
+	}
+	if err := c.updateObject(ctx, sightingJSON, sightingReportedEvent); err != nil {
+		return "", err
+	}
+	return string(sightingJSON), nil
+}
+
+// GetSightingSummary aggregates the sightings of an object visible to the caller:
+// how many there are, their total count, the organizations that reported them and
+// the earliest first_seen and latest last_seen. Revoked sightings are left out.
+func (c *CTIStixContract) GetSightingSummary(
+	ctx contractapi.TransactionContextInterface,
+	sightingOfRef string,
+) (*SightingSummary, error) {
+	v, err := c.newObjectViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.summarizeSightings(ctx, v, sightingOfRef)
+}
+
+// summarizeSightings aggregates the current, unrevoked sightings of an object that
+// the viewer may read
+func (c *CTIStixContract) summarizeSightings(
+	ctx contractapi.TransactionContextInterface,
+	v *objectViewer,
+	sightingOfRef string,
+) (*SightingSummary, error) {
+	ids, err := c.indexedIDs(ctx, sightingOfObjectType, []string{sightingOfRef})
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &SightingSummary{SightingOfRef: sightingOfRef, Orgs: []string{}}
+	orgs := map[string]bool{}
+	var first, last time.Time
+	for _, id := range ids {
+		rec, err := c.getStixObject(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if rec.Revoked {
+			continue
+		}
+		obj, err := v.view(rec)
+		if err != nil {
+			return nil, err
+		}
+		if obj == nil {
+			continue
+		}
+		var props map[string]json.RawMessage
+		if err := json.Unmarshal(obj, &props); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s JSON: %v", id, err)
+		}
+
+		summary.Sightings++
+		count := int64(1)
+		if hasProperty(props, "count") {
+			if err := json.Unmarshal(props["count"], &count); err != nil {
+				return nil, fmt.Errorf("%s has an invalid count: %v", id, err)
+			}
+		}
+		summary.TotalCount += count
+		if !orgs[rec.CreatorMSP] {
+			orgs[rec.CreatorMSP] = true
+			summary.Orgs = append(summary.Orgs, rec.CreatorMSP)
+		}
+		if t, err := parseStixTimestamp(stringProperty(props, "first_seen")); err == nil && (first.IsZero() || t.Before(first)) {
+			first = t
+		}
+		if t, err := parseStixTimestamp(stringProperty(props, "last_seen")); err == nil && t.After(last) {
+			last = t
+		}
+	}
+	sort.Strings(summary.Orgs)
+	if !first.IsZero() {
+		summary.FirstSeen = formatStixTimestamp(first)
+	}
+	if !last.IsZero() {
+		summary.LastSeen = formatStixTimestamp(last)
+	}
+	return summary, nil
+}
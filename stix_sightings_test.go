@@ -0,0 +1,149 @@
+// File: stix_sightings_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// reportSighting runs ReportSighting as id
+func (l *mockLedger) reportSighting(id *mockIdentity, ref string, count int, firstSeen, lastSeen string) error {
+	return l.invoke(id, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := (&CTIStixContract{}).ReportSighting(ctx, ref, count, firstSeen, lastSeen)
+		return err
+	})
+}
+
+// sightingSummary runs GetSightingSummary as id
+func (l *mockLedger) sightingSummary(id *mockIdentity, ref string) *SightingSummary {
+	l.t.Helper()
+	var summary *SightingSummary
+	err := l.invoke(id, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		summary, err = (&CTIStixContract{}).GetSightingSummary(ctx, ref)
+		return err
+	})
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	return summary
+}
+
+func TestReportSighting(t *testing.T) {
+	const indicator = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+	l := newMockLedger(t)
+	org1 := newMockIdentity("Org1MSP", "sensor", "producer", false)
+	l.create(org1, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45"))
+
+	// Reports of one organization merge into its sighting of the indicator, each as a
+	// new version even within one millisecond
+	l.clock = time.Date(2025, 5, 3, 12, 0, 0, 400000, time.UTC)
+	for _, r := range []struct {
+		count       int
+		first, last string
+	}{
+		{2, "2025-05-02T09:00:00Z", "2025-05-02T10:00:00Z"},
+		{3, "2025-05-02T08:30:00Z", "2025-05-02T09:30:00Z"},
+		{1, "2025-05-03T07:00:00Z", "2025-05-03T07:00:00Z"},
+	} {
+		if err := l.reportSighting(org1, indicator, r.count, r.first, r.last); err != nil {
+			t.Fatal(err)
+		}
+	}
+	id := orgSightingID("Org1MSP", indicator)
+	var sighting Sighting
+	if err := json.Unmarshal(l.record(id).Object, &sighting); err != nil {
+		t.Fatal(err)
+	}
+	if sighting.Count != 6 || sighting.FirstSeen != "2025-05-02T08:30:00.000Z" || sighting.LastSeen != "2025-05-03T07:00:00.000Z" ||
+		sighting.SightingOfRef != indicator || sighting.CreatedByRef != orgIdentityID("Org1MSP") || sighting.Modified != "2025-05-03T12:00:00.002Z" {
+		t.Errorf("merged sighting %+v", sighting)
+	}
+	if versions, err := l.versions(org1, id); err != nil || len(versions) != 3 {
+		t.Errorf("%d versions, %v; want one per report", len(versions), err)
+	}
+
+	// The count stays within the STIX bound
+	if err := l.reportSighting(org1, indicator, maxSightingCount, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(l.record(id).Object, &sighting); err != nil || sighting.Count != maxSightingCount {
+		t.Errorf("count %d, %v; want %d", sighting.Count, err, maxSightingCount)
+	}
+
+	for _, tt := range []struct {
+		count       int
+		first, last string
+		errText     string
+	}{
+		{0, "", "", "count must be between 1 and"},
+		{maxSightingCount + 1, "", "", "count must be between 1 and"},
+		{1, "2025-05-02T10:00:00Z", "2025-05-02T09:00:00Z", "must not be earlier than first_seen"},
+		{1, "2025-05-02T10:00:00+02:00", "", "must be an RFC 3339 timestamp in UTC ending in Z"},
+	} {
+		if err := l.reportSighting(org1, indicator, tt.count, tt.first, tt.last); err == nil || !strings.Contains(err.Error(), tt.errText) {
+			t.Errorf("ReportSighting(%d, %q, %q): error %v, want %q", tt.count, tt.first, tt.last, err, tt.errText)
+		}
+	}
+}
+
+func TestGetSightingSummary(t *testing.T) {
+	const indicator = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+	l := newMockLedger(t)
+	org1 := newMockIdentity("Org1MSP", "sensor", "", false)
+	org2 := newMockIdentity("Org2MSP", "sensor", "", false)
+	org3 := newMockIdentity("Org3MSP", "sensor", "", false)
+	l.create(org1, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45"))
+	if err := l.reportSighting(org1, indicator, 4, "2025-05-02T09:00:00Z", "2025-05-02T10:00:00Z"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.reportSighting(org2, indicator, 1, "2025-05-01T23:00:00Z", "2025-05-02T01:00:00Z"); err != nil {
+		t.Fatal(err)
+	}
+	// A sighting without a count counts once; this one is withheld from other orgs
+	sighting := sdo("sighting", "1b7b0c07-8b1a-4b7b-aab9-091a2b3c4d5e")
+	sighting["sighting_of_ref"], sighting["object_marking_refs"] = indicator, []string{tlpRed}
+	sighting["first_seen"], sighting["last_seen"] = "2025-05-04T00:00:00Z", "2025-05-04T00:00:00Z"
+	l.create(org3, sighting)
+
+	want := SightingSummary{SightingOfRef: indicator, Sightings: 2, TotalCount: 5, Orgs: []string{"Org1MSP", "Org2MSP"},
+		FirstSeen: "2025-05-01T23:00:00.000Z", LastSeen: "2025-05-02T10:00:00.000Z"}
+	if got := l.sightingSummary(org2, indicator); !sameSummary(got, &want) {
+		t.Errorf("Org2MSP summary %+v, want %+v", got, want)
+	}
+	want.Sightings, want.TotalCount, want.Orgs, want.LastSeen = 3, 6, []string{"Org1MSP", "Org2MSP", "Org3MSP"}, "2025-05-04T00:00:00.000Z"
+	if got := l.sightingSummary(org3, indicator); !sameSummary(got, &want) {
+		t.Errorf("Org3MSP summary %+v, want %+v", got, want)
+	}
+
+	// A revoked sighting drops out and takes no more reports
+	err := l.invoke(org2, func(ctx contractapi.TransactionContextInterface) error {
+		return (&CTIStixContract{}).RevokeObject(ctx, orgSightingID("Org2MSP", indicator), "sensor misfired")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = SightingSummary{SightingOfRef: indicator, Sightings: 1, TotalCount: 4, Orgs: []string{"Org1MSP"},
+		FirstSeen: "2025-05-02T09:00:00.000Z", LastSeen: "2025-05-02T10:00:00.000Z"}
+	if got := l.sightingSummary(org1, indicator); !sameSummary(got, &want) {
+		t.Errorf("after revocation %+v, want %+v", got, want)
+	}
+	if err := l.reportSighting(org2, indicator, 1, "", ""); err == nil || !strings.Contains(err.Error(), "has been revoked") {
+		t.Errorf("report on a revoked sighting: error %v", err)
+	}
+
+	if got := l.sightingSummary(org1, "malware--31b940d4-6f7f-459a-80ea-9c1f17b5891b"); got.Sightings != 0 || len(got.Orgs) != 0 || got.FirstSeen != "" {
+		t.Errorf("summary of an unsighted object %+v", got)
+	}
+}
+
+// sameSummary compares two sighting summaries
+func sameSummary(a, b *SightingSummary) bool {
+	return a.SightingOfRef == b.SightingOfRef && a.Sightings == b.Sightings && a.TotalCount == b.TotalCount &&
+		strings.Join(a.Orgs, ",") == strings.Join(b.Orgs, ",") && a.FirstSeen == b.FirstSeen && a.LastSeen == b.LastSeen
+}
@@ -0,0 +1,332 @@
+// File: stix_storage.go
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Ledger Records
+// ──────────────────────────────────────────────────────────────────────────────
+
+// stixDocType marks world-state values that hold a STIX object
+const stixDocType = "stix"
+
+// ledgerRecord is the world-state value of every STIX object. Object holds the
+// submitted JSON verbatim (compacted), so properties that the typed structs do not
+// model, such as custom “x_” properties and extensions, survive storage.
+type ledgerRecord struct {
+	DocType        string          `json:"docType"`                     // always "stix"
+	Type           string          `json:"type"`                        // STIX type of Object
+	ID             string          `json:"id"`                          // STIX ID of Object
+	Modified       string          `json:"modified,omitempty"`          // “modified” of Object, if it has one
+	ValidFrom      string          `json:"valid_from,omitempty"`        // “valid_from” of Object as a sortable timestamp, if it has one
+	CreatorMSP     string          `json:"creator_msp"`                 // MSP ID of the org that created the first version
+	CreatorSubject string          `json:"creator_subject,omitempty"`   // certificate subject of the submitter of the first version
+	Revoked        bool            `json:"revoked,omitempty"`           // “revoked” of Object
+	Reason         string          `json:"revocation_reason,omitempty"` // reason given to RevokeObject
+	ReportedBy     []string        `json:"reported_by,omitempty"`       // MSP IDs of the orgs that submitted an SCO
+	Reports        []scoReport     `json:"reports,omitempty"`           // resubmissions of an SCO by other orgs, as submitted
+	Added          string          `json:"added,omitempty"`             // transaction time at which this version was stored
+	Salt           string          `json:"salt,omitempty"`              // hex salt of a private object's stub hash, kept in the collection
+	Object         json.RawMessage `json:"object"`                      // the original STIX JSON
+}
+
+// newLedgerRecord wraps raw STIX JSON into a ledger record, keeping the original
+// property order and values and only dropping insignificant whitespace
+func newLedgerRecord(raw []byte) (*ledgerRecord, error) {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return nil, fmt.Errorf("failed to compact STIX JSON: %v", err)
+	}
+
+	var header struct {
+		Type      string `json:"type"`
+		ID        string `json:"id"`
+		Modified  string `json:"modified"`
+		ValidFrom string `json:"valid_from"`
+		Revoked   bool   `json:"revoked"`
+	}
+	if err := json.Unmarshal(compact.Bytes(), &header); err != nil {
+		return nil, fmt.Errorf("failed to parse STIX JSON: %v", err)
+	}
+	rec := &ledgerRecord{
+		DocType:  stixDocType,
+		Type:     header.Type,
+		ID:       header.ID,
+		Modified: header.Modified,
+		Revoked:  header.Revoked,
+		Object:   compact.Bytes(),
+	}
+	if t, err := parseStixTimestamp(header.ValidFrom); err == nil {
+		rec.ValidFrom = sortableTimestamp(t)
+	}
+	return rec, nil
+}
+
+// sortableTimestamp renders a time in UTC with a fixed nanosecond precision, so that
+// timestamps compare as strings in time order; STIX allows any precision, under which
+// "…:00Z" sorts after "…:00.500Z"
+func sortableTimestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000Z")
+}
+
+// parseLedgerRecord decodes a world-state value written by putStixObject
+func parseLedgerRecord(value []byte) (*ledgerRecord, error) {
+	var rec ledgerRecord
+	if err := json.Unmarshal(value, &rec); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ledger record: %v", err)
+	}
+	if rec.DocType != stixDocType {
+		return nil, fmt.Errorf("world-state value is not a STIX object record")
+	}
+	return &rec, nil
+}
+
+// stixObjectType is the composite-key namespace of STIX objects; keys are
+// stixObjectType + [STIX type, STIX ID] so objects can be listed by type
+const stixObjectType = "stix"
+
+// stixTypeOf returns the type part of a STIX ID ("indicator" for "indicator--…")
+func stixTypeOf(id string) (string, error) {
+	idx := strings.Index(id, "--")
+	if idx <= 0 {
+		return "", fmt.Errorf("'%s' is not a STIX ID of the form <type>--<uuid>", id)
+	}
+	return id[:idx], nil
+}
+
+// stixObjectKey returns the world-state key of a STIX object
+func (c *CTIStixContract) stixObjectKey(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	typ, err := stixTypeOf(id)
+	if err != nil {
+		return "", err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(stixObjectType, []string{typ, id})
+	if err != nil {
+		return "", fmt.Errorf("failed to create key for %s: %v", id, err)
+	}
+	return key, nil
+}
+
+// putStixObject writes a ledger record into world state under its type and STIX ID,
+// stamping it with the transaction time
+func (c *CTIStixContract) putStixObject(ctx contractapi.TransactionContextInterface, rec *ledgerRecord) error {
+	key, err := c.stixObjectKey(ctx, rec.ID)
+	if err != nil {
+		return err
+	}
+	now, err := c.txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	rec.Added = formatStixTimestamp(now)
+	bytes, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s for storage: %v", rec.Type, err)
+	}
+	return c.putAsset(ctx, key, bytes)
+}
+
+// getStixObject reads the ledger record of a STIX object by its STIX ID
+func (c *CTIStixContract) getStixObject(ctx contractapi.TransactionContextInterface, id string) (*ledgerRecord, error) {
+	key, err := c.stixObjectKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from world state: %v", id, err)
+	}
+	if bytes == nil {
+		return nil, objectNotFound(id)
+	}
+	rec, err := parseLedgerRecord(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("asset %s: %v", id, err)
+	}
+	return rec, nil
+}
+
+// objectNotFound is the error for an object that is not stored, and also for one that
+// the caller's markings withhold, so that the two cannot be told apart
+func objectNotFound(id string) error {
+	return fmt.Errorf("asset %s does not exist", id)
+}
+
+// stixObjectExists checks if a STIX object with the given ID is already stored
+func (c *CTIStixContract) stixObjectExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+	key, err := c.stixObjectKey(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return c.assetExists(ctx, key)
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Custom Properties and Extensions
+// ──────────────────────────────────────────────────────────────────────────────
+
+// propertyNamePattern is the STIX 2.1 rule for property names, custom ones included
+var propertyNamePattern = regexp.MustCompile(`^[a-z0-9_]{3,250}$`)
+
+// extensionTypes are the values allowed in an extension definition's extension_types
+var extensionTypes = map[string]bool{
+	"new-sdo":                     true,
+	"new-sco":                     true,
+	"new-sro":                     true,
+	"property-extension":          true,
+	"toplevel-property-extension": true,
+}
+
+// checkPropertyNames enforces the STIX naming rules on top-level property names
+// and on the keys of the “extensions” dictionary
+func checkPropertyNames(props map[string]json.RawMessage, verr *ValidationError) {
+	for _, name := range sortedKeys(props) {
+		if name == "id" || name == "type" {
+			continue
+		}
+		if !propertyNamePattern.MatchString(name) {
+			verr.add(name, "property name must be 3-250 characters of a-z, 0-9 or _")
+		}
+	}
+	if !hasProperty(props, "extensions") {
+		return
+	}
+
+	var extensions map[string]json.RawMessage
+	if err := json.Unmarshal(props["extensions"], &extensions); err != nil {
+		verr.add("extensions", "must be a dictionary")
+		return
+	}
+	for _, key := range sortedKeys(extensions) {
+		if !strings.HasPrefix(key, "extension-definition--") && !strings.HasSuffix(key, "-ext") {
+			verr.add("extensions", "key '%s' must be an extension-definition ID or end in '-ext'", key)
+		}
+	}
+}
+
+// newTypeExtension returns the extension_type (“new-sdo”, “new-sco” or “new-sro”)
+// under which an object of a type unknown to the registry declares itself, or ""
+func newTypeExtension(props map[string]json.RawMessage) string {
+	var extensions map[string]struct {
+		ExtensionType string `json:"extension_type"`
+	}
+	if err := json.Unmarshal(props["extensions"], &extensions); err != nil {
+		return ""
+	}
+	for key, ext := range extensions {
+		if strings.HasPrefix(key, "extension-definition--") && strings.HasPrefix(ext.ExtensionType, "new-") {
+			return ext.ExtensionType
+		}
+	}
+	return ""
+}
+
+// lookupObject returns the JSON of a STIX object written earlier in this transaction
+// (pending, keyed by ID) or stored on the ledger, or nil if neither has it. Fabric
+// does not let a transaction read its own writes, hence the pending map.
+func (c *CTIStixContract) lookupObject(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+	pending map[string]json.RawMessage,
+) (json.RawMessage, error) {
+	if obj, ok := pending[id]; ok {
+		return obj, nil
+	}
+	exists, err := c.stixObjectExists(ctx, id)
+	if err != nil || !exists {
+		return nil, err
+	}
+	rec, err := c.getStixObject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return rec.Object, nil
+}
+
+// checkExtensions verifies that every extension-definition an object uses is stored
+// on the ledger (or pending in this transaction) and allows the extension_type the
+// object claims
+func (c *CTIStixContract) checkExtensions(
+	ctx contractapi.TransactionContextInterface,
+	props map[string]json.RawMessage,
+	pending map[string]json.RawMessage,
+) error {
+	if !hasProperty(props, "extensions") {
+		return nil
+	}
+	var extensions map[string]struct {
+		ExtensionType string `json:"extension_type"`
+	}
+	if err := json.Unmarshal(props["extensions"], &extensions); err != nil {
+		return fmt.Errorf("extensions must be a dictionary of objects: %v", err)
+	}
+
+	for key, ext := range extensions {
+		if !strings.HasPrefix(key, "extension-definition--") {
+			continue
+		}
+		defJSON, err := c.lookupObject(ctx, key, pending)
+		if err != nil {
+			return err
+		}
+		if defJSON == nil {
+			return fmt.Errorf("extension %s is not defined on the ledger", key)
+		}
+		var def ExtensionDefinition
+		if err := json.Unmarshal(defJSON, &def); err != nil {
+			return fmt.Errorf("failed to unmarshal extension-definition JSON: %v", err)
+		}
+		if ext.ExtensionType == "" {
+			continue
+		}
+		allowed := false
+		for _, t := range def.ExtensionTypes {
+			if t == ext.ExtensionType {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("extension %s does not allow extension_type '%s'", key, ext.ExtensionType)
+		}
+	}
+	return nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 3) Extension Definitions
+// ──────────────────────────────────────────────────────────────────────────────
+
+// ExtensionDefinition represents a STIX 2.1 “extension-definition” object
+type ExtensionDefinition struct {
+	CommonProperties
+	Name           string   `json:"name"`
+	Description    string   `json:"description,omitempty"`
+	Schema         string   `json:"schema"`          // URL or inline JSON schema
+	Version        string   `json:"version"`         // e.g. "1.0.0"
+	ExtensionTypes []string `json:"extension_types"` // e.g. ["property-extension"]
+	ExtensionProps []string `json:"extension_properties,omitempty"`
+}
+
+// checkExtensionDefinition enforces the extension_types vocabulary
+func checkExtensionDefinition(props map[string]json.RawMessage) error {
+	var types []string
+	if err := json.Unmarshal(props["extension_types"], &types); err != nil || len(types) == 0 {
+		return fmt.Errorf("extension-definition extension_types must be a non-empty list")
+	}
+	for _, t := range types {
+		if !extensionTypes[t] {
+			return fmt.Errorf("extension-definition has unknown extension_type '%s'", t)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+// File: stix_validation.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Structured Validation Errors
+// ──────────────────────────────────────────────────────────────────────────────
+
+// FieldError is one violated rule, tied to the property it concerns ("" for the object)
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects every rule a STIX object violates. Its message is
+// "invalid STIX object: " followed by the JSON form of the error, so that the REST
+// server can hand the field-level errors back to users.
+type ValidationError struct {
+	Type   string       `json:"type"`
+	ID     string       `json:"id,omitempty"`
+	Errors []FieldError `json:"errors"`
+}
+
+// add records a violated rule
+func (v *ValidationError) add(field, format string, args ...interface{}) {
+	v.Errors = append(v.Errors, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Error implements the error interface
+func (v *ValidationError) Error() string {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("invalid STIX object %s", v.ID)
+	}
+	return "invalid STIX object: " + string(bytes)
+}
+
+// sortedKeys returns the property names of an object in a stable order, so that
+// every endorser reports errors in the same order
+func sortedKeys(props map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) STIX 2.1 MUST Rules
+// ──────────────────────────────────────────────────────────────────────────────
+
+var (
+	// typeNamePattern is the STIX rule for type names
+	typeNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,248}[a-z0-9]$`)
+
+	// uuidPattern matches an RFC 4122 UUID and captures its version digit
+	uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-([0-9a-f])[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	// timestampPattern is the STIX timestamp format: RFC 3339 in UTC with a “Z”
+	timestampPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?Z$`)
+)
+
+// timestampProperties are the properties that hold a STIX timestamp in any type
+var timestampProperties = []string{
+	"created", "modified", "valid_from", "valid_until", "first_seen", "last_seen",
+	"first_observed", "last_observed", "published", "start_time", "stop_time",
+	"submitted", "analysis_started", "analysis_ended",
+}
+
+// orderedTimestamps are pairs whose second timestamp may not precede the first
+var orderedTimestamps = [][2]string{
+	{"created", "modified"},
+	{"first_seen", "last_seen"},
+	{"first_observed", "last_observed"},
+	{"start_time", "stop_time"},
+	{"analysis_started", "analysis_ended"},
+}
+
+// integerRanges are the inclusive bounds of integer properties
+var integerRanges = map[string][2]int64{
+	"confidence":      {0, 100},
+	"count":           {0, 999999999},
+	"number_observed": {1, 999999999},
+}
+
+// opinionScores maps the STIX opinion-enum to the confidence each value stands for
+var opinionScores = map[string]int{
+	"strongly-disagree": 0,
+	"disagree":          25,
+	"neutral":           50,
+	"agree":             75,
+	"strongly-agree":    100,
+}
+
+// checkIdentifier validates an id (or a reference) against the STIX identifier rules:
+// <type>--<UUID>, optionally with the expected type and UUID version
+func checkIdentifier(value, expectedType string, versions string) string {
+	idx := strings.Index(value, "--")
+	if idx <= 0 {
+		return fmt.Sprintf("'%s' is not of the form <type>--<uuid>", value)
+	}
+	typ, uuid := value[:idx], value[idx+2:]
+	if expectedType != "" && typ != expectedType {
+		return fmt.Sprintf("'%s' must be a %s identifier", value, expectedType)
+	}
+	m := uuidPattern.FindStringSubmatch(uuid)
+	if m == nil {
+		return fmt.Sprintf("'%s' does not contain a lowercase RFC 4122 UUID", value)
+	}
+	if versions != "" && !strings.Contains(versions, m[1]) {
+		return fmt.Sprintf("'%s' must use a UUID of version %s", value, strings.Join(strings.Split(versions, ""), " or "))
+	}
+	return ""
+}
+
+// parseTimestampProperty returns the parsed timestamp of a property, recording an
+// error if it is present but malformed
+func parseTimestampProperty(props map[string]json.RawMessage, name string, verr *ValidationError) (ts string, ok bool) {
+	if !hasProperty(props, name) {
+		return "", false
+	}
+	var value string
+	if err := json.Unmarshal(props[name], &value); err != nil {
+		verr.add(name, "must be a timestamp string")
+		return "", false
+	}
+	if !timestampPattern.MatchString(value) {
+		verr.add(name, "'%s' must be an RFC 3339 timestamp in UTC ending in Z", value)
+		return "", false
+	}
+	if _, err := parseStixTimestamp(value); err != nil {
+		verr.add(name, "'%s' is not a valid timestamp", value)
+		return "", false
+	}
+	return value, true
+}
+
+// validateStixObject applies the STIX 2.1 MUST rules shared by every SDO, SRO and
+// SCO, plus the per-property rules that apply whatever the type. uuidVersions lists
+// the UUID versions accepted in the id ("4" for SDOs and SROs).
+func validateStixObject(typ string, props map[string]json.RawMessage, uuidVersions string, verr *ValidationError) {
+	if !typeNamePattern.MatchString(typ) {
+		verr.add("type", "'%s' must be 3-250 characters of a-z, 0-9 or hyphen", typ)
+	}
+	if id := stringProperty(props, "id"); id != "" {
+		if msg := checkIdentifier(id, typ, uuidVersions); msg != "" {
+			verr.add("id", "%s", msg)
+		}
+	}
+	if hasProperty(props, "spec_version") && stringProperty(props, "spec_version") != "2.1" {
+		verr.add("spec_version", "must be \"2.1\"")
+	}
+
+	// Timestamps: format first, then ordering
+	parsed := map[string]string{}
+	for _, name := range timestampProperties {
+		if value, ok := parseTimestampProperty(props, name, verr); ok {
+			parsed[name] = value
+		}
+	}
+	for _, pair := range orderedTimestamps {
+		first, okFirst := parsed[pair[0]]
+		second, okSecond := parsed[pair[1]]
+		if !okFirst || !okSecond {
+			continue
+		}
+		t1, _ := parseStixTimestamp(first)
+		t2, _ := parseStixTimestamp(second)
+		if t2.Before(t1) {
+			verr.add(pair[1], "must not be earlier than %s (%s)", pair[0], first)
+		}
+	}
+
+	for _, name := range sortedKeys(props) {
+		bounds, ok := integerRanges[name]
+		if !ok || !hasProperty(props, name) {
+			continue
+		}
+		var n float64
+		if err := json.Unmarshal(props[name], &n); err != nil || n != float64(int64(n)) {
+			verr.add(name, "must be an integer")
+			continue
+		}
+		if int64(n) < bounds[0] || int64(n) > bounds[1] {
+			verr.add(name, "must be between %d and %d, got %d", bounds[0], bounds[1], int64(n))
+		}
+	}
+
+	if hasProperty(props, "labels") {
+		var labels []string
+		if err := json.Unmarshal(props["labels"], &labels); err != nil {
+			verr.add("labels", "must be a list of strings")
+		}
+		for _, label := range labels {
+			if strings.TrimSpace(label) == "" {
+				verr.add("labels", "must not contain empty strings")
+			}
+		}
+	}
+
+	if hasProperty(props, "created_by_ref") {
+		if msg := checkIdentifier(stringProperty(props, "created_by_ref"), "identity", ""); msg != "" {
+			verr.add("created_by_ref", "%s", msg)
+		}
+	}
+	if hasProperty(props, "object_marking_refs") {
+		var refs []string
+		if err := json.Unmarshal(props["object_marking_refs"], &refs); err != nil {
+			verr.add("object_marking_refs", "must be a list of identifiers")
+		}
+		for _, ref := range refs {
+			if msg := checkIdentifier(ref, "marking-definition", ""); msg != "" {
+				verr.add("object_marking_refs", "%s", msg)
+			}
+		}
+	}
+	validateGranularMarkings(props, verr)
+	if hasProperty(props, "object_refs") {
+		var refs []string
+		if err := json.Unmarshal(props["object_refs"], &refs); err != nil || len(refs) == 0 {
+			verr.add("object_refs", "must be a non-empty list of identifiers")
+		}
+		for _, ref := range refs {
+			if msg := checkIdentifier(ref, "", ""); msg != "" {
+				verr.add("object_refs", "%s", msg)
+			}
+		}
+	}
+
+	if hasProperty(props, "external_references") {
+		var refs []map[string]json.RawMessage
+		if err := json.Unmarshal(props["external_references"], &refs); err != nil {
+			verr.add("external_references", "must be a list of external references")
+		}
+		for i, ref := range refs {
+			field := fmt.Sprintf("external_references[%d]", i)
+			if stringProperty(ref, "source_name") == "" {
+				verr.add(field, "source_name is required")
+			}
+			if !hasProperty(ref, "description") && !hasProperty(ref, "url") && !hasProperty(ref, "external_id") {
+				verr.add(field, "needs at least one of description, url or external_id")
+			}
+		}
+	}
+
+	switch typ {
+	case "indicator":
+		if hasProperty(props, "pattern") && strings.TrimSpace(stringProperty(props, "pattern")) == "" {
+			verr.add("pattern", "must not be empty")
+		}
+		if hasProperty(props, "pattern_type") && strings.TrimSpace(stringProperty(props, "pattern_type")) == "" {
+			verr.add("pattern_type", "must not be empty")
+		}
+		if pattern := stringProperty(props, "pattern"); strings.TrimSpace(pattern) != "" {
+			if _, err := validateIndicatorPattern(stringProperty(props, "pattern_type"), pattern); err != nil {
+				verr.add("pattern", "invalid STIX pattern: %v", err)
+			}
+		}
+		if from, ok := parsed["valid_from"]; ok {
+			if until, ok := parsed["valid_until"]; ok {
+				t1, _ := parseStixTimestamp(from)
+				t2, _ := parseStixTimestamp(until)
+				if !t2.After(t1) {
+					verr.add("valid_until", "must be later than valid_from (%s)", from)
+				}
+			}
+		}
+	case "opinion":
+		if _, ok := opinionScores[stringProperty(props, "opinion")]; hasProperty(props, "opinion") && !ok {
+			verr.add("opinion", "'%s' is not a value of the opinion-enum", stringProperty(props, "opinion"))
+		}
+	}
+}
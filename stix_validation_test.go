@@ -0,0 +1,140 @@
+// File: stix_validation_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// validIndicator is an indicator that passes every rule; test cases change one
+// property at a time
+const validIndicator = `{
+	"type": "indicator",
+	"spec_version": "2.1",
+	"id": "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f",
+	"created": "2025-05-01T12:15:00.000Z",
+	"modified": "2025-05-01T12:15:00.000Z",
+	"pattern": "[ipv4-addr:value = '203.0.113.45']",
+	"pattern_type": "stix",
+	"valid_from": "2025-05-01T00:00:00Z",
+	"confidence": 50
+}`
+
+// withProperties returns validIndicator with properties replaced, or removed when
+// the value is nil
+func withProperties(t *testing.T, changes map[string]interface{}) []byte {
+	t.Helper()
+	var props map[string]interface{}
+	if err := json.Unmarshal([]byte(validIndicator), &props); err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(props, name)
+		} else {
+			props[name] = value
+		}
+	}
+	raw, err := json.Marshal(props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestDecodeStixObjectFieldErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes map[string]interface{}
+		fields  []string // fields with errors, sorted; nil if valid
+	}{
+		{"valid", nil, nil},
+		{"missing created", map[string]interface{}{"created": nil}, []string{"created"}},
+		{"UUIDv1 id", map[string]interface{}{"id": "indicator--6ba7b810-9dad-11d1-80b4-00c04fd430c8"}, []string{"id"}},
+		{"id of another type", map[string]interface{}{"id": "malware--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"}, []string{"id"}},
+		{"uppercase UUID", map[string]interface{}{"id": "indicator--8E2E2D2B-17D4-4CBF-938F-98EE46B3CD3F"}, []string{"id"}},
+		{"spec_version 2.0", map[string]interface{}{"spec_version": "2.0"}, []string{"spec_version"}},
+		{"date without time", map[string]interface{}{"valid_from": "2025-05-01"}, []string{"valid_from"}},
+		{"timestamp with offset", map[string]interface{}{"created": "2025-05-01T12:15:00+02:00"}, []string{"created"}},
+		{"impossible date", map[string]interface{}{"valid_from": "2025-02-30T00:00:00Z"}, []string{"valid_from"}},
+		{"modified before created", map[string]interface{}{"modified": "2025-04-01T12:15:00.000Z"}, []string{"modified"}},
+		{"confidence over 100", map[string]interface{}{"confidence": 150}, []string{"confidence"}},
+		{"negative confidence", map[string]interface{}{"confidence": -1}, []string{"confidence"}},
+		{"fractional confidence", map[string]interface{}{"confidence": 50.5}, []string{"", "confidence"}}, // the typed decode fails too
+		{"empty pattern", map[string]interface{}{"pattern": " "}, []string{"pattern"}},
+		{"malformed pattern", map[string]interface{}{"pattern": "[ipv4-addr:value = ]"}, []string{"pattern"}},
+		{"valid_until equal to valid_from", map[string]interface{}{"valid_until": "2025-05-01T00:00:00Z"}, []string{"valid_until"}},
+		{"empty label", map[string]interface{}{"labels": []string{"c2", " "}}, []string{"labels"}},
+		{"bad created_by_ref", map[string]interface{}{"created_by_ref": "identity--x"}, []string{"created_by_ref"}},
+		{"marking ref of another type", map[string]interface{}{"object_marking_refs": []string{"identity--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"}}, []string{"object_marking_refs"}},
+		{"external reference without a locator", map[string]interface{}{"external_references": []map[string]string{{"source_name": "x"}}}, []string{"external_references[0]"}},
+		{"several violations", map[string]interface{}{"spec_version": "2.0", "confidence": 101, "created": nil}, []string{"confidence", "created", "spec_version"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := decodeStixObject("indicator", withProperties(t, tt.changes))
+			if tt.fields == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			verr, ok := err.(*ValidationError)
+			if !ok {
+				t.Fatalf("got %v, want a *ValidationError", err)
+			}
+			var fields []string
+			for _, fe := range verr.Errors {
+				if len(fields) == 0 || fields[len(fields)-1] != fe.Field {
+					fields = append(fields, fe.Field)
+				}
+			}
+			sort.Strings(fields)
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("error fields = %v, want %v (%v)", fields, tt.fields, err)
+			}
+		})
+	}
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	_, _, _, err := decodeStixObject("indicator", withProperties(t, map[string]interface{}{"confidence": 150}))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	const prefix = "invalid STIX object: "
+	msg := err.Error()
+	if len(msg) < len(prefix) || msg[:len(prefix)] != prefix {
+		t.Fatalf("message %q does not start with %q", msg, prefix)
+	}
+	var decoded ValidationError
+	if err := json.Unmarshal([]byte(msg[len(prefix):]), &decoded); err != nil {
+		t.Fatalf("message is not followed by JSON: %v", err)
+	}
+	if decoded.Type != "indicator" || decoded.ID != "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f" || len(decoded.Errors) != 1 {
+		t.Errorf("decoded %+v", decoded)
+	}
+}
+
+func TestCheckIdentifier(t *testing.T) {
+	tests := []struct {
+		value, expectedType, versions string
+		valid                         bool
+	}{
+		{"indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "indicator", "4", true},
+		{"ipv4-addr--28bb3599-77cd-5a82-a950-b5bc3caf07c4", "ipv4-addr", "45", true},
+		{"ipv4-addr--28bb3599-77cd-5a82-a950-b5bc3caf07c4", "ipv4-addr", "4", false},
+		{"indicator--8e2e2d2b-17d4-4cbf-c38f-98ee46b3cd3f", "", "", false}, // not the RFC 4122 variant
+		{"--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "", "", false},
+		{"indicator-8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "", "", false},
+		{"indicator--8e2e2d2b", "", "", false},
+	}
+	for _, tt := range tests {
+		if msg := checkIdentifier(tt.value, tt.expectedType, tt.versions); (msg == "") != tt.valid {
+			t.Errorf("checkIdentifier(%q, %q, %q) = %q, want valid %v", tt.value, tt.expectedType, tt.versions, msg, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// File: stix_versioning.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 1) Version Structs and Helpers
+// ──────────────────────────────────────────────────────────────────────────────
+
+// ObjectVersion is one historical version of a STIX object, as recorded on the ledger
+type ObjectVersion struct {
+	TxID      string          `json:"tx_id"`            // transaction that wrote this version
+	Timestamp string          `json:"timestamp"`        // transaction timestamp, RFC 3339
+	IsDelete  bool            `json:"is_delete"`        // true if the key was deleted
+	Modified  string          `json:"modified"`         // “modified” of this version
+	Object    json.RawMessage `json:"object,omitempty"` // the STIX JSON of this version
+}
+
+// versionHeader holds the properties that STIX versioning rules look at
+type versionHeader struct {
+	Type         string `json:"type"`
+	Created      string `json:"created"`
+	Modified     string `json:"modified"`
+	CreatedByRef string `json:"created_by_ref"`
+}
+
+// parseStixTimestamp parses a STIX timestamp (RFC 3339, optional fractional seconds)
+func parseStixTimestamp(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// formatStixTimestamp renders a time as a STIX timestamp with millisecond precision
+func formatStixTimestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// txTimestamp returns the transaction timestamp, which is the same on every endorser
+func (c *CTIStixContract) txTimestamp(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC(), nil
+}
+
+// checkNewVersion applies the STIX versioning rules to a proposed new version:
+// same type, same created and created_by_ref, and a strictly later modified
+func checkNewVersion(current, next []byte) error {
+	var cur, nxt versionHeader
+	if err := json.Unmarshal(current, &cur); err != nil {
+		return fmt.Errorf("failed to unmarshal current version: %v", err)
+	}
+	if err := json.Unmarshal(next, &nxt); err != nil {
+		return fmt.Errorf("failed to unmarshal new version: %v", err)
+	}
+
+	if nxt.Type != cur.Type {
+		return fmt.Errorf("type may not change between versions ('%s' to '%s')", cur.Type, nxt.Type)
+	}
+	if nxt.Created != cur.Created {
+		return fmt.Errorf("created is immutable (was '%s', got '%s')", cur.Created, nxt.Created)
+	}
+	if nxt.CreatedByRef != cur.CreatedByRef {
+		return fmt.Errorf("created_by_ref is immutable (was '%s', got '%s')", cur.CreatedByRef, nxt.CreatedByRef)
+	}
+
+	curModified, err := parseStixTimestamp(cur.Modified)
+	if err != nil {
+		return fmt.Errorf("current version has invalid modified '%s': %v", cur.Modified, err)
+	}
+	nxtModified, err := parseStixTimestamp(nxt.Modified)
+	if err != nil {
+		return fmt.Errorf("modified must be an RFC 3339 timestamp, got '%s'", nxt.Modified)
+	}
+	if !nxtModified.After(curModified) {
+		return fmt.Errorf("modified '%s' must be later than the current version's '%s'", nxt.Modified, cur.Modified)
+	}
+	return nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// 2) Versioning Methods
+// ──────────────────────────────────────────────────────────────────────────────
+
+// UpdateObject stores a new version of an existing STIX object. The new version keeps
+// the same id, created and created_by_ref, and must have a strictly later modified.
+// Only the organization that created the object may version it.
+func (c *CTIStixContract) UpdateObject(
+	ctx contractapi.TransactionContextInterface,
+	jsonStr string,
+) error {
+	if err := c.requirePermission(ctx, actionUpdate); err != nil {
+		return err
+	}
+	return c.updateObject(ctx, []byte(jsonStr), objectVersionedEvent)
+}
+
+// updateObject validates and stores a new version of an existing object, emitting
+// the named event
+func (c *CTIStixContract) updateObject(
+	ctx contractapi.TransactionContextInterface,
+	raw []byte,
+	eventName string,
+) error {
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return fmt.Errorf("failed to parse STIX object JSON: %v", err)
+	}
+	if header.Type == "bundle" {
+		return fmt.Errorf("bundles are not versioned; submit a new bundle instead")
+	}
+	if spec := stixTypeSpecs[header.Type]; spec.sco {
+		return fmt.Errorf("cyber-observables are not versioned; resubmit the %s to merge new properties", header.Type)
+	}
+	if header.Type == "marking-definition" {
+		return fmt.Errorf("marking definitions cannot be versioned; create a new one instead")
+	}
+
+	sub, err := c.getSubmitterIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	identityID, err := c.ensureOrgIdentity(ctx, sub.MSPID)
+	if err != nil {
+		return err
+	}
+	if raw, err = stampCreatedByRef(raw, identityID); err != nil {
+		return err
+	}
+
+	_, props, id, err := decodeStixObject(header.Type, raw)
+	if err != nil {
+		return err
+	}
+	if err := c.checkExtensions(ctx, props, nil); err != nil {
+		return err
+	}
+	if err := c.checkMarkingRefs(ctx, props, nil); err != nil {
+		return err
+	}
+	if err := c.checkSingleOpinion(ctx, id, props, nil); err != nil {
+		return err
+	}
+
+	current, err := c.getStixObject(ctx, id)
+	if err != nil {
+		return err
+	}
+	if current.CreatorMSP != sub.MSPID {
+		return fmt.Errorf("only %s may version %s, submitter is %s", current.CreatorMSP, id, sub.MSPID)
+	}
+	if current.Revoked {
+		return fmt.Errorf("%s has been revoked and cannot be versioned", id)
+	}
+	var revoked bool
+	if err := json.Unmarshal(props["revoked"], &revoked); err == nil && revoked {
+		return fmt.Errorf("%s cannot be revoked by a new version; use RevokeObject", id)
+	}
+	if err := checkNewVersion(current.Object, raw); err != nil {
+		return fmt.Errorf("invalid new version of %s: %v", id, err)
+	}
+
+	cfg, err := c.getChannelConfig(ctx)
+	if err != nil {
+		return err
+	}
+	v, err := c.orgViewer(ctx, sub.MSPID)
+	if err != nil {
+		return err
+	}
+	missing, err := c.checkObjectRefs(ctx, v, header.Type, props, nil, cfg.AllowForwardRefs)
+	if err != nil {
+		return err
+	}
+	var previous map[string]json.RawMessage
+	if err := json.Unmarshal(current.Object, &previous); err != nil {
+		return fmt.Errorf("failed to parse current version of %s: %v", id, err)
+	}
+	if err := c.updatePendingRefs(ctx, id, previous, missing); err != nil {
+		return err
+	}
+
+	rec, err := newLedgerRecord(raw)
+	if err != nil {
+		return err
+	}
+	rec.CreatorMSP, rec.CreatorSubject = current.CreatorMSP, current.CreatorSubject
+	if err := c.putStixObject(ctx, rec); err != nil {
+		return err
+	}
+	if err := c.updateObjectIndexes(ctx, id, previous, props); err != nil {
+		return err
+	}
+	return c.emitObjectEvent(ctx, eventName, rec, props)
+}
+
+// GetObjectVersions returns every version of a STIX object recorded in the ledger
+// history, oldest first, with the transaction ID and timestamp that wrote it
+func (c *CTIStixContract) GetObjectVersions(
+	ctx contractapi.TransactionContextInterface,
+	id string,
+) (string, error) {
+	// The submitter must be allowed to read the current version; older versions are
+	// filtered with the same rules and returned without content if withheld
+	if _, _, err := c.readVisibleObject(ctx, id); err != nil {
+		return "", err
+	}
+	v, err := c.newObjectViewer(ctx)
+	if err != nil {
+		return "", err
+	}
+	key, err := c.stixObjectKey(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	iterator, err := ctx.GetStub().GetHistoryForKey(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to get history for %s: %v", id, err)
+	}
+	defer iterator.Close()
+
+	var versions []ObjectVersion
+	for iterator.HasNext() {
+		mod, err := iterator.Next()
+		if err != nil {
+			return "", fmt.Errorf("failed to iterate history: %v", err)
+		}
+
+		version := ObjectVersion{TxID: mod.TxId, IsDelete: mod.IsDelete}
+		if mod.Timestamp != nil {
+			version.Timestamp = time.Unix(mod.Timestamp.Seconds, int64(mod.Timestamp.Nanos)).UTC().Format(time.RFC3339Nano)
+		}
+		if !mod.IsDelete {
+			rec, err := parseLedgerRecord(mod.Value)
+			if err != nil {
+				return "", fmt.Errorf("version %s of %s: %v", mod.TxId, id, err)
+			}
+			version.Modified = rec.Modified
+			if version.Object, err = v.view(rec); err != nil {
+				return "", err
+			}
+		}
+		versions = append(versions, version)
+	}
+	if len(versions) == 0 {
+		return "", fmt.Errorf("asset %s has no history", id)
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		ti, _ := parseStixTimestamp(versions[i].Timestamp)
+		tj, _ := parseStixTimestamp(versions[j].Timestamp)
+		return ti.Before(tj)
+	})
+	return resultJSON(versions)
+}
@@ -0,0 +1,122 @@
+// File: stix_versioning_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// update runs UpdateObject as id
+func (l *mockLedger) update(id *mockIdentity, props map[string]interface{}) error {
+	return l.invoke(id, func(ctx contractapi.TransactionContextInterface) error {
+		return (&CTIStixContract{}).UpdateObject(ctx, string(stixObject(l.t, props)))
+	})
+}
+
+// versions runs GetObjectVersions as id
+func (l *mockLedger) versions(id *mockIdentity, objectID string) ([]ObjectVersion, error) {
+	var versions []ObjectVersion
+	err := l.invoke(id, func(ctx contractapi.TransactionContextInterface) error {
+		out, err := (&CTIStixContract{}).GetObjectVersions(ctx, objectID)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(out), &versions)
+	})
+	return versions, err
+}
+
+func TestUpdateObject(t *testing.T) {
+	const id = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+	l := newMockLedger(t)
+	org1 := newMockIdentity("Org1MSP", "analyst", "producer", false)
+	l.create(org1, testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45"))
+	l.events()
+
+	next := testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45")
+	next["modified"], next["description"] = "2025-05-02T08:00:00.000Z", "Poison Ivy C2"
+	if err := l.update(org1, next); err != nil {
+		t.Fatal(err)
+	}
+	if events := l.events(); len(events) != 1 || events[0] != objectVersionedEvent {
+		t.Errorf("events %v", events)
+	}
+	if got, err := l.readObject(org1, id); err != nil || string(got["description"]) != `"Poison Ivy C2"` {
+		t.Errorf("current version %v, %v", got, err)
+	}
+
+	change := func(props map[string]interface{}) map[string]interface{} {
+		v := testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45")
+		v["modified"] = "2025-05-03T08:00:00.000Z"
+		for name, value := range props {
+			v[name] = value
+		}
+		return v
+	}
+	tests := []struct {
+		name    string
+		by      *mockIdentity
+		props   map[string]interface{}
+		errText string
+	}{
+		{"same modified", org1, change(map[string]interface{}{"modified": "2025-05-02T08:00:00.000Z"}), "must be later than the current version's"},
+		{"earlier modified", org1, change(map[string]interface{}{"modified": "2025-05-01T13:00:00.000Z"}), "must be later than the current version's"},
+		{"created changed", org1, change(map[string]interface{}{"created": "2025-05-01T12:00:00.000Z"}), "created is immutable"},
+		{"created_by_ref changed", org1, change(map[string]interface{}{"created_by_ref": orgIdentityID("Org2MSP")}), "does not match the submitting organization"},
+		{"another organization", newMockIdentity("Org2MSP", "analyst", "producer", false), change(nil), "only Org1MSP may version " + id},
+		{"revoked by a version", org1, change(map[string]interface{}{"revoked": true}), "use RevokeObject"},
+		{"unknown object", org1, testIndicator("0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21", "198.51.100.7"), "does not exist"},
+		{"observable", org1, map[string]interface{}{"type": "ipv4-addr", "id": "ipv4-addr--cb9d70c5-d244-531c-9efb-bcae57adc793", "value": "203.0.113.45"}, "cyber-observables are not versioned"},
+	}
+	for _, tt := range tests {
+		if err := l.update(tt.by, tt.props); err == nil || !strings.Contains(err.Error(), tt.errText) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.errText)
+		}
+	}
+}
+
+func TestGetObjectVersions(t *testing.T) {
+	const id = "indicator--8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f"
+	l := newMockLedger(t)
+	org1 := newMockIdentity("Org1MSP", "analyst", "producer", false)
+	first := testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45")
+	first["object_marking_refs"] = []string{tlpRed}
+	l.create(org1, first)
+	second := testIndicator("8e2e2d2b-17d4-4cbf-938f-98ee46b3cd3f", "203.0.113.45")
+	second["modified"], second["object_marking_refs"] = "2025-05-02T08:00:00.000Z", []string{tlpGreen}
+	if err := l.update(org1, second); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := l.versions(org1, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[0].Modified != "2025-05-01T12:15:00.000Z" || versions[1].Modified != "2025-05-02T08:00:00.000Z" {
+		t.Fatalf("versions %+v", versions)
+	}
+	for i, v := range versions {
+		if v.TxID == "" || v.Timestamp == "" || v.IsDelete || v.Object == nil {
+			t.Errorf("version %d: %+v", i, v)
+		}
+	}
+	if versions[0].TxID == versions[1].TxID {
+		t.Error("both versions have the same transaction ID")
+	}
+
+	// Another organization sees that the RED first version exists, but not its content
+	versions, err = l.versions(newMockIdentity("Org2MSP", "reader", "", false), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[0].Object != nil || versions[1].Object == nil {
+		t.Errorf("Org2MSP versions %+v", versions)
+	}
+	if _, err := l.versions(org1, "indicator--0a2d5d8a-3c5e-4b1f-9c59-4f1a5f3a7b21"); err == nil {
+		t.Error("an unknown object has a history")
+	}
+}